@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.34.2
+// 	protoc-gen-go v1.35.1
 // 	protoc        v5.28.2
 // source: accounting.proto
 
@@ -89,11 +89,9 @@ type BookCreated struct {
 
 func (x *BookCreated) Reset() {
 	*x = BookCreated{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_accounting_proto_msgTypes[0]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_accounting_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *BookCreated) String() string {
@@ -104,7 +102,7 @@ func (*BookCreated) ProtoMessage() {}
 
 func (x *BookCreated) ProtoReflect() protoreflect.Message {
 	mi := &file_accounting_proto_msgTypes[0]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -134,11 +132,9 @@ type BookClosed struct {
 
 func (x *BookClosed) Reset() {
 	*x = BookClosed{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_accounting_proto_msgTypes[1]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_accounting_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *BookClosed) String() string {
@@ -149,7 +145,7 @@ func (*BookClosed) ProtoMessage() {}
 
 func (x *BookClosed) ProtoReflect() protoreflect.Message {
 	mi := &file_accounting_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -175,11 +171,9 @@ type BookAccountAdded struct {
 
 func (x *BookAccountAdded) Reset() {
 	*x = BookAccountAdded{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_accounting_proto_msgTypes[2]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_accounting_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *BookAccountAdded) String() string {
@@ -190,7 +184,7 @@ func (*BookAccountAdded) ProtoMessage() {}
 
 func (x *BookAccountAdded) ProtoReflect() protoreflect.Message {
 	mi := &file_accounting_proto_msgTypes[2]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -234,11 +228,9 @@ type BookTransactionEntered struct {
 
 func (x *BookTransactionEntered) Reset() {
 	*x = BookTransactionEntered{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_accounting_proto_msgTypes[3]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_accounting_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *BookTransactionEntered) String() string {
@@ -249,7 +241,7 @@ func (*BookTransactionEntered) ProtoMessage() {}
 
 func (x *BookTransactionEntered) ProtoReflect() protoreflect.Message {
 	mi := &file_accounting_proto_msgTypes[3]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -306,6 +298,298 @@ func (x *BookTransactionEntered) GetAccountCreditedNewBalance() uint64 {
 	return 0
 }
 
+type BookTransferSent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TransferId        string                 `protobuf:"bytes,1,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"`
+	Timestamp         *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Account           string                 `protobuf:"bytes,3,opt,name=account,proto3" json:"account,omitempty"`
+	ToBookId          string                 `protobuf:"bytes,4,opt,name=to_book_id,json=toBookId,proto3" json:"to_book_id,omitempty"`
+	ToAccount         string                 `protobuf:"bytes,5,opt,name=to_account,json=toAccount,proto3" json:"to_account,omitempty"`
+	Amount            uint64                 `protobuf:"varint,6,opt,name=amount,proto3" json:"amount,omitempty"`
+	AccountNewBalance uint64                 `protobuf:"varint,7,opt,name=account_new_balance,json=accountNewBalance,proto3" json:"account_new_balance,omitempty"`
+}
+
+func (x *BookTransferSent) Reset() {
+	*x = BookTransferSent{}
+	mi := &file_accounting_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookTransferSent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookTransferSent) ProtoMessage() {}
+
+func (x *BookTransferSent) ProtoReflect() protoreflect.Message {
+	mi := &file_accounting_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookTransferSent.ProtoReflect.Descriptor instead.
+func (*BookTransferSent) Descriptor() ([]byte, []int) {
+	return file_accounting_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BookTransferSent) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+func (x *BookTransferSent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *BookTransferSent) GetAccount() string {
+	if x != nil {
+		return x.Account
+	}
+	return ""
+}
+
+func (x *BookTransferSent) GetToBookId() string {
+	if x != nil {
+		return x.ToBookId
+	}
+	return ""
+}
+
+func (x *BookTransferSent) GetToAccount() string {
+	if x != nil {
+		return x.ToAccount
+	}
+	return ""
+}
+
+func (x *BookTransferSent) GetAmount() uint64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *BookTransferSent) GetAccountNewBalance() uint64 {
+	if x != nil {
+		return x.AccountNewBalance
+	}
+	return 0
+}
+
+type BookTransferReceived struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TransferId        string                 `protobuf:"bytes,1,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"`
+	Timestamp         *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Account           string                 `protobuf:"bytes,3,opt,name=account,proto3" json:"account,omitempty"`
+	FromBookId        string                 `protobuf:"bytes,4,opt,name=from_book_id,json=fromBookId,proto3" json:"from_book_id,omitempty"`
+	Amount            uint64                 `protobuf:"varint,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	AccountNewBalance uint64                 `protobuf:"varint,6,opt,name=account_new_balance,json=accountNewBalance,proto3" json:"account_new_balance,omitempty"`
+}
+
+func (x *BookTransferReceived) Reset() {
+	*x = BookTransferReceived{}
+	mi := &file_accounting_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookTransferReceived) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookTransferReceived) ProtoMessage() {}
+
+func (x *BookTransferReceived) ProtoReflect() protoreflect.Message {
+	mi := &file_accounting_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookTransferReceived.ProtoReflect.Descriptor instead.
+func (*BookTransferReceived) Descriptor() ([]byte, []int) {
+	return file_accounting_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BookTransferReceived) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+func (x *BookTransferReceived) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *BookTransferReceived) GetAccount() string {
+	if x != nil {
+		return x.Account
+	}
+	return ""
+}
+
+func (x *BookTransferReceived) GetFromBookId() string {
+	if x != nil {
+		return x.FromBookId
+	}
+	return ""
+}
+
+func (x *BookTransferReceived) GetAmount() uint64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *BookTransferReceived) GetAccountNewBalance() uint64 {
+	if x != nil {
+		return x.AccountNewBalance
+	}
+	return 0
+}
+
+type BookTransferCompleted struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TransferId string `protobuf:"bytes,1,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"`
+}
+
+func (x *BookTransferCompleted) Reset() {
+	*x = BookTransferCompleted{}
+	mi := &file_accounting_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookTransferCompleted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookTransferCompleted) ProtoMessage() {}
+
+func (x *BookTransferCompleted) ProtoReflect() protoreflect.Message {
+	mi := &file_accounting_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookTransferCompleted.ProtoReflect.Descriptor instead.
+func (*BookTransferCompleted) Descriptor() ([]byte, []int) {
+	return file_accounting_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BookTransferCompleted) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+type BookTransferCancelled struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TransferId        string `protobuf:"bytes,1,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"`
+	Account           string `protobuf:"bytes,2,opt,name=account,proto3" json:"account,omitempty"`
+	Amount            uint64 `protobuf:"varint,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	AccountNewBalance uint64 `protobuf:"varint,4,opt,name=account_new_balance,json=accountNewBalance,proto3" json:"account_new_balance,omitempty"`
+}
+
+func (x *BookTransferCancelled) Reset() {
+	*x = BookTransferCancelled{}
+	mi := &file_accounting_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookTransferCancelled) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookTransferCancelled) ProtoMessage() {}
+
+func (x *BookTransferCancelled) ProtoReflect() protoreflect.Message {
+	mi := &file_accounting_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookTransferCancelled.ProtoReflect.Descriptor instead.
+func (*BookTransferCancelled) Descriptor() ([]byte, []int) {
+	return file_accounting_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BookTransferCancelled) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+func (x *BookTransferCancelled) GetAccount() string {
+	if x != nil {
+		return x.Account
+	}
+	return ""
+}
+
+func (x *BookTransferCancelled) GetAmount() uint64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *BookTransferCancelled) GetAccountNewBalance() uint64 {
+	if x != nil {
+		return x.AccountNewBalance
+	}
+	return 0
+}
+
 var File_accounting_proto protoreflect.FileDescriptor
 
 var file_accounting_proto_rawDesc = []byte{
@@ -346,13 +630,59 @@ var file_accounting_proto_rawDesc = []byte{
 	0x72, 0x65, 0x64, 0x69, 0x74, 0x65, 0x64, 0x5f, 0x6e, 0x65, 0x77, 0x5f, 0x62, 0x61, 0x6c, 0x61,
 	0x6e, 0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x19, 0x61, 0x63, 0x63, 0x6f, 0x75,
 	0x6e, 0x74, 0x43, 0x72, 0x65, 0x64, 0x69, 0x74, 0x65, 0x64, 0x4e, 0x65, 0x77, 0x42, 0x61, 0x6c,
-	0x61, 0x6e, 0x63, 0x65, 0x2a, 0x5a, 0x0a, 0x0b, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x54,
-	0x79, 0x70, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00,
-	0x12, 0x0b, 0x0a, 0x07, 0x43, 0x41, 0x50, 0x49, 0x54, 0x41, 0x4c, 0x10, 0x01, 0x12, 0x09, 0x0a,
-	0x05, 0x41, 0x53, 0x53, 0x45, 0x54, 0x10, 0x02, 0x12, 0x0d, 0x0a, 0x09, 0x4c, 0x49, 0x41, 0x42,
-	0x49, 0x4c, 0x49, 0x54, 0x59, 0x10, 0x03, 0x12, 0x0a, 0x0a, 0x06, 0x49, 0x4e, 0x43, 0x4f, 0x4d,
-	0x45, 0x10, 0x04, 0x12, 0x0b, 0x0a, 0x07, 0x45, 0x58, 0x50, 0x45, 0x4e, 0x53, 0x45, 0x10, 0x05,
-	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x61, 0x6e, 0x63, 0x65, 0x22, 0x8c, 0x02, 0x0a, 0x10, 0x42, 0x6f, 0x6f, 0x6b, 0x54, 0x72, 0x61,
+	0x6e, 0x73, 0x66, 0x65, 0x72, 0x53, 0x65, 0x6e, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x74, 0x72, 0x61,
+	0x6e, 0x73, 0x66, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
+	0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x49, 0x64, 0x12, 0x38, 0x0a, 0x09, 0x74, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x1c,
+	0x0a, 0x0a, 0x74, 0x6f, 0x5f, 0x62, 0x6f, 0x6f, 0x6b, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x74, 0x6f, 0x42, 0x6f, 0x6f, 0x6b, 0x49, 0x64, 0x12, 0x1d, 0x0a, 0x0a,
+	0x74, 0x6f, 0x5f, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x74, 0x6f, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x61,
+	0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x61, 0x6d, 0x6f,
+	0x75, 0x6e, 0x74, 0x12, 0x2e, 0x0a, 0x13, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x6e,
+	0x65, 0x77, 0x5f, 0x62, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x11, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x4e, 0x65, 0x77, 0x42, 0x61, 0x6c, 0x61,
+	0x6e, 0x63, 0x65, 0x22, 0xf5, 0x01, 0x0a, 0x14, 0x42, 0x6f, 0x6f, 0x6b, 0x54, 0x72, 0x61, 0x6e,
+	0x73, 0x66, 0x65, 0x72, 0x52, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x12, 0x1f, 0x0a, 0x0b,
+	0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0a, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x49, 0x64, 0x12, 0x38, 0x0a,
+	0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x74, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x63, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x12, 0x20, 0x0a, 0x0c, 0x66, 0x72, 0x6f, 0x6d, 0x5f, 0x62, 0x6f, 0x6f, 0x6b, 0x5f, 0x69,
+	0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x66, 0x72, 0x6f, 0x6d, 0x42, 0x6f, 0x6f,
+	0x6b, 0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x2e, 0x0a, 0x13, 0x61,
+	0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x6e, 0x65, 0x77, 0x5f, 0x62, 0x61, 0x6c, 0x61, 0x6e,
+	0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x11, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x4e, 0x65, 0x77, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x22, 0x38, 0x0a, 0x15, 0x42,
+	0x6f, 0x6f, 0x6b, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x43, 0x6f, 0x6d, 0x70, 0x6c,
+	0x65, 0x74, 0x65, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72,
+	0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x72, 0x61, 0x6e, 0x73,
+	0x66, 0x65, 0x72, 0x49, 0x64, 0x22, 0x9a, 0x01, 0x0a, 0x15, 0x42, 0x6f, 0x6f, 0x6b, 0x54, 0x72,
+	0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x6c, 0x65, 0x64, 0x12,
+	0x1f, 0x0a, 0x0b, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x49, 0x64,
+	0x12, 0x18, 0x0a, 0x07, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d,
+	0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75,
+	0x6e, 0x74, 0x12, 0x2e, 0x0a, 0x13, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x6e, 0x65,
+	0x77, 0x5f, 0x62, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x11, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x4e, 0x65, 0x77, 0x42, 0x61, 0x6c, 0x61, 0x6e,
+	0x63, 0x65, 0x2a, 0x5a, 0x0a, 0x0b, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x54, 0x79, 0x70,
+	0x65, 0x12, 0x0b, 0x0a, 0x07, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00, 0x12, 0x0b,
+	0x0a, 0x07, 0x43, 0x41, 0x50, 0x49, 0x54, 0x41, 0x4c, 0x10, 0x01, 0x12, 0x09, 0x0a, 0x05, 0x41,
+	0x53, 0x53, 0x45, 0x54, 0x10, 0x02, 0x12, 0x0d, 0x0a, 0x09, 0x4c, 0x49, 0x41, 0x42, 0x49, 0x4c,
+	0x49, 0x54, 0x59, 0x10, 0x03, 0x12, 0x0a, 0x0a, 0x06, 0x49, 0x4e, 0x43, 0x4f, 0x4d, 0x45, 0x10,
+	0x04, 0x12, 0x0b, 0x0a, 0x07, 0x45, 0x58, 0x50, 0x45, 0x4e, 0x53, 0x45, 0x10, 0x05, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -368,23 +698,29 @@ func file_accounting_proto_rawDescGZIP() []byte {
 }
 
 var file_accounting_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_accounting_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_accounting_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_accounting_proto_goTypes = []any{
 	(AccountType)(0),               // 0: rnovatorov.eventsource.examples.accounting.AccountType
 	(*BookCreated)(nil),            // 1: rnovatorov.eventsource.examples.accounting.BookCreated
 	(*BookClosed)(nil),             // 2: rnovatorov.eventsource.examples.accounting.BookClosed
 	(*BookAccountAdded)(nil),       // 3: rnovatorov.eventsource.examples.accounting.BookAccountAdded
 	(*BookTransactionEntered)(nil), // 4: rnovatorov.eventsource.examples.accounting.BookTransactionEntered
-	(*timestamppb.Timestamp)(nil),  // 5: google.protobuf.Timestamp
+	(*BookTransferSent)(nil),       // 5: rnovatorov.eventsource.examples.accounting.BookTransferSent
+	(*BookTransferReceived)(nil),   // 6: rnovatorov.eventsource.examples.accounting.BookTransferReceived
+	(*BookTransferCompleted)(nil),  // 7: rnovatorov.eventsource.examples.accounting.BookTransferCompleted
+	(*BookTransferCancelled)(nil),  // 8: rnovatorov.eventsource.examples.accounting.BookTransferCancelled
+	(*timestamppb.Timestamp)(nil),  // 9: google.protobuf.Timestamp
 }
 var file_accounting_proto_depIdxs = []int32{
 	0, // 0: rnovatorov.eventsource.examples.accounting.BookAccountAdded.type:type_name -> rnovatorov.eventsource.examples.accounting.AccountType
-	5, // 1: rnovatorov.eventsource.examples.accounting.BookTransactionEntered.timestamp:type_name -> google.protobuf.Timestamp
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	9, // 1: rnovatorov.eventsource.examples.accounting.BookTransactionEntered.timestamp:type_name -> google.protobuf.Timestamp
+	9, // 2: rnovatorov.eventsource.examples.accounting.BookTransferSent.timestamp:type_name -> google.protobuf.Timestamp
+	9, // 3: rnovatorov.eventsource.examples.accounting.BookTransferReceived.timestamp:type_name -> google.protobuf.Timestamp
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_accounting_proto_init() }
@@ -392,63 +728,13 @@ func file_accounting_proto_init() {
 	if File_accounting_proto != nil {
 		return
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_accounting_proto_msgTypes[0].Exporter = func(v any, i int) any {
-			switch v := v.(*BookCreated); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_accounting_proto_msgTypes[1].Exporter = func(v any, i int) any {
-			switch v := v.(*BookClosed); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_accounting_proto_msgTypes[2].Exporter = func(v any, i int) any {
-			switch v := v.(*BookAccountAdded); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_accounting_proto_msgTypes[3].Exporter = func(v any, i int) any {
-			switch v := v.(*BookTransactionEntered); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_accounting_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   4,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,14 @@
+package application
+
+import (
+	"context"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+type AggregateSubscriber interface {
+	SubscribeAggregate(
+		ctx context.Context, aggregateID string, afterVersion int,
+		handler eventstore.EventHandler,
+	) error
+}
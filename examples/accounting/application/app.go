@@ -2,6 +2,7 @@ package application
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/rnovatorov/go-eventsource/examples/accounting/accountingpb"
@@ -11,20 +12,38 @@ import (
 )
 
 type App struct {
-	bookRepository    *eventsource.AggregateRepository[model.Book, *model.Book]
-	projectionQueries ProjectionQueries
+	bookRepository      *eventsource.AggregateRepository[model.Book, *model.Book]
+	aggregateSubscriber AggregateSubscriber
+	commandBus          *eventsource.CommandBus
 }
 
 type Params struct {
-	EventStore        eventstore.Interface
-	ProjectionQueries ProjectionQueries
+	EventStore          eventstore.Interface
+	AggregateSubscriber AggregateSubscriber
 }
 
 func New(p Params) *App {
-	return &App{
-		bookRepository:    eventsource.NewAggregateRepository[model.Book](p.EventStore),
-		projectionQueries: p.ProjectionQueries,
+	repositories := eventsource.NewRepositories(p.EventStore)
+
+	a := &App{
+		bookRepository:      eventsource.Repository[model.Book](repositories),
+		aggregateSubscriber: p.AggregateSubscriber,
+		commandBus:          eventsource.NewCommandBus(),
 	}
+
+	eventsource.RegisterUpdateCommands[model.Book](a.commandBus, repositories,
+		model.BookTransferReceive{},
+		model.BookTransferComplete{},
+		model.BookTransferCancel{},
+	)
+
+	return a
+}
+
+// CommandBus routes the commands dispatched by process managers, see
+// TransferProcess.
+func (a *App) CommandBus() *eventsource.CommandBus {
+	return a.commandBus
 }
 
 func (a *App) CreateBook(
@@ -42,37 +61,46 @@ func (a *App) CreateBook(
 	return book.ID(), nil
 }
 
-func (a *App) CloseBook(
+// GetBook returns the book along with its version, which the mutating
+// methods accept as the expected version.
+func (a *App) GetBook(
 	ctx context.Context, bookID string,
-) error {
-	_, err := a.bookRepository.Update(ctx, bookID, model.BookClose{})
-	return err
+) (*model.Book, int, error) {
+	book, err := a.bookRepository.Get(ctx, bookID)
+	if err != nil {
+		if errors.Is(err, eventsource.ErrAggregateDoesNotExist) {
+			return nil, 0, model.ErrBookNotFound
+		}
+		return nil, 0, err
+	}
+
+	return book.Root(), book.Version(), nil
+}
+
+func (a *App) CloseBook(
+	ctx context.Context, bookID string, expectedVersion int,
+) (int, error) {
+	return a.updateBook(ctx, bookID, expectedVersion, model.BookClose{})
 }
 
 func (a *App) AddBookAccount(
-	ctx context.Context, bookID string, accountName string,
-	accountType accountingpb.AccountType,
-) error {
-	_, err := a.bookRepository.Update(ctx, bookID,
+	ctx context.Context, bookID string, expectedVersion int,
+	accountName string, accountType accountingpb.AccountType,
+) (int, error) {
+	return a.updateBook(ctx, bookID, expectedVersion,
 		model.BookAccountAdd{
 			AccountName: accountName,
 			AccountType: accountType,
 		},
 	)
-	return err
-}
-
-func (a *App) GetBookAccountBalance(
-	ctx context.Context, bookID string, accountName string,
-) (uint64, error) {
-	return a.projectionQueries.GetAccountBalance(ctx, bookID, accountName)
 }
 
 func (a *App) EnterBookTransaction(
-	ctx context.Context, bookID string, timestamp time.Time,
-	accountDebited string, accountCredited string, amount uint64,
-) error {
-	_, err := a.bookRepository.Update(ctx, bookID,
+	ctx context.Context, bookID string, expectedVersion int,
+	timestamp time.Time, accountDebited string, accountCredited string,
+	amount uint64,
+) (int, error) {
+	return a.updateBook(ctx, bookID, expectedVersion,
 		model.BookTransactionEnter{Transaction: model.Transaction{
 			Timestamp:       timestamp,
 			AccountDebited:  accountDebited,
@@ -80,5 +108,60 @@ func (a *App) EnterBookTransaction(
 			Amount:          amount,
 		}},
 	)
-	return err
+}
+
+// TransferBetweenBooks takes the amount out of the account of the sending
+// book, returning its new version. The transfer is then carried on by
+// TransferProcess.
+func (a *App) TransferBetweenBooks(
+	ctx context.Context, transferID string, timestamp time.Time,
+	fromBookID string, fromBookExpectedVersion int, fromAccount string,
+	toBookID string, toAccount string, amount uint64,
+) (int, error) {
+	return a.updateBook(ctx, fromBookID, fromBookExpectedVersion,
+		model.BookTransferSend{
+			TransferID: transferID,
+			Timestamp:  timestamp,
+			Account:    fromAccount,
+			ToBookID:   toBookID,
+			ToAccount:  toAccount,
+			Amount:     amount,
+		},
+	)
+}
+
+// updateBook processes cmd on the book and returns its new version. An
+// expected version of 0 means that the command is processed whatever the
+// version, otherwise a mismatch fails with eventstore.ErrConcurrentUpdate.
+func (a *App) updateBook(
+	ctx context.Context, bookID string, expectedVersion int,
+	cmd eventsource.Command,
+) (int, error) {
+	var (
+		book *eventsource.Aggregate[model.Book, *model.Book]
+		err  error
+	)
+	if expectedVersion > 0 {
+		book, err = a.bookRepository.UpdateAt(
+			ctx, bookID, expectedVersion, cmd)
+	} else {
+		book, err = a.bookRepository.Update(ctx, bookID, cmd)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return book.Version(), nil
+}
+
+func (a *App) StreamBookEvents(
+	ctx context.Context, bookID string, afterVersion int,
+	handler eventstore.EventHandler,
+) error {
+	if _, err := a.bookRepository.Get(ctx, bookID); err != nil {
+		return err
+	}
+
+	return a.aggregateSubscriber.SubscribeAggregate(
+		ctx, bookID, afterVersion, handler)
 }
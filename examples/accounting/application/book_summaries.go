@@ -0,0 +1,75 @@
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rnovatorov/go-eventsource/examples/accounting/accountingpb"
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/projection"
+)
+
+var (
+	_ projection.Projection = (*BookSummaries)(nil)
+	_ projection.Resettable = (*BookSummaries)(nil)
+)
+
+// BookSummary is the read model listed by QueryService.ListBooks.
+type BookSummary struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Closed      bool   `json:"closed"`
+}
+
+// BookSummaries projects a BookSummary of every book into a read model
+// store, whatever the store is backed by.
+type BookSummaries struct {
+	store projection.ReadModelStore[BookSummary]
+}
+
+func NewBookSummaries(
+	store projection.ReadModelStore[BookSummary],
+) *BookSummaries {
+	return &BookSummaries{
+		store: store,
+	}
+}
+
+func (p *BookSummaries) Name() string {
+	return "book_summaries"
+}
+
+func (p *BookSummaries) Handle(
+	ctx context.Context, event *eventstore.Event,
+) error {
+	if event.Type == eventstore.TombstoneEventType {
+		return p.store.Delete(ctx, event.AggregateID)
+	}
+
+	data, err := eventsource.ProtoCodec{}.Unmarshal(event.Type, event.Data)
+	if err != nil {
+		return fmt.Errorf("unmarshal data: %w", err)
+	}
+
+	switch d := data.(type) {
+	case *accountingpb.BookCreated:
+		return p.store.Put(ctx, event.AggregateID, BookSummary{
+			ID:          event.AggregateID,
+			Description: d.Description,
+		})
+	case *accountingpb.BookClosed:
+		summary, err := p.store.Get(ctx, event.AggregateID)
+		if err != nil {
+			return fmt.Errorf("get summary: %w", err)
+		}
+		summary.Closed = true
+		return p.store.Put(ctx, event.AggregateID, summary)
+	}
+
+	return nil
+}
+
+func (p *BookSummaries) Reset(ctx context.Context) error {
+	return p.store.Clear(ctx)
+}
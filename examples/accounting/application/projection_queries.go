@@ -7,3 +7,9 @@ type ProjectionQueries interface {
 		ctx context.Context, bookID string, accountName string,
 	) (uint64, error)
 }
+
+type BookBalances interface {
+	GetBookBalances(
+		ctx context.Context, bookID string,
+	) (map[string]uint64, error)
+}
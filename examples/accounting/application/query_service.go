@@ -0,0 +1,49 @@
+package application
+
+import (
+	"context"
+
+	"github.com/rnovatorov/go-eventsource/pkg/projection"
+)
+
+// QueryService answers queries from projections rather than by loading
+// aggregates, which is left to App on the command side.
+type QueryService struct {
+	projectionQueries ProjectionQueries
+	bookBalances      BookBalances
+	bookSummaries     projection.ReadModelStore[BookSummary]
+}
+
+type QueryServiceParams struct {
+	ProjectionQueries ProjectionQueries
+	BookBalances      BookBalances
+	BookSummaries     projection.ReadModelStore[BookSummary]
+}
+
+func NewQueryService(p QueryServiceParams) *QueryService {
+	return &QueryService{
+		projectionQueries: p.ProjectionQueries,
+		bookBalances:      p.BookBalances,
+		bookSummaries:     p.BookSummaries,
+	}
+}
+
+// ListBooks lists up to limit books with an ID greater than afterID,
+// ordered by ID. A limit of 0 means no limit.
+func (q *QueryService) ListBooks(
+	ctx context.Context, afterID string, limit int,
+) ([]BookSummary, error) {
+	return q.bookSummaries.List(ctx, afterID, limit)
+}
+
+func (q *QueryService) GetBookAccountBalance(
+	ctx context.Context, bookID string, accountName string,
+) (uint64, error) {
+	return q.projectionQueries.GetAccountBalance(ctx, bookID, accountName)
+}
+
+func (q *QueryService) GetBookBalances(
+	ctx context.Context, bookID string,
+) (map[string]uint64, error) {
+	return q.bookBalances.GetBookBalances(ctx, bookID)
+}
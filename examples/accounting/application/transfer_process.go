@@ -0,0 +1,168 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/examples/accounting/accountingpb"
+	"github.com/rnovatorov/go-eventsource/examples/accounting/model"
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/processmanager"
+)
+
+var _ processmanager.ProcessManager = (*TransferProcess)(nil)
+
+const (
+	transferReceiveTimeout     = "receive"
+	transferReceiveRetryDelay  = 10 * time.Second
+	transferReceiveMaxAttempts = 3
+)
+
+// TransferProcess moves money between books in two phases. Once the sending
+// book has taken the amount out of its account, the receiving book is asked
+// to put it into its own, retrying a few times. The sending book then
+// completes the transfer if it was received, or cancels it, putting the
+// amount back, if it was not.
+type TransferProcess struct{}
+
+func NewTransferProcess() *TransferProcess {
+	return &TransferProcess{}
+}
+
+type transferState struct {
+	FromBookID string    `json:"from_book_id"`
+	ToBookID   string    `json:"to_book_id"`
+	ToAccount  string    `json:"to_account"`
+	Amount     uint64    `json:"amount"`
+	Timestamp  time.Time `json:"timestamp"`
+	Attempts   int       `json:"attempts"`
+}
+
+func (t *TransferProcess) Name() string {
+	return "transfers"
+}
+
+func (t *TransferProcess) ProcessID(event *eventstore.Event) string {
+	data, err := eventsource.ProtoCodec{}.Unmarshal(event.Type, event.Data)
+	if err != nil {
+		return ""
+	}
+
+	switch d := data.(type) {
+	case *accountingpb.BookTransferSent:
+		return d.TransferId
+	case *accountingpb.BookTransferReceived:
+		return d.TransferId
+	case *accountingpb.BookTransferCompleted:
+		return d.TransferId
+	case *accountingpb.BookTransferCancelled:
+		return d.TransferId
+	}
+
+	return ""
+}
+
+func (t *TransferProcess) Handle(
+	ctx context.Context, process *processmanager.Process,
+	event *eventstore.Event,
+) error {
+	data, err := eventsource.ProtoCodec{}.Unmarshal(event.Type, event.Data)
+	if err != nil {
+		return fmt.Errorf("unmarshal data: %w", err)
+	}
+
+	switch d := data.(type) {
+	case *accountingpb.BookTransferSent:
+		state := transferState{
+			FromBookID: event.AggregateID,
+			ToBookID:   d.ToBookId,
+			ToAccount:  d.ToAccount,
+			Amount:     d.Amount,
+			Timestamp:  d.Timestamp.AsTime(),
+		}
+		return t.receive(ctx, process, state)
+	case *accountingpb.BookTransferReceived:
+		process.Cancel(transferReceiveTimeout)
+		state, err := t.loadState(process)
+		if err != nil {
+			return err
+		}
+		return process.Dispatch(ctx, state.FromBookID,
+			model.BookTransferComplete{TransferID: process.ID})
+	case *accountingpb.BookTransferCompleted, *accountingpb.BookTransferCancelled:
+		process.Complete()
+	}
+
+	return nil
+}
+
+func (t *TransferProcess) HandleTimeout(
+	ctx context.Context, process *processmanager.Process,
+	timeout processmanager.Timeout,
+) error {
+	state, err := t.loadState(process)
+	if err != nil {
+		return err
+	}
+
+	return t.receive(ctx, process, state)
+}
+
+// receive asks the receiving book to receive the transfer, scheduling
+// another attempt if it fails, and cancelling the transfer once out of
+// attempts. A transfer already received counts as a success, its event is
+// on its way.
+func (t *TransferProcess) receive(
+	ctx context.Context, process *processmanager.Process,
+	state transferState,
+) error {
+	err := process.Dispatch(ctx, state.ToBookID, model.BookTransferReceive{
+		TransferID: process.ID,
+		Timestamp:  state.Timestamp,
+		FromBookID: state.FromBookID,
+		Account:    state.ToAccount,
+		Amount:     state.Amount,
+	})
+	if err == nil || errors.Is(err, model.ErrTransferAlreadyReceived) {
+		return t.saveState(process, state)
+	}
+
+	state.Attempts++
+	if state.Attempts < transferReceiveMaxAttempts {
+		process.ScheduleAfter(transferReceiveTimeout, transferReceiveRetryDelay)
+		return t.saveState(process, state)
+	}
+
+	if err := process.Dispatch(ctx, state.FromBookID,
+		model.BookTransferCancel{TransferID: process.ID},
+	); err != nil {
+		return fmt.Errorf("cancel: %w", err)
+	}
+
+	return t.saveState(process, state)
+}
+
+func (t *TransferProcess) loadState(
+	process *processmanager.Process,
+) (transferState, error) {
+	var state transferState
+	if err := json.Unmarshal(process.State, &state); err != nil {
+		return transferState{}, fmt.Errorf("unmarshal state: %w", err)
+	}
+	return state, nil
+}
+
+func (t *TransferProcess) saveState(
+	process *processmanager.Process, state transferState,
+) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal state: %w", err)
+	}
+	process.State = data
+	return nil
+}
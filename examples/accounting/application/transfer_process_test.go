@@ -0,0 +1,173 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/examples/accounting/accountingpb"
+	"github.com/rnovatorov/go-eventsource/examples/accounting/application"
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource/eventsourcetest"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoreinmemory"
+	"github.com/rnovatorov/go-eventsource/pkg/processmanager"
+	"github.com/rnovatorov/go-eventsource/pkg/processmanager/processmanagerinmemory"
+	"github.com/rnovatorov/go-eventsource/pkg/projection/projectioninmemory"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type transferFixture struct {
+	app       *application.App
+	clock     *eventsourcetest.FakeClock
+	processes *processmanagerinmemory.Store
+}
+
+// newTransferFixture runs the transfer process over an app with book "a"
+// holding 100 in its cash account, and book "b", which has a cash account
+// only if withCash.
+func newTransferFixture(t testing.TB, withCash bool) *transferFixture {
+	t.Helper()
+
+	ctx := context.Background()
+	eventStore := eventstoreinmemory.New()
+	f := &transferFixture{
+		app: application.New(application.Params{
+			EventStore: eventStore,
+		}),
+		clock:     eventsourcetest.NewFakeClock(epoch),
+		processes: processmanagerinmemory.NewStore(),
+	}
+
+	must := func(what string, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", what, err)
+		}
+	}
+
+	for _, id := range []string{"a", "b"} {
+		_, err := f.app.CreateBook(ctx, id, "book "+id)
+		must("create book "+id, err)
+	}
+	_, err := f.app.AddBookAccount(ctx, "a", 0, "capital",
+		accountingpb.AccountType_CAPITAL)
+	must("add capital account", err)
+	_, err = f.app.AddBookAccount(ctx, "a", 0, "cash",
+		accountingpb.AccountType_ASSET)
+	must("add cash account", err)
+	_, err = f.app.EnterBookTransaction(ctx, "a", 0, epoch, "cash", "capital",
+		100)
+	must("fund cash account", err)
+	if withCash {
+		_, err = f.app.AddBookAccount(ctx, "b", 0, "cash",
+			accountingpb.AccountType_ASSET)
+		must("add cash account to b", err)
+	}
+
+	coordinator := processmanager.NewCoordinator(eventStore,
+		f.app.CommandBus(), f.processes,
+		projectioninmemory.NewCheckpointStore(),
+		processmanager.WithClock(f.clock),
+		processmanager.WithTimeoutInterval(time.Millisecond))
+
+	runCtx, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if err := coordinator.Run(
+			runCtx, application.NewTransferProcess(),
+		); err != nil {
+			t.Errorf("run coordinator: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	_, err = f.app.TransferBetweenBooks(ctx, "t", epoch, "a", 0, "cash",
+		"b", "cash", 30)
+	must("transfer", err)
+
+	return f
+}
+
+func (f *transferFixture) process(t testing.TB) *processmanager.Process {
+	t.Helper()
+
+	process, err := f.processes.LoadProcess(
+		context.Background(), application.NewTransferProcess().Name(), "t")
+	if err != nil {
+		t.Fatalf("load process: %v", err)
+	}
+	return process
+}
+
+func (f *transferFixture) balance(t testing.TB, bookID string) uint64 {
+	t.Helper()
+
+	book, _, err := f.app.GetBook(context.Background(), bookID)
+	if err != nil {
+		t.Fatalf("get book %s: %v", bookID, err)
+	}
+	account, err := book.AccountByName("cash")
+	if err != nil {
+		t.Fatalf("cash account of book %s: %v", bookID, err)
+	}
+	return account.Balance()
+}
+
+func eventually(t testing.TB, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTransferIsCompleted(t *testing.T) {
+	f := newTransferFixture(t, true)
+
+	eventually(t, "transfer to complete", func() bool {
+		process := f.process(t)
+		return process != nil && process.Done
+	})
+
+	if got := f.balance(t, "a"); got != 70 {
+		t.Fatalf("balance of a: got %d, want 70", got)
+	}
+	if got := f.balance(t, "b"); got != 30 {
+		t.Fatalf("balance of b: got %d, want 30", got)
+	}
+}
+
+func TestTransferIsCancelledAfterMaxAttempts(t *testing.T) {
+	f := newTransferFixture(t, false)
+
+	// The receiving book has no cash account, so every attempt to receive
+	// fails, each scheduling the next one 10 seconds later, up to 3.
+	for attempt := 1; attempt < 3; attempt++ {
+		retryAt := epoch.Add(time.Duration(attempt) * 10 * time.Second)
+		eventually(t, "retry to be scheduled", func() bool {
+			process := f.process(t)
+			return process != nil && len(process.Timeouts) == 1 &&
+				process.Timeouts[0].At.Equal(retryAt)
+		})
+		if got := f.balance(t, "a"); got != 70 {
+			t.Fatalf("balance of a while retrying: got %d, want 70", got)
+		}
+		f.clock.Set(retryAt)
+	}
+
+	eventually(t, "transfer to be cancelled", func() bool {
+		return f.process(t).Done
+	})
+
+	if got := f.balance(t, "a"); got != 100 {
+		t.Fatalf("balance of a: got %d, want 100", got)
+	}
+}
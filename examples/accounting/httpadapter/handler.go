@@ -3,13 +3,18 @@ package httpadapter
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/rnovatorov/go-eventsource/examples/accounting/accountingpb"
+	"github.com/rnovatorov/go-eventsource/examples/accounting/application"
+	"github.com/rnovatorov/go-eventsource/examples/accounting/model"
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
 	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
 )
 
@@ -17,31 +22,57 @@ type accountingService interface {
 	CreateBook(
 		ctx context.Context, bookID string, bookDescription string,
 	) (string, error)
-	CloseBook(
+	GetBook(
 		ctx context.Context, bookID string,
-	) error
+	) (*model.Book, int, error)
+	CloseBook(
+		ctx context.Context, bookID string, expectedVersion int,
+	) (int, error)
 	AddBookAccount(
-		ctx context.Context, bookID string, accountName string,
-		accountType accountingpb.AccountType,
+		ctx context.Context, bookID string, expectedVersion int,
+		accountName string, accountType accountingpb.AccountType,
+	) (int, error)
+	EnterBookTransaction(
+		ctx context.Context, bookID string, expectedVersion int,
+		timestamp time.Time, accountDebited string, accountCredited string,
+		amount uint64,
+	) (int, error)
+	TransferBetweenBooks(
+		ctx context.Context, transferID string, timestamp time.Time,
+		fromBookID string, fromBookExpectedVersion int, fromAccount string,
+		toBookID string, toAccount string, amount uint64,
+	) (int, error)
+	StreamBookEvents(
+		ctx context.Context, bookID string, afterVersion int,
+		handler eventstore.EventHandler,
 	) error
+}
+
+type queryService interface {
+	ListBooks(
+		ctx context.Context, afterID string, limit int,
+	) ([]application.BookSummary, error)
 	GetBookAccountBalance(
 		ctx context.Context, bookID string, accountName string,
 	) (uint64, error)
-	EnterBookTransaction(
-		ctx context.Context, bookID string, timestamp time.Time,
-		accountDebited string, accountCredited string, amount uint64,
-	) error
+	GetBookBalances(
+		ctx context.Context, bookID string,
+	) (map[string]uint64, error)
 }
 
+const eventStreamWriteTimeout = 10 * time.Second
+
 type Handler struct {
 	mux               *http.ServeMux
 	accountingService accountingService
+	queryService      queryService
 }
 
-func NewHandler(s accountingService) *Handler {
+func NewHandler(s accountingService, q queryService) *Handler {
 	h := &Handler{
 		mux:               http.NewServeMux(),
 		accountingService: s,
+		queryService:      q,
 	}
 
 	h.mux.HandleFunc("/book/create", h.handleBookCreate)
@@ -49,16 +80,28 @@ func NewHandler(s accountingService) *Handler {
 	h.mux.HandleFunc("/book/account/add", h.handleBookAccountAdd)
 	h.mux.HandleFunc("/book/account/balance", h.handleBookAccountBalance)
 	h.mux.HandleFunc("/book/transaction/enter", h.handleBookTransactionEnter)
+	h.mux.HandleFunc("/book/transfer", h.handleBookTransfer)
+	h.mux.HandleFunc("/books/{id}/events/stream", h.handleBookEventsStream)
+	h.mux.HandleFunc("GET /books", h.handleBooks)
+	h.mux.HandleFunc("GET /books/{id}", h.handleBook)
+	h.mux.HandleFunc("GET /books/{id}/balances", h.handleBookBalances)
 
 	return h
 }
 
+// ServeHTTP uses the Idempotency-Key of the request, or the legacy
+// X-Idempotency-Key, as the causation ID of the command it issues. Commands
+// are skipped if the book has already processed one with the same causation
+// ID, so a retried request answers as the original one did, with the ETag
+// of the current version of the book.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if idempotencyKey := r.Header.Get("X-Idempotency-Key"); idempotencyKey != "" {
-		ctx := eventstore.WithMetadata(r.Context(), eventstore.Metadata{
-			eventstore.CausationID: idempotencyKey,
-		})
-		r = r.WithContext(ctx)
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		idempotencyKey = r.Header.Get("X-Idempotency-Key")
+	}
+	if idempotencyKey != "" {
+		r = r.WithContext(
+			eventstore.WithCausationID(r.Context(), idempotencyKey))
 	}
 	h.mux.ServeHTTP(w, r)
 }
@@ -81,6 +124,10 @@ func (h *Handler) handleBookCreate(w http.ResponseWriter, r *http.Request) {
 	if _, err := h.accountingService.CreateBook(
 		r.Context(), payload.BookID, payload.BookDescription,
 	); err != nil {
+		if errors.Is(err, eventsource.ErrAggregateAlreadyExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -102,13 +149,21 @@ func (h *Handler) handleBookClose(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.accountingService.CloseBook(
-		r.Context(), payload.BookID,
-	); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	expectedVersion, err := h.ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	version, err := h.accountingService.CloseBook(
+		r.Context(), payload.BookID, expectedVersion,
+	)
+	if err != nil {
+		h.writeUpdateError(w, err, expectedVersion)
+		return
+	}
+
+	h.setETag(w, version)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -130,13 +185,22 @@ func (h *Handler) handleBookAccountAdd(w http.ResponseWriter, r *http.Request) {
 	accountType := accountingpb.AccountType(
 		accountingpb.AccountType_value[payload.AccountType])
 
-	if err := h.accountingService.AddBookAccount(
-		r.Context(), payload.BookID, payload.AccountName, accountType,
-	); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	expectedVersion, err := h.ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	version, err := h.accountingService.AddBookAccount(
+		r.Context(), payload.BookID, expectedVersion,
+		payload.AccountName, accountType,
+	)
+	if err != nil {
+		h.writeUpdateError(w, err, expectedVersion)
+		return
+	}
+
+	h.setETag(w, version)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -152,7 +216,7 @@ func (h *Handler) handleBookAccountBalance(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	balance, err := h.accountingService.GetBookAccountBalance(
+	balance, err := h.queryService.GetBookAccountBalance(
 		r.Context(), q.Get("book_id"), q.Get("account_name"),
 	)
 	if err != nil {
@@ -175,6 +239,100 @@ func (h *Handler) handleBookAccountBalance(w http.ResponseWriter, r *http.Reques
 	w.Write(data)
 }
 
+func (h *Handler) handleBooks(w http.ResponseWriter, r *http.Request) {
+	var limit int
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 0 {
+			http.Error(w, fmt.Sprintf("invalid limit: %s", l),
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	books, err := h.queryService.ListBooks(
+		r.Context(), r.URL.Query().Get("after"), limit,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		Books []application.BookSummary `json:"books"`
+	}
+	data, err := json.Marshal(response{
+		Books: books,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func (h *Handler) handleBook(w http.ResponseWriter, r *http.Request) {
+	book, version, err := h.accountingService.GetBook(
+		r.Context(), r.PathValue("id"),
+	)
+	if err != nil {
+		if errors.Is(err, model.ErrBookNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		Description string `json:"description"`
+		Closed      bool   `json:"closed"`
+	}
+	data, err := json.Marshal(response{
+		Description: book.Description(),
+		Closed:      book.Closed(),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.setETag(w, version)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func (h *Handler) handleBookBalances(w http.ResponseWriter, r *http.Request) {
+	balances, err := h.queryService.GetBookBalances(
+		r.Context(), r.PathValue("id"),
+	)
+	if err != nil {
+		if errors.Is(err, model.ErrBookNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type response struct {
+		Balances map[string]uint64 `json:"balances"`
+	}
+	data, err := json.Marshal(response{
+		Balances: balances,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
 func (h *Handler) handleBookTransactionEnter(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.NotFound(w, r)
@@ -199,17 +357,188 @@ func (h *Handler) handleBookTransactionEnter(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := h.accountingService.EnterBookTransaction(
-		r.Context(), payload.BookID, timestamp,
+	expectedVersion, err := h.ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := h.accountingService.EnterBookTransaction(
+		r.Context(), payload.BookID, expectedVersion, timestamp,
 		payload.AccountDebited, payload.AccountCredited, payload.Amount,
-	); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	)
+	if err != nil {
+		h.writeUpdateError(w, err, expectedVersion)
+		return
+	}
+
+	h.setETag(w, version)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) handleBookTransfer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var payload struct {
+		TransferID  string `json:"transfer_id"`
+		Timestamp   string `json:"timestamp"`
+		FromBookID  string `json:"from_book_id"`
+		FromAccount string `json:"from_account"`
+		ToBookID    string `json:"to_book_id"`
+		ToAccount   string `json:"to_account"`
+		Amount      uint64 `json:"amount"`
+	}
+	if err := h.unmarshalJSON(r, &payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, payload.Timestamp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The precondition applies to the sending book, the only one updated
+	// synchronously.
+	expectedVersion, err := h.ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := h.accountingService.TransferBetweenBooks(
+		r.Context(), payload.TransferID, timestamp,
+		payload.FromBookID, expectedVersion, payload.FromAccount,
+		payload.ToBookID, payload.ToAccount, payload.Amount,
+	)
+	if err != nil {
+		h.writeUpdateError(w, err, expectedVersion)
 		return
 	}
 
+	h.setETag(w, version)
 	w.WriteHeader(http.StatusOK)
 }
 
+func (h *Handler) handleBookEventsStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	var afterVersion int
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		var err error
+		afterVersion, err = strconv.Atoi(lastEventID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	if err := h.accountingService.StreamBookEvents(
+		r.Context(), r.PathValue("id"), afterVersion,
+		func(ctx context.Context, event *eventstore.Event) error {
+			return h.writeEventStreamEvent(w, rc, event)
+		},
+	); err != nil {
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", err)
+		rc.Flush()
+	}
+}
+
+func (h *Handler) writeEventStreamEvent(
+	w http.ResponseWriter, rc *http.ResponseController, event *eventstore.Event,
+) error {
+	type eventView struct {
+		ID               string              `json:"id"`
+		AggregateID      string              `json:"aggregate_id"`
+		AggregateVersion int                 `json:"aggregate_version"`
+		Timestamp        time.Time           `json:"timestamp"`
+		Metadata         eventstore.Metadata `json:"metadata"`
+		Type             string              `json:"type"`
+		Data             json.RawMessage     `json:"data"`
+	}
+	// Events are encoded by the default ProtoCodec, i.e. as JSON already.
+	payload, err := json.Marshal(eventView{
+		ID:               event.ID,
+		AggregateID:      event.AggregateID,
+		AggregateVersion: event.AggregateVersion,
+		Timestamp:        event.Timestamp,
+		Metadata:         event.Metadata,
+		Type:             event.Type,
+		Data:             event.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	// A client that stops reading must not hold the subscription forever.
+	if err := rc.SetWriteDeadline(
+		time.Now().Add(eventStreamWriteTimeout),
+	); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return fmt.Errorf("set write deadline: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n",
+		event.AggregateVersion, payload); err != nil {
+		return fmt.Errorf("write event: %w", err)
+	}
+
+	return rc.Flush()
+}
+
+// setETag sets the ETag to the version of the book, which clients send back
+// in If-Match to make sure that the book has not changed in the meantime.
+func (h *Handler) setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// ifMatchVersion returns the version expected by If-Match, or 0 if the
+// request is unconditional.
+func (h *Handler) ifMatchVersion(r *http.Request) (int, error) {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+
+	etag, err := strconv.Unquote(ifMatch)
+	if err != nil {
+		return 0, fmt.Errorf("invalid If-Match: %s", ifMatch)
+	}
+
+	version, err := strconv.Atoi(etag)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid If-Match: %s", ifMatch)
+	}
+
+	return version, nil
+}
+
+func (h *Handler) writeUpdateError(
+	w http.ResponseWriter, err error, expectedVersion int,
+) {
+	switch {
+	case errors.Is(err, eventstore.ErrConcurrentUpdate) && expectedVersion > 0:
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, eventsource.ErrAggregateDoesNotExist):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (h *Handler) unmarshalJSON(r *http.Request, dest any) error {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -0,0 +1,396 @@
+package httpadapter_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/examples/accounting/application"
+	"github.com/rnovatorov/go-eventsource/examples/accounting/httpadapter"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoreinmemory"
+)
+
+// noQueries serves no projections, which the tests do not need.
+type noQueries struct{}
+
+var errNoQueries = errors.New("no queries")
+
+func (noQueries) ListBooks(
+	context.Context, string, int,
+) ([]application.BookSummary, error) {
+	return nil, errNoQueries
+}
+
+func (noQueries) GetBookAccountBalance(
+	context.Context, string, string,
+) (uint64, error) {
+	return 0, errNoQueries
+}
+
+func (noQueries) GetBookBalances(
+	context.Context, string,
+) (map[string]uint64, error) {
+	return nil, errNoQueries
+}
+
+func newTestHandler(t testing.TB) http.Handler {
+	t.Helper()
+
+	app := application.New(application.Params{
+		EventStore: eventstoreinmemory.New(),
+	})
+	h := httpadapter.NewHandler(app, noQueries{})
+
+	resp := serve(h, http.MethodPost, "/book/create", "",
+		`{"book_id": "b", "book_description": "d"}`)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("create book: got %d: %s", resp.Code, resp.Body)
+	}
+
+	return h
+}
+
+func serve(
+	h http.Handler, method string, target string, ifMatch string, body string,
+) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if ifMatch != "" {
+		r.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestIfMatch(t *testing.T) {
+	h := newTestHandler(t)
+
+	resp := serve(h, http.MethodGet, "/books/b", "", "")
+	if resp.Code != http.StatusOK || resp.Header().Get("ETag") != `"1"` {
+		t.Fatalf("get book: got %d with ETag %s, want 200 with \"1\"",
+			resp.Code, resp.Header().Get("ETag"))
+	}
+
+	const addAccount = `{"book_id": "b", "account_name": "cash", "account_type": "ASSET"}`
+	resp = serve(h, http.MethodPost, "/book/account/add", `"1"`, addAccount)
+	if resp.Code != http.StatusOK || resp.Header().Get("ETag") != `"2"` {
+		t.Fatalf("add account: got %d with ETag %s, want 200 with \"2\": %s",
+			resp.Code, resp.Header().Get("ETag"), resp.Body)
+	}
+
+	// The book has changed since version 1.
+	resp = serve(h, http.MethodPost, "/book/close", `"1"`, `{"book_id": "b"}`)
+	if resp.Code != http.StatusPreconditionFailed {
+		t.Fatalf("close at stale version: got %d, want 412: %s",
+			resp.Code, resp.Body)
+	}
+
+	resp = serve(h, http.MethodGet, "/books/b", "", "")
+	var book struct {
+		Closed bool `json:"closed"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &book); err != nil {
+		t.Fatalf("unmarshal book: %v", err)
+	}
+	if book.Closed || resp.Header().Get("ETag") != `"2"` {
+		t.Fatalf("book after failed close: got closed %t and ETag %s",
+			book.Closed, resp.Header().Get("ETag"))
+	}
+
+	resp = serve(h, http.MethodPost, "/book/close", `"2"`, `{"book_id": "b"}`)
+	if resp.Code != http.StatusOK || resp.Header().Get("ETag") != `"3"` {
+		t.Fatalf("close at current version: got %d with ETag %s: %s",
+			resp.Code, resp.Header().Get("ETag"), resp.Body)
+	}
+}
+
+func TestIfMatchInvalid(t *testing.T) {
+	h := newTestHandler(t)
+
+	for _, ifMatch := range []string{"1", `"x"`, `"0"`} {
+		resp := serve(h, http.MethodPost, "/book/close", ifMatch,
+			`{"book_id": "b"}`)
+		if resp.Code != http.StatusBadRequest {
+			t.Fatalf("If-Match %s: got %d, want 400", ifMatch, resp.Code)
+		}
+	}
+}
+
+func TestWithoutIfMatch(t *testing.T) {
+	h := newTestHandler(t)
+
+	resp := serve(h, http.MethodPost, "/book/close", "", `{"book_id": "b"}`)
+	if resp.Code != http.StatusOK || resp.Header().Get("ETag") != `"2"` {
+		t.Fatalf("close: got %d with ETag %s, want 200 with \"2\": %s",
+			resp.Code, resp.Header().Get("ETag"), resp.Body)
+	}
+}
+
+func serveWithIdempotencyKey(
+	h http.Handler, target string, key string, body string,
+) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	r.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// serveReplayed serves the request twice with the same Idempotency-Key and
+// fails unless the replay answers as the original request did.
+func serveReplayed(
+	t testing.TB, h http.Handler, target string, key string, body string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+
+	resp := serveWithIdempotencyKey(h, target, key, body)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("%s: got %d: %s", target, resp.Code, resp.Body)
+	}
+
+	replay := serveWithIdempotencyKey(h, target, key, body)
+	if replay.Code != resp.Code ||
+		replay.Header().Get("ETag") != resp.Header().Get("ETag") ||
+		replay.Body.String() != resp.Body.String() {
+		t.Fatalf("%s replayed: got %d with ETag %s: %q, want %d with %s: %q",
+			target, replay.Code, replay.Header().Get("ETag"), replay.Body,
+			resp.Code, resp.Header().Get("ETag"), resp.Body)
+	}
+
+	return resp
+}
+
+func TestCreateBookReplay(t *testing.T) {
+	eventStore := eventstoreinmemory.New()
+	h := httpadapter.NewHandler(application.New(application.Params{
+		EventStore: eventStore,
+	}), noQueries{})
+
+	const create = `{"book_id": "r", "book_description": "d"}`
+	serveReplayed(t, h, "/book/create", "k", create)
+
+	resp := serveWithIdempotencyKey(h, "/book/create", "other", create)
+	if resp.Code != http.StatusConflict {
+		t.Fatalf("create with another key: got %d, want 409: %s",
+			resp.Code, resp.Body)
+	}
+
+	events, err := eventStore.ListEvents(context.Background(), "r")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+}
+
+func TestEnterBookTransactionReplay(t *testing.T) {
+	eventStore := eventstoreinmemory.New()
+	h := httpadapter.NewHandler(application.New(application.Params{
+		EventStore: eventStore,
+	}), noQueries{})
+
+	serveReplayed(t, h, "/book/create", "create",
+		`{"book_id": "r", "book_description": "d"}`)
+	serveReplayed(t, h, "/book/account/add", "add-capital",
+		`{"book_id": "r", "account_name": "capital", "account_type": "CAPITAL"}`)
+	serveReplayed(t, h, "/book/account/add", "add-cash",
+		`{"book_id": "r", "account_name": "cash", "account_type": "ASSET"}`)
+
+	const enter = `{"book_id": "r", "timestamp": "2024-01-01T00:00:00Z",
+		"account_debited": "cash", "account_credited": "capital", "amount": 100}`
+	resp := serveReplayed(t, h, "/book/transaction/enter", "enter", enter)
+	if got := resp.Header().Get("ETag"); got != `"4"` {
+		t.Fatalf("ETag: got %s, want \"4\"", got)
+	}
+
+	events, err := eventStore.ListEvents(context.Background(), "r")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4", len(events))
+	}
+}
+
+// storeSubscriber subscribes to aggregates of an in-memory store, which does
+// not implement SubscribeAggregate itself, and closes done once no
+// subscription is left.
+type storeSubscriber struct {
+	store  *eventstoreinmemory.Store
+	active atomic.Int32
+	done   chan struct{}
+}
+
+func (s *storeSubscriber) SubscribeAggregate(
+	ctx context.Context, aggregateID string, afterVersion int,
+	handler eventstore.EventHandler,
+) error {
+	s.active.Add(1)
+	defer func() {
+		if s.active.Add(-1) == 0 {
+			close(s.done)
+		}
+	}()
+
+	events, err := s.store.SubscribeAll(ctx, 0)
+	if err != nil {
+		return err
+	}
+	for event := range events {
+		if event.AggregateID != aggregateID ||
+			event.AggregateVersion <= afterVersion {
+			continue
+		}
+		if err := handler(ctx, event); err != nil {
+			return fmt.Errorf("event handler: %w", err)
+		}
+	}
+	return nil
+}
+
+// openEventStream requests the event stream of book b, resuming after
+// lastEventID unless it is empty.
+func openEventStream(
+	t testing.TB, ctx context.Context, url string, lastEventID string,
+) *bufio.Reader {
+	t.Helper()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		url+"/books/b/events/stream", nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if lastEventID != "" {
+		r.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatalf("open event stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK ||
+		resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("open event stream: got %d with %s", resp.StatusCode,
+			resp.Header.Get("Content-Type"))
+	}
+
+	return bufio.NewReader(resp.Body)
+}
+
+// readStreamEvent reads the next event of the stream, returning its ID and
+// the aggregate version it renders.
+func readStreamEvent(t testing.TB, stream *bufio.Reader) (string, int) {
+	t.Helper()
+
+	var id string
+	var event struct {
+		AggregateVersion int `json:"aggregate_version"`
+	}
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return id, event.AggregateVersion
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal(
+				[]byte(strings.TrimPrefix(line, "data: ")), &event,
+			); err != nil {
+				t.Fatalf("unmarshal event: %v", err)
+			}
+		default:
+			t.Fatalf("unexpected line: %q", line)
+		}
+	}
+}
+
+func TestBookEventsStream(t *testing.T) {
+	eventStore := eventstoreinmemory.New()
+	subscriber := &storeSubscriber{
+		store: eventStore,
+		done:  make(chan struct{}),
+	}
+	h := httpadapter.NewHandler(application.New(application.Params{
+		EventStore:          eventStore,
+		AggregateSubscriber: subscriber,
+	}), noQueries{})
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	if resp := serve(h, http.MethodPost, "/book/create", "",
+		`{"book_id": "b", "book_description": "d"}`,
+	); resp.Code != http.StatusOK {
+		t.Fatalf("create book: got %d: %s", resp.Code, resp.Body)
+	}
+
+	expect := func(stream *bufio.Reader, want int) {
+		t.Helper()
+
+		id, version := readStreamEvent(t, stream)
+		if id != strconv.Itoa(want) || version != want {
+			t.Fatalf("event: got ID %s of version %d, want %d",
+				id, version, want)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The existing event is replayed, then new ones are pushed live.
+	stream := openEventStream(t, ctx, server.URL, "")
+	expect(stream, 1)
+	if resp := serve(h, http.MethodPost, "/book/account/add", "",
+		`{"book_id": "b", "account_name": "cash", "account_type": "ASSET"}`,
+	); resp.Code != http.StatusOK {
+		t.Fatalf("add account: got %d: %s", resp.Code, resp.Body)
+	}
+	expect(stream, 2)
+
+	// A reconnecting client only gets the events after the last it saw.
+	resumed := openEventStream(t, ctx, server.URL, "1")
+	expect(resumed, 2)
+	if resp := serve(h, http.MethodPost, "/book/account/add", "",
+		`{"book_id": "b", "account_name": "capital", "account_type": "CAPITAL"}`,
+	); resp.Code != http.StatusOK {
+		t.Fatalf("add account: got %d: %s", resp.Code, resp.Body)
+	}
+	expect(stream, 3)
+	expect(resumed, 3)
+
+	// Both subscriptions end once the clients disconnect.
+	cancel()
+	select {
+	case <-subscriber.done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("%d subscriptions left after disconnect",
+			subscriber.active.Load())
+	}
+}
+
+func TestBookEventsStreamInvalidLastEventID(t *testing.T) {
+	h := newTestHandler(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/books/b/events/stream", nil)
+	r.Header.Set("Last-Event-ID", "x")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,99 @@
+package inmemoryadapter
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/rnovatorov/go-eventsource/examples/accounting/accountingpb"
+	"github.com/rnovatorov/go-eventsource/examples/accounting/model"
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/projection"
+)
+
+var (
+	_ projection.Projection = (*BookBalances)(nil)
+	_ projection.Resettable = (*BookBalances)(nil)
+)
+
+// BookBalances projects the balances of the accounts of every book.
+type BookBalances struct {
+	mu       sync.RWMutex
+	balances map[string]map[string]uint64
+}
+
+func NewBookBalances() *BookBalances {
+	return &BookBalances{
+		balances: make(map[string]map[string]uint64),
+	}
+}
+
+func (p *BookBalances) Name() string {
+	return "book_balances"
+}
+
+func (p *BookBalances) Handle(
+	ctx context.Context, event *eventstore.Event,
+) error {
+	if event.Type == eventstore.TombstoneEventType {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		delete(p.balances, event.AggregateID)
+
+		return nil
+	}
+
+	data, err := eventsource.ProtoCodec{}.Unmarshal(event.Type, event.Data)
+	if err != nil {
+		return fmt.Errorf("unmarshal data: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch d := data.(type) {
+	case *accountingpb.BookCreated:
+		p.balances[event.AggregateID] = make(map[string]uint64)
+	case *accountingpb.BookAccountAdded:
+		p.balances[event.AggregateID][d.Name] = 0
+	case *accountingpb.BookTransactionEntered:
+		p.balances[event.AggregateID][d.AccountDebited] = d.AccountDebitedNewBalance
+		p.balances[event.AggregateID][d.AccountCredited] = d.AccountCreditedNewBalance
+	case *accountingpb.BookTransferSent:
+		p.balances[event.AggregateID][d.Account] = d.AccountNewBalance
+	case *accountingpb.BookTransferReceived:
+		p.balances[event.AggregateID][d.Account] = d.AccountNewBalance
+	case *accountingpb.BookTransferCancelled:
+		p.balances[event.AggregateID][d.Account] = d.AccountNewBalance
+	}
+
+	return nil
+}
+
+func (p *BookBalances) Reset(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	clear(p.balances)
+
+	return nil
+}
+
+// GetBookBalances returns the balances of the accounts of the book by
+// account name.
+func (p *BookBalances) GetBookBalances(
+	ctx context.Context, bookID string,
+) (map[string]uint64, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	balances, ok := p.balances[bookID]
+	if !ok {
+		return nil, model.ErrBookNotFound
+	}
+
+	return maps.Clone(balances), nil
+}
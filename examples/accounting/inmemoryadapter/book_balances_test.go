@@ -0,0 +1,78 @@
+package inmemoryadapter_test
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/examples/accounting/accountingpb"
+	"github.com/rnovatorov/go-eventsource/examples/accounting/inmemoryadapter"
+	"github.com/rnovatorov/go-eventsource/examples/accounting/model"
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoreinmemory"
+	"github.com/rnovatorov/go-eventsource/pkg/projection"
+	"github.com/rnovatorov/go-eventsource/pkg/projection/projectioninmemory"
+)
+
+func TestBookBalances(t *testing.T) {
+	ctx := context.Background()
+	store := eventstoreinmemory.New()
+	repo := eventsource.NewAggregateRepository[model.Book](store)
+
+	if _, err := repo.Create(ctx, "b", model.BookCreate{}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for _, cmd := range []eventsource.Command{
+		model.BookAccountAdd{
+			AccountName: "equity",
+			AccountType: accountingpb.AccountType_CAPITAL,
+		},
+		model.BookAccountAdd{
+			AccountName: "cash",
+			AccountType: accountingpb.AccountType_ASSET,
+		},
+		model.BookAccountAdd{
+			AccountName: "rent",
+			AccountType: accountingpb.AccountType_EXPENSE,
+		},
+		enter("cash", "equity", 100),
+		enter("rent", "cash", 30),
+	} {
+		if _, err := repo.Update(ctx, "b", cmd); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+
+	balances := inmemoryadapter.NewBookBalances()
+	runner := projection.NewRunner(store, projectioninmemory.NewCheckpointStore())
+	if err := runner.Rebuild(ctx, balances); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+
+	got, err := balances.GetBookBalances(ctx, "b")
+	if err != nil {
+		t.Fatalf("get book balances: %v", err)
+	}
+	want := map[string]uint64{"equity": 100, "cash": 70, "rent": 30}
+	if !maps.Equal(got, want) {
+		t.Fatalf("balances: got %v, want %v", got, want)
+	}
+
+	if _, err := balances.GetBookBalances(ctx, "other"); !errors.Is(
+		err, model.ErrBookNotFound,
+	) {
+		t.Fatalf("get other book balances: got %v, want %v",
+			err, model.ErrBookNotFound)
+	}
+}
+
+func enter(debited, credited string, amount uint64) model.BookTransactionEnter {
+	return model.BookTransactionEnter{Transaction: model.Transaction{
+		Timestamp:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		AccountDebited:  debited,
+		AccountCredited: credited,
+		Amount:          amount,
+	}}
+}
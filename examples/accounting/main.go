@@ -14,9 +14,15 @@ import (
 
 	"github.com/rnovatorov/go-eventsource/examples/accounting/application"
 	"github.com/rnovatorov/go-eventsource/examples/accounting/httpadapter"
+	"github.com/rnovatorov/go-eventsource/examples/accounting/inmemoryadapter"
 	"github.com/rnovatorov/go-eventsource/examples/accounting/postgresadapter"
 	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
 	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstorepostgres"
+	"github.com/rnovatorov/go-eventsource/pkg/processmanager"
+	"github.com/rnovatorov/go-eventsource/pkg/processmanager/processmanagerpostgres"
+	"github.com/rnovatorov/go-eventsource/pkg/projection"
+	"github.com/rnovatorov/go-eventsource/pkg/projection/projectioninmemory"
+	"github.com/rnovatorov/go-eventsource/pkg/projection/projectionpostgres"
 )
 
 func main() {
@@ -45,11 +51,47 @@ func run(ctx context.Context) error {
 		eventstorepostgres.WithSaveEventHook(postgresadapter.UpdateProjections))
 	defer eventStore.Stop()
 
+	bookBalances := inmemoryadapter.NewBookBalances()
+	bookSummaries := projectioninmemory.NewReadModelStore[application.BookSummary]()
+	projectionRunner := projection.NewRunner(eventStore,
+		projectioninmemory.NewCheckpointStore())
+	go func() {
+		if err := projectionRunner.Run(ctx, bookBalances); err != nil {
+			logger.Error("book balances projection stopped",
+				slog.String("error", err.Error()))
+		}
+	}()
+	go func() {
+		if err := projectionRunner.Run(
+			ctx, application.NewBookSummaries(bookSummaries),
+		); err != nil {
+			logger.Error("book summaries projection stopped",
+				slog.String("error", err.Error()))
+		}
+	}()
+
 	app := application.New(application.Params{
-		EventStore:        eventStore,
+		EventStore:          eventStore,
+		AggregateSubscriber: eventStore,
+	})
+	queryService := application.NewQueryService(application.QueryServiceParams{
 		ProjectionQueries: postgresadapter.NewProjectionQueries(pool),
+		BookBalances:      bookBalances,
+		BookSummaries:     bookSummaries,
 	})
 
+	processCoordinator := processmanager.NewCoordinator(eventStore,
+		app.CommandBus(), processmanagerpostgres.NewStore(pool),
+		projectionpostgres.NewCheckpointStore(pool))
+	go func() {
+		if err := processCoordinator.Run(
+			ctx, application.NewTransferProcess(),
+		); err != nil {
+			logger.Error("transfer process manager stopped",
+				slog.String("error", err.Error()))
+		}
+	}()
+
 	if err := eventStore.Subscribe(ctx, "mysub", func(
 		ctx context.Context, event *eventstore.Event,
 	) error {
@@ -61,7 +103,7 @@ func run(ctx context.Context) error {
 
 	server := &http.Server{
 		Addr:        os.Getenv("HTTP_SERVER_LISTEN_ADDRESS"),
-		Handler:     httpadapter.NewHandler(app),
+		Handler:     httpadapter.NewHandler(app, queryService),
 		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 	go func() {
@@ -0,0 +1,5 @@
+BEGIN;
+
+CREATE INDEX es_events_correlation_id_idx ON es_events ((metadata ->> 'X-Correlation-ID'));
+
+END;
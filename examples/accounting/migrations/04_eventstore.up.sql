@@ -0,0 +1,9 @@
+BEGIN;
+
+CREATE TABLE es_aggregate_tags (
+    tag TEXT NOT NULL,
+    aggregate_id TEXT NOT NULL,
+    PRIMARY KEY (tag, aggregate_id)
+);
+
+END;
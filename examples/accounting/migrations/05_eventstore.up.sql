@@ -0,0 +1,11 @@
+BEGIN;
+
+CREATE TABLE es_snapshots (
+    aggregate_id TEXT NOT NULL,
+    aggregate_version INT NOT NULL,
+    timestamp TIMESTAMPTZ NOT NULL,
+    data JSONB NOT NULL,
+    PRIMARY KEY (aggregate_id, aggregate_version)
+);
+
+END;
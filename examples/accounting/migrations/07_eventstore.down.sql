@@ -0,0 +1,45 @@
+BEGIN;
+
+ALTER TABLE es_aggregate_tags
+    DROP CONSTRAINT es_aggregate_tags_pkey;
+
+ALTER TABLE es_aggregate_tags
+    ADD PRIMARY KEY (tag, aggregate_id);
+
+ALTER TABLE es_snapshots
+    DROP CONSTRAINT es_snapshots_pkey;
+
+ALTER TABLE es_snapshots
+    ADD PRIMARY KEY (aggregate_id, aggregate_version);
+
+ALTER TABLE es_events
+    DROP CONSTRAINT es_events_tenant_id_aggregate_id_fkey;
+
+ALTER TABLE es_events
+    DROP CONSTRAINT es_events_tenant_id_aggregate_id_aggregate_version_key;
+
+ALTER TABLE es_aggregates
+    DROP CONSTRAINT es_aggregates_pkey;
+
+ALTER TABLE es_aggregates
+    ADD PRIMARY KEY (id);
+
+ALTER TABLE es_events
+    ADD UNIQUE (aggregate_id, aggregate_version);
+
+ALTER TABLE es_events
+    ADD FOREIGN KEY (aggregate_id) REFERENCES es_aggregates (id);
+
+ALTER TABLE es_aggregate_tags
+    DROP COLUMN tenant_id;
+
+ALTER TABLE es_snapshots
+    DROP COLUMN tenant_id;
+
+ALTER TABLE es_events
+    DROP COLUMN tenant_id;
+
+ALTER TABLE es_aggregates
+    DROP COLUMN tenant_id;
+
+END;
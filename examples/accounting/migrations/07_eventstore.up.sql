@@ -0,0 +1,45 @@
+BEGIN;
+
+ALTER TABLE es_aggregates
+    ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE es_events
+    ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE es_snapshots
+    ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE es_aggregate_tags
+    ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE es_events
+    DROP CONSTRAINT es_events_aggregate_id_fkey;
+
+ALTER TABLE es_events
+    DROP CONSTRAINT es_events_aggregate_id_aggregate_version_key;
+
+ALTER TABLE es_aggregates
+    DROP CONSTRAINT es_aggregates_pkey;
+
+ALTER TABLE es_aggregates
+    ADD PRIMARY KEY (tenant_id, id);
+
+ALTER TABLE es_events
+    ADD UNIQUE (tenant_id, aggregate_id, aggregate_version);
+
+ALTER TABLE es_events
+    ADD FOREIGN KEY (tenant_id, aggregate_id) REFERENCES es_aggregates (tenant_id, id);
+
+ALTER TABLE es_snapshots
+    DROP CONSTRAINT es_snapshots_pkey;
+
+ALTER TABLE es_snapshots
+    ADD PRIMARY KEY (tenant_id, aggregate_id, aggregate_version);
+
+ALTER TABLE es_aggregate_tags
+    DROP CONSTRAINT es_aggregate_tags_pkey;
+
+ALTER TABLE es_aggregate_tags
+    ADD PRIMARY KEY (tenant_id, tag, aggregate_id);
+
+END;
@@ -0,0 +1,8 @@
+BEGIN;
+
+CREATE TABLE es_projection_checkpoints (
+    name TEXT PRIMARY KEY,
+    position BIGINT NOT NULL
+);
+
+END;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX es_events_event_type_idx;
+
+END;
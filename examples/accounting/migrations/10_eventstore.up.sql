@@ -0,0 +1,5 @@
+BEGIN;
+
+CREATE INDEX es_events_event_type_idx ON es_events (event_type, sequence_number);
+
+END;
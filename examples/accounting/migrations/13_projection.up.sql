@@ -0,0 +1,13 @@
+BEGIN;
+
+CREATE TABLE es_projection_dead_letters (
+    projection TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    global_position BIGINT NOT NULL,
+    error TEXT NOT NULL,
+    attempts INT NOT NULL,
+    timestamp TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (projection, event_id)
+);
+
+END;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE es_events
+    ADD COLUMN data_compression TEXT NOT NULL DEFAULT '';
+
+END;
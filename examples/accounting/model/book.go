@@ -10,11 +10,24 @@ import (
 )
 
 type Book struct {
-	created      bool
-	closed       bool
-	description  string
-	transactions []Transaction
-	accounts     map[string]*Account
+	created           bool
+	closed            bool
+	description       string
+	transactions      []Transaction
+	accounts          map[string]*Account
+	transfersSent     map[string]*transferSent
+	transfersReceived map[string]struct{}
+
+	// transactionsByAccount indexes transactions by the accounts involved.
+	// It is built by AfterLoad rather than by every state change applied
+	// during replay, and reset by those applied after it.
+	transactionsByAccount map[string][]Transaction
+}
+
+type transferSent struct {
+	account string
+	amount  uint64
+	settled bool
 }
 
 func (b *Book) Closed() bool {
@@ -33,6 +46,34 @@ func (b *Book) AccountByName(name string) (*Account, error) {
 	return nil, ErrAccountNotFound
 }
 
+// AccountTransactions returns the transactions debiting or crediting the
+// account, in the order they were entered.
+func (b *Book) AccountTransactions(name string) ([]Transaction, error) {
+	if _, ok := b.accounts[name]; !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	if b.transactionsByAccount == nil {
+		b.indexTransactions()
+	}
+
+	return b.transactionsByAccount[name], nil
+}
+
+func (b *Book) AfterLoad() {
+	b.indexTransactions()
+}
+
+func (b *Book) indexTransactions() {
+	b.transactionsByAccount = make(map[string][]Transaction)
+	for _, t := range b.transactions {
+		b.transactionsByAccount[t.AccountDebited] = append(
+			b.transactionsByAccount[t.AccountDebited], t)
+		b.transactionsByAccount[t.AccountCredited] = append(
+			b.transactionsByAccount[t.AccountCredited], t)
+	}
+}
+
 func (b *Book) ProcessCommand(
 	command eventsource.Command,
 ) (eventsource.StateChanges, error) {
@@ -45,6 +86,14 @@ func (b *Book) ProcessCommand(
 		return b.processAccountAdd(cmd)
 	case BookTransactionEnter:
 		return b.processTransactionEnter(cmd)
+	case BookTransferSend:
+		return b.processTransferSend(cmd)
+	case BookTransferReceive:
+		return b.processTransferReceive(cmd)
+	case BookTransferComplete:
+		return b.processTransferComplete(cmd)
+	case BookTransferCancel:
+		return b.processTransferCancel(cmd)
 	default:
 		return nil, fmt.Errorf("%w: %T", eventsource.ErrCommandUnknown, cmd)
 	}
@@ -85,14 +134,6 @@ func (b *Book) processAccountAdd(cmd BookAccountAdd) (eventsource.StateChanges,
 		return nil, ErrAccountNameConflict
 	}
 
-	if cmd.AccountName == "" {
-		return nil, ErrAccountNameEmpty
-	}
-
-	if cmd.AccountType == accountingpb.AccountType_UNKNOWN {
-		return nil, ErrAccountTypeUnknown
-	}
-
 	return eventsource.StateChanges{
 		&accountingpb.BookAccountAdded{
 			Name: cmd.AccountName,
@@ -140,7 +181,114 @@ func (b *Book) processTransactionEnter(
 	}, nil
 }
 
-func (b *Book) ApplyStateChange(stateChange eventsource.StateChange) {
+func (b *Book) processTransferSend(
+	cmd BookTransferSend,
+) (eventsource.StateChanges, error) {
+	if b.closed {
+		return nil, ErrBookClosed
+	}
+
+	if _, ok := b.transfersSent[cmd.TransferID]; ok {
+		return nil, ErrTransferIDConflict
+	}
+
+	account, ok := b.accounts[cmd.Account]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	accountNewBalance, err := account.canCredit(cmd.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccountCreditDeclined, err)
+	}
+
+	return eventsource.StateChanges{
+		&accountingpb.BookTransferSent{
+			TransferId:        cmd.TransferID,
+			Timestamp:         timestamppb.New(cmd.Timestamp),
+			Account:           cmd.Account,
+			ToBookId:          cmd.ToBookID,
+			ToAccount:         cmd.ToAccount,
+			Amount:            cmd.Amount,
+			AccountNewBalance: accountNewBalance,
+		},
+	}, nil
+}
+
+func (b *Book) processTransferReceive(
+	cmd BookTransferReceive,
+) (eventsource.StateChanges, error) {
+	if b.closed {
+		return nil, ErrBookClosed
+	}
+
+	if _, ok := b.transfersReceived[cmd.TransferID]; ok {
+		return nil, ErrTransferAlreadyReceived
+	}
+
+	account, ok := b.accounts[cmd.Account]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	accountNewBalance, err := account.canDebit(cmd.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccountDebitDeclined, err)
+	}
+
+	return eventsource.StateChanges{
+		&accountingpb.BookTransferReceived{
+			TransferId:        cmd.TransferID,
+			Timestamp:         timestamppb.New(cmd.Timestamp),
+			Account:           cmd.Account,
+			FromBookId:        cmd.FromBookID,
+			Amount:            cmd.Amount,
+			AccountNewBalance: accountNewBalance,
+		},
+	}, nil
+}
+
+// Transfers are settled even if the book has been closed since they were
+// sent, so that the books involved end up consistent.
+func (b *Book) processTransferComplete(
+	cmd BookTransferComplete,
+) (eventsource.StateChanges, error) {
+	transfer, ok := b.transfersSent[cmd.TransferID]
+	if !ok || transfer.settled {
+		return nil, ErrTransferNotFound
+	}
+
+	return eventsource.StateChanges{
+		&accountingpb.BookTransferCompleted{
+			TransferId: cmd.TransferID,
+		},
+	}, nil
+}
+
+func (b *Book) processTransferCancel(
+	cmd BookTransferCancel,
+) (eventsource.StateChanges, error) {
+	transfer, ok := b.transfersSent[cmd.TransferID]
+	if !ok || transfer.settled {
+		return nil, ErrTransferNotFound
+	}
+
+	accountNewBalance, err := b.accounts[transfer.account].canDebit(transfer.amount)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccountDebitDeclined, err)
+	}
+
+	return eventsource.StateChanges{
+		&accountingpb.BookTransferCancelled{
+			TransferId:        cmd.TransferID,
+			Account:           transfer.account,
+			Amount:            transfer.amount,
+			AccountNewBalance: accountNewBalance,
+		},
+	}, nil
+}
+
+func (b *Book) ApplyStateChange(stateChange eventsource.StateChange) error {
 	switch sc := stateChange.(type) {
 	case *accountingpb.BookCreated:
 		b.applyCreated(sc)
@@ -150,15 +298,27 @@ func (b *Book) ApplyStateChange(stateChange eventsource.StateChange) {
 		b.applyAccountAdded(sc)
 	case *accountingpb.BookTransactionEntered:
 		b.applyTransactionEntered(sc)
+	case *accountingpb.BookTransferSent:
+		b.applyTransferSent(sc)
+	case *accountingpb.BookTransferReceived:
+		b.applyTransferReceived(sc)
+	case *accountingpb.BookTransferCompleted:
+		b.applyTransferCompleted(sc)
+	case *accountingpb.BookTransferCancelled:
+		b.applyTransferCancelled(sc)
 	default:
-		panic(fmt.Sprintf("unexpected state change: %T", sc))
+		return fmt.Errorf("%w: %T", eventsource.ErrUnknownStateChange, sc)
 	}
+
+	return nil
 }
 
 func (b *Book) applyCreated(sc *accountingpb.BookCreated) {
 	b.created = true
 	b.description = sc.Description
 	b.accounts = make(map[string]*Account)
+	b.transfersSent = make(map[string]*transferSent)
+	b.transfersReceived = make(map[string]struct{})
 }
 
 func (b *Book) applyClosed(*accountingpb.BookClosed) {
@@ -183,4 +343,30 @@ func (b *Book) applyTransactionEntered(sc *accountingpb.BookTransactionEntered)
 		AccountCredited: sc.AccountCredited,
 		Amount:          sc.Amount,
 	})
+	b.transactionsByAccount = nil
+}
+
+func (b *Book) applyTransferSent(sc *accountingpb.BookTransferSent) {
+	b.accounts[sc.Account].balance = sc.AccountNewBalance
+
+	b.transfersSent[sc.TransferId] = &transferSent{
+		account: sc.Account,
+		amount:  sc.Amount,
+	}
+}
+
+func (b *Book) applyTransferReceived(sc *accountingpb.BookTransferReceived) {
+	b.accounts[sc.Account].balance = sc.AccountNewBalance
+
+	b.transfersReceived[sc.TransferId] = struct{}{}
+}
+
+func (b *Book) applyTransferCompleted(sc *accountingpb.BookTransferCompleted) {
+	b.transfersSent[sc.TransferId].settled = true
+}
+
+func (b *Book) applyTransferCancelled(sc *accountingpb.BookTransferCancelled) {
+	b.accounts[sc.Account].balance = sc.AccountNewBalance
+
+	b.transfersSent[sc.TransferId].settled = true
 }
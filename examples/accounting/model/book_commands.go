@@ -1,6 +1,10 @@
 package model
 
-import "github.com/rnovatorov/go-eventsource/examples/accounting/accountingpb"
+import (
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/examples/accounting/accountingpb"
+)
 
 type BookCreate struct {
 	Description string
@@ -13,6 +17,90 @@ type BookAccountAdd struct {
 	AccountType accountingpb.AccountType
 }
 
+func (cmd BookAccountAdd) Validate() error {
+	if cmd.AccountName == "" {
+		return ErrAccountNameEmpty
+	}
+
+	if cmd.AccountType == accountingpb.AccountType_UNKNOWN {
+		return ErrAccountTypeUnknown
+	}
+
+	return nil
+}
+
 type BookTransactionEnter struct {
 	Transaction Transaction
 }
+
+func (cmd BookTransactionEnter) Validate() error {
+	if cmd.Transaction.Amount == 0 {
+		return ErrTransactionAmountZero
+	}
+
+	if cmd.Transaction.AccountDebited == cmd.Transaction.AccountCredited {
+		return ErrTransactionAccountsSame
+	}
+
+	return nil
+}
+
+// BookTransferSend starts a transfer to another book by taking the amount
+// out of the account, see BookTransferReceive.
+type BookTransferSend struct {
+	TransferID string
+	Timestamp  time.Time
+	Account    string
+	ToBookID   string
+	ToAccount  string
+	Amount     uint64
+}
+
+func (cmd BookTransferSend) Validate() error {
+	if cmd.TransferID == "" {
+		return ErrTransferIDEmpty
+	}
+
+	if cmd.ToBookID == "" || cmd.ToAccount == "" {
+		return ErrTransferTargetEmpty
+	}
+
+	if cmd.Amount == 0 {
+		return ErrTransferAmountZero
+	}
+
+	return nil
+}
+
+// BookTransferReceive puts the amount of a transfer sent by another book
+// into the account. The sending book then either completes or cancels the
+// transfer, depending on whether it was received.
+type BookTransferReceive struct {
+	TransferID string
+	Timestamp  time.Time
+	FromBookID string
+	Account    string
+	Amount     uint64
+}
+
+func (cmd BookTransferReceive) Validate() error {
+	if cmd.TransferID == "" {
+		return ErrTransferIDEmpty
+	}
+
+	if cmd.Amount == 0 {
+		return ErrTransferAmountZero
+	}
+
+	return nil
+}
+
+type BookTransferComplete struct {
+	TransferID string
+}
+
+// BookTransferCancel puts the amount of a transfer that could not be
+// received back into the account it was taken from.
+type BookTransferCancel struct {
+	TransferID string
+}
@@ -0,0 +1,186 @@
+package model_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/rnovatorov/go-eventsource/examples/accounting/accountingpb"
+	"github.com/rnovatorov/go-eventsource/examples/accounting/model"
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource/eventsourcetest"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoreinmemory"
+)
+
+func TestBookLoadVersion(t *testing.T) {
+	ctx := context.Background()
+	repo := eventsource.NewAggregateRepository[model.Book](
+		eventstoreinmemory.New())
+
+	if _, err := repo.Create(ctx, "b", model.BookCreate{}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for _, cmd := range []eventsource.Command{
+		model.BookAccountAdd{
+			AccountName: "equity",
+			AccountType: accountingpb.AccountType_CAPITAL,
+		},
+		model.BookAccountAdd{
+			AccountName: "cash",
+			AccountType: accountingpb.AccountType_ASSET,
+		},
+		enter("cash", "equity", 100),
+		enter("cash", "equity", 50),
+	} {
+		if _, err := repo.Update(ctx, "b", cmd); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+
+	for version, want := range map[int]uint64{3: 0, 4: 100, 5: 150} {
+		book, err := repo.LoadVersion(ctx, "b", version)
+		if err != nil {
+			t.Fatalf("load version %d: %v", version, err)
+		}
+		if book.Version() != version {
+			t.Fatalf("version: got %d, want %d", book.Version(), version)
+		}
+		if n := len(book.PendingStateChanges()); n != 0 {
+			t.Fatalf("version %d: got %d pending state changes", version, n)
+		}
+		for _, name := range []string{"cash", "equity"} {
+			account, err := book.Root().AccountByName(name)
+			if err != nil {
+				t.Fatalf("version %d: account %s: %v", version, name, err)
+			}
+			if account.Balance() != want {
+				t.Fatalf("version %d: balance of %s: got %d, want %d",
+					version, name, account.Balance(), want)
+			}
+		}
+	}
+
+	if _, err := repo.LoadVersion(ctx, "b", 6); !errors.Is(
+		err, eventsource.ErrVersionNotReached,
+	) {
+		t.Fatalf("load version 6: got %v, want %v",
+			err, eventsource.ErrVersionNotReached)
+	}
+}
+
+func enter(debited, credited string, amount uint64) model.BookTransactionEnter {
+	return model.BookTransactionEnter{Transaction: model.Transaction{
+		Timestamp:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		AccountDebited:  debited,
+		AccountCredited: credited,
+		Amount:          amount,
+	}}
+}
+
+func TestBookInvalidCommandsProduceNoEvents(t *testing.T) {
+	ctx := context.Background()
+	store := eventstoreinmemory.New()
+	repo := eventsource.NewAggregateRepository[model.Book](store)
+
+	if _, err := repo.Create(ctx, "b", model.BookCreate{}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for _, cmd := range []eventsource.Command{
+		model.BookAccountAdd{
+			AccountName: "equity",
+			AccountType: accountingpb.AccountType_CAPITAL,
+		},
+		model.BookAccountAdd{
+			AccountName: "cash",
+			AccountType: accountingpb.AccountType_ASSET,
+		},
+	} {
+		if _, err := repo.Update(ctx, "b", cmd); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+
+	for cmd, want := range map[eventsource.Command]error{
+		enter("cash", "equity", 0):             model.ErrTransactionAmountZero,
+		enter("cash", "cash", 10):              model.ErrTransactionAccountsSame,
+		model.BookAccountAdd{}:                 model.ErrAccountNameEmpty,
+		model.BookAccountAdd{AccountName: "x"}: model.ErrAccountTypeUnknown,
+	} {
+		_, err := repo.Update(ctx, "b", cmd)
+		if !errors.Is(err, eventsource.ErrCommandInvalid) || !errors.Is(err, want) {
+			t.Fatalf("%T: got %v, want %v and %v",
+				cmd, err, eventsource.ErrCommandInvalid, want)
+		}
+	}
+
+	events, err := store.ListEvents(ctx, "b")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+}
+
+func TestBookCreate(t *testing.T) {
+	eventsourcetest.Given[model.Book](t).
+		When(model.BookCreate{Description: "d"}).
+		Then(&accountingpb.BookCreated{Description: "d"})
+
+	eventsourcetest.Given[model.Book](t, &accountingpb.BookCreated{}).
+		When(model.BookCreate{}).
+		ThenError(model.ErrBookAlreadyCreated)
+}
+
+func TestBookClose(t *testing.T) {
+	eventsourcetest.Given[model.Book](t, &accountingpb.BookCreated{}).
+		When(model.BookClose{}).
+		Then(&accountingpb.BookClosed{})
+
+	eventsourcetest.Given[model.Book](t,
+		&accountingpb.BookCreated{},
+		&accountingpb.BookClosed{},
+	).
+		When(model.BookAccountAdd{
+			AccountName: "cash",
+			AccountType: accountingpb.AccountType_ASSET,
+		}).
+		ThenError(model.ErrBookClosed)
+}
+
+func TestBookTransactionEnter(t *testing.T) {
+	opened := []eventsource.StateChange{
+		&accountingpb.BookCreated{},
+		&accountingpb.BookAccountAdded{
+			Name: "equity",
+			Type: accountingpb.AccountType_CAPITAL,
+		},
+		&accountingpb.BookAccountAdded{
+			Name: "cash",
+			Type: accountingpb.AccountType_ASSET,
+		},
+	}
+	timestamp := timestamppb.New(enter("", "", 0).Transaction.Timestamp)
+
+	eventsourcetest.Given[model.Book](t, opened...).
+		When(enter("cash", "equity", 100)).
+		Then(&accountingpb.BookTransactionEntered{
+			Timestamp:                 timestamp,
+			AccountDebited:            "cash",
+			AccountCredited:           "equity",
+			Amount:                    100,
+			AccountDebitedNewBalance:  100,
+			AccountCreditedNewBalance: 100,
+		})
+
+	eventsourcetest.Given[model.Book](t, opened...).
+		When(enter("equity", "cash", 100)).
+		ThenError(model.ErrAccountOverdrawn)
+
+	eventsourcetest.Given[model.Book](t, opened...).
+		When(enter("cash", "rent", 100)).
+		ThenError(model.ErrAccountCreditedNotFound)
+}
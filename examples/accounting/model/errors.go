@@ -14,4 +14,13 @@ var (
 	ErrAccountCreditDeclined   = errors.New("account credit declined")
 	ErrBookClosed              = errors.New("book closed")
 	ErrBookAlreadyCreated      = errors.New("book already created")
+	ErrBookNotFound            = errors.New("book not found")
+	ErrTransactionAmountZero   = errors.New("transaction amount zero")
+	ErrTransactionAccountsSame = errors.New("transaction accounts same")
+	ErrTransferIDEmpty         = errors.New("transfer ID empty")
+	ErrTransferIDConflict      = errors.New("transfer ID conflict")
+	ErrTransferNotFound        = errors.New("transfer not found")
+	ErrTransferAlreadyReceived = errors.New("transfer already received")
+	ErrTransferAmountZero      = errors.New("transfer amount zero")
+	ErrTransferTargetEmpty     = errors.New("transfer target empty")
 )
@@ -7,13 +7,14 @@ import (
 	"github.com/jackc/pgx/v5"
 
 	"github.com/rnovatorov/go-eventsource/examples/accounting/accountingpb"
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
 	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
 )
 
 func UpdateProjections(
 	ctx context.Context, tx pgx.Tx, event *eventstore.Event,
 ) error {
-	data, err := event.Data.UnmarshalNew()
+	data, err := eventsource.ProtoCodec{}.Unmarshal(event.Type, event.Data)
 	if err != nil {
 		return fmt.Errorf("unmarshal data: %w", err)
 	}
@@ -27,6 +28,12 @@ func UpdateProjections(
 		return handleBookAccountAdded(ctx, tx, event, d)
 	case *accountingpb.BookTransactionEntered:
 		return handleBookTransactionEntered(ctx, tx, event, d)
+	case *accountingpb.BookTransferSent:
+		return updateAccountBalance(ctx, tx, event, d.Account, d.AccountNewBalance)
+	case *accountingpb.BookTransferReceived:
+		return updateAccountBalance(ctx, tx, event, d.Account, d.AccountNewBalance)
+	case *accountingpb.BookTransferCancelled:
+		return updateAccountBalance(ctx, tx, event, d.Account, d.AccountNewBalance)
 	}
 
 	return nil
@@ -97,3 +104,15 @@ func handleBookTransactionEntered(
 
 	return nil
 }
+
+func updateAccountBalance(
+	ctx context.Context, tx pgx.Tx, e *eventstore.Event,
+	account string, balance uint64,
+) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE accounts
+		SET balance = $3
+		WHERE book_id = $1 AND name = $2
+	`, e.AggregateID, account, balance)
+	return err
+}
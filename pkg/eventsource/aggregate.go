@@ -3,16 +3,30 @@ package eventsource
 import (
 	"context"
 	"fmt"
+	"iter"
+	"slices"
+	"sync/atomic"
 
 	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
 )
 
+// Aggregate is owned by a single goroutine at a time, like the root it
+// wraps: it is not safe for concurrent use. Locking it would not help, as
+// Root hands out the root that ProcessCommand and saving mutate. Goroutines
+// that need the same aggregate should each load their own, or hand it over
+// with whatever synchronization they already use. Processing a command or
+// saving while another goroutine does either panics, the way concurrent map
+// writes do; other misuse is left to the race detector.
 type Aggregate[T any, R aggregateRoot[T]] struct {
+	inUse        atomic.Bool
 	id           string
 	version      int
 	root         R
 	stateChanges StateChanges
 	causationIDs map[string]struct{}
+	shredded     bool
+	deleted      bool
+	poisoned     error
 }
 
 func NewAggregate[T any, R aggregateRoot[T]](id string) *Aggregate[T, R] {
@@ -27,30 +41,90 @@ func NewAggregate[T any, R aggregateRoot[T]](id string) *Aggregate[T, R] {
 func RehydrateAggregate[T any, R aggregateRoot[T]](
 	id string, events eventstore.Events,
 ) (*Aggregate[T, R], error) {
+	return rehydrateAggregate(
+		id, 0, newAggregateRoot[T, R](id),
+		func(event *eventstore.Event) (StateChange, error) {
+			return ProtoCodec{}.Unmarshal(event.Type, event.Data)
+		},
+		eventsSeq(events))
+}
+
+func newDeletedAggregate[T any, R aggregateRoot[T]](id string) *Aggregate[T, R] {
+	agg := NewAggregate[T, R](id)
+	agg.deleted = true
+	return agg
+}
+
+func eventsSeq(events eventstore.Events) iter.Seq2[*eventstore.Event, error] {
+	return func(yield func(*eventstore.Event, error) bool) {
+		for _, event := range events {
+			if !yield(event, nil) {
+				return
+			}
+		}
+	}
+}
+
+func newAggregateRoot[T any, R aggregateRoot[T]](id string) R {
 	var root R = new(T)
-	var version int
-	causationIDs := make(map[string]struct{}, len(events))
+	if initializer, ok := any(root).(aggregateRootInitializer); ok {
+		initializer.Init(id)
+	}
+	return root
+}
+
+func rehydrateAggregate[T any, R aggregateRoot[T]](
+	id string, version int, root R,
+	decode func(*eventstore.Event) (StateChange, error),
+	events iter.Seq2[*eventstore.Event, error],
+) (*Aggregate[T, R], error) {
+	causationIDs := make(map[string]struct{})
+	shredded := false
+
+	for event, err := range events {
+		if err != nil {
+			return nil, err
+		}
+
+		if event.AggregateVersion != version+1 {
+			return nil, fmt.Errorf("%w: expected version %d, got %d",
+				ErrEventVersionMisaligned, version+1, event.AggregateVersion)
+		}
 
-	for _, event := range events {
-		stateChange, err := event.Data.UnmarshalNew()
+		stateChange, err := decode(event)
 		if err != nil {
-			return nil, fmt.Errorf("unmarshal state change: %w", err)
+			return nil, fmt.Errorf(
+				"unmarshal state change of aggregate %s at version %d (%s): %w",
+				id, event.AggregateVersion, event.Type, err)
 		}
 
-		root.ApplyStateChange(stateChange)
+		// A nil state change is an event decode chose to skip.
+		if stateChange != nil {
+			if err := root.ApplyStateChange(stateChange); err != nil {
+				return nil, fmt.Errorf(
+					"apply state change of aggregate %s at version %d: %w",
+					id, event.AggregateVersion, err)
+			}
+		}
 		version = event.AggregateVersion
+		shredded = shredded || event.Shredded
 
 		if cid := event.Metadata.CausationID(); cid != "" {
 			causationIDs[cid] = struct{}{}
 		}
 	}
 
+	if afterLoader, ok := any(root).(aggregateRootAfterLoader); ok {
+		afterLoader.AfterLoad()
+	}
+
 	return &Aggregate[T, R]{
 		id:           id,
 		version:      version,
 		root:         root,
 		stateChanges: nil,
 		causationIDs: causationIDs,
+		shredded:     shredded,
 	}, nil
 }
 
@@ -62,14 +136,79 @@ func (a *Aggregate[T, R]) Version() int {
 	return a.version
 }
 
+// BusinessVersion returns the version reported by the root if it implements
+// BusinessVersion() int, and the storage version otherwise.
+func (a *Aggregate[T, R]) BusinessVersion() int {
+	if v, ok := any(a.root).(aggregateRootBusinessVersioner); ok {
+		return v.BusinessVersion()
+	}
+	return a.version
+}
+
+// Shredded reports whether the aggregate was loaded from events with data
+// erased by crypto-shredding.
+func (a *Aggregate[T, R]) Shredded() bool {
+	return a.shredded
+}
+
+// Deleted reports whether the aggregate was loaded from events ending with
+// a tombstone, see WithSoftDelete, in which case it is at version 0 like an
+// aggregate that was never created.
+func (a *Aggregate[T, R]) Deleted() bool {
+	return a.deleted
+}
+
 func (a *Aggregate[T, R]) Root() R {
 	return a.root
 }
 
+// Poisoned reports whether the root failed to apply a state change of a
+// command, which may have left it partially mutated. A poisoned aggregate
+// processes no more commands and cannot be saved, it has to be loaded
+// again.
+func (a *Aggregate[T, R]) Poisoned() bool {
+	return a.poisoned != nil
+}
+
+// PendingStateChanges returns a copy of the state changes produced by the
+// commands processed since the aggregate was loaded or last saved.
+func (a *Aggregate[T, R]) PendingStateChanges() StateChanges {
+	return slices.Clone(a.stateChanges)
+}
+
 func (a *Aggregate[T, R]) ProcessCommand(ctx context.Context, cmd Command) error {
-	metadata := eventstore.MetadataFromContext(ctx)
+	return a.processCommand(ctx, cmd, true)
+}
 
-	if _, ok := a.causationIDs[metadata.CausationID()]; ok {
+// acquire marks the aggregate in use until the returned function is called,
+// panicking if it already is.
+func (a *Aggregate[T, R]) acquire() (release func()) {
+	if !a.inUse.CompareAndSwap(false, true) {
+		panic(fmt.Sprintf("eventsource: concurrent use of aggregate %s", a.id))
+	}
+	return func() { a.inUse.Store(false) }
+}
+
+// processCommand only skips commands already processed if dedupe is set,
+// see AggregateRepository.UpdateBatch.
+func (a *Aggregate[T, R]) processCommand(
+	ctx context.Context, cmd Command, dedupe bool,
+) error {
+	defer a.acquire()()
+
+	if a.poisoned != nil {
+		return a.poisoned
+	}
+
+	if validatable, ok := cmd.(Validatable); ok {
+		if err := validatable.Validate(); err != nil {
+			return fmt.Errorf("%T: %w: %w", cmd, ErrCommandInvalid, err)
+		}
+	}
+
+	causationID := commandCausationID(ctx, cmd)
+
+	if _, ok := a.causationIDs[causationID]; ok && dedupe {
 		return ErrCommandAlreadyProcessed
 	}
 
@@ -79,14 +218,25 @@ func (a *Aggregate[T, R]) ProcessCommand(ctx context.Context, cmd Command) error
 	}
 
 	for _, stateChange := range stateChanges {
-		a.root.ApplyStateChange(stateChange)
+		if err := a.root.ApplyStateChange(stateChange); err != nil {
+			a.poisoned = fmt.Errorf("%w: %T: apply %T: %w",
+				ErrAggregatePoisoned, cmd, stateChange, err)
+			return a.poisoned
+		}
 		a.stateChanges = append(a.stateChanges, stateChange)
 		a.version++
 	}
 
-	if cid := metadata.CausationID(); cid != "" {
-		a.causationIDs[cid] = struct{}{}
+	if causationID != "" {
+		a.causationIDs[causationID] = struct{}{}
 	}
 
 	return nil
 }
+
+// processed reports whether a command with the causation ID of cmd has
+// already been processed.
+func (a *Aggregate[T, R]) processed(ctx context.Context, cmd Command) bool {
+	_, ok := a.causationIDs[commandCausationID(ctx, cmd)]
+	return ok
+}
@@ -4,28 +4,93 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"google.golang.org/protobuf/types/known/anypb"
 
 	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
 )
 
 func NewAggregateRepository[T any, R aggregateRoot[T]](
-	eventStore eventstore.Interface,
+	eventStore eventstore.Interface, opts ...option,
 ) *AggregateRepository[T, R] {
-	return &AggregateRepository[T, R]{
-		eventStore: eventStore,
+	r := &AggregateRepository[T, R]{
+		eventStore:    eventStore,
+		config:        newConfig(opts...),
+		aggregateType: aggregateTypeName[T, R](),
 	}
+
+	if err := r.checkStateChanges(); err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+// checkStateChanges applies the state changes of WithStateChangeCheck to
+// fresh roots, see there.
+func (r *AggregateRepository[T, R]) checkStateChanges() error {
+	var unknown []string
+	for _, stateChange := range r.config.checkedChanges {
+		if errors.Is(applyToFreshRoot[T, R](stateChange),
+			ErrUnknownStateChange) {
+			unknown = append(unknown, fmt.Sprintf("%T", stateChange))
+		}
+	}
+
+	if len(unknown) > 0 {
+		return fmt.Errorf("eventsource: %s: %w: %s", r.aggregateType,
+			ErrUnknownStateChange, strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+func applyToFreshRoot[T any, R aggregateRoot[T]](
+	stateChange StateChange,
+) (err error) {
+	defer func() {
+		if recover() != nil {
+			err = nil
+		}
+	}()
+	return newAggregateRoot[T, R]("").ApplyStateChange(stateChange)
+}
+
+func aggregateTypeName[T any, R aggregateRoot[T]]() string {
+	var root R = new(T)
+	if namer, ok := any(root).(aggregateRootTypeNamer); ok {
+		return namer.TypeName()
+	}
+	return reflect.TypeFor[T]().Name()
 }
 
 type AggregateRepository[T any, R aggregateRoot[T]] struct {
-	eventStore eventstore.Interface
+	eventStore    eventstore.Interface
+	config        config
+	aggregateType string
 }
 
+// Get is like Load but fails with ErrAggregateDoesNotExist if the aggregate
+// has no events. The error also wraps ErrAggregateDeleted if the aggregate
+// was deleted with WithSoftDelete, and eventstore.ErrStreamNotFound
+// otherwise.
 func (r *AggregateRepository[T, R]) Get(
 	ctx context.Context, id string,
+) (*Aggregate[T, R], error) {
+	ctx, span := r.startSpan(ctx, "eventsource.Get", id)
+	agg, err := r.get(ctx, id)
+	endSpan(span, agg, err)
+	return agg, err
+}
+
+func (r *AggregateRepository[T, R]) get(
+	ctx context.Context, id string,
 ) (*Aggregate[T, R], error) {
 	agg, err := r.Load(ctx, id)
 	if err != nil {
@@ -33,21 +98,50 @@ func (r *AggregateRepository[T, R]) Get(
 	}
 
 	if agg.Version() == 0 {
-		return nil, ErrAggregateDoesNotExist
+		return nil, doesNotExist(agg)
 	}
 
 	return agg, nil
 }
 
+// doesNotExist returns the error for agg being at version 0, which wraps
+// ErrAggregateDoesNotExist along with ErrAggregateDeleted if agg was
+// deleted, or eventstore.ErrStreamNotFound if it was never created.
+// Aggregates deleted with DeleteStream leave no trace and count as never
+// created.
+func doesNotExist[T any, R aggregateRoot[T]](agg *Aggregate[T, R]) error {
+	if agg.Deleted() {
+		return fmt.Errorf("%w: %w", ErrAggregateDoesNotExist,
+			ErrAggregateDeleted)
+	}
+	return fmt.Errorf("%w: %w", ErrAggregateDoesNotExist,
+		eventstore.ErrStreamNotFound)
+}
+
+// Create processes cmd on a new aggregate and saves it, failing with
+// ErrAggregateAlreadyExists if the aggregate exists already, unless it was
+// created by a command with the same causation ID, in which case the
+// existing aggregate is returned.
 func (r *AggregateRepository[T, R]) Create(
 	ctx context.Context, id string, cmd Command,
 ) (*Aggregate[T, R], error) {
+	ctx, span := r.startSpan(ctx, "eventsource.Create", id)
+	agg, err := r.create(ctx, id, cmd)
+	endSpan(span, agg, err)
+	return agg, err
+}
+
+func (r *AggregateRepository[T, R]) create(
+	ctx context.Context, id string, cmd Command,
+) (*Aggregate[T, R], error) {
+	ctx = contextWithCommand(ctx, cmd)
+
 	if id == "" {
-		randomID, err := uuid.NewRandom()
+		newID, err := r.config.idGenerator.NewID()
 		if err != nil {
 			return nil, fmt.Errorf("generate ID: %w", err)
 		}
-		id = randomID.String()
+		id = newID
 	}
 
 	agg, err := r.Load(ctx, id)
@@ -56,16 +150,19 @@ func (r *AggregateRepository[T, R]) Create(
 	}
 
 	if agg.Version() != 0 {
+		if agg.processed(ctx, cmd) {
+			return agg, nil
+		}
 		return nil, ErrAggregateAlreadyExists
 	}
 
-	if err := agg.ProcessCommand(ctx, cmd); err != nil {
+	if err := r.processCommand(ctx, agg, cmd, true); err != nil {
 		return nil, fmt.Errorf("process command: %w", err)
 	}
 
 	if err := r.Save(ctx, agg); err != nil {
 		if errors.Is(err, eventstore.ErrConcurrentUpdate) {
-			return nil, ErrAggregateAlreadyExists
+			return r.createdConcurrently(ctx, id, cmd)
 		}
 		return nil, fmt.Errorf("save: %w", err)
 	}
@@ -73,70 +170,244 @@ func (r *AggregateRepository[T, R]) Create(
 	return agg, nil
 }
 
+// createdConcurrently returns the aggregate created before cmd could be
+// saved if it was created by a command with the same causation ID, as when a
+// request is retried before the original one completes.
+func (r *AggregateRepository[T, R]) createdConcurrently(
+	ctx context.Context, id string, cmd Command,
+) (*Aggregate[T, R], error) {
+	agg, err := r.Load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("load: %w", err)
+	}
+
+	if !agg.processed(ctx, cmd) {
+		return nil, ErrAggregateAlreadyExists
+	}
+
+	return agg, nil
+}
+
+// GetOrCreate returns the existing aggregate, or processes cmd on a new
+// one and saves it.
 func (r *AggregateRepository[T, R]) GetOrCreate(
 	ctx context.Context, id string, cmd Command,
 ) (*Aggregate[T, R], error) {
+	agg, _, err := r.GetOrCreateWithStatus(ctx, id, cmd)
+	return agg, err
+}
+
+// GetOrCreateWithStatus is like GetOrCreate, but also reports whether the
+// aggregate was created by this call. It was not if the aggregate already
+// existed, or if it got created concurrently before cmd could be saved.
+func (r *AggregateRepository[T, R]) GetOrCreateWithStatus(
+	ctx context.Context, id string, cmd Command,
+) (*Aggregate[T, R], bool, error) {
+	ctx, span := r.startSpan(ctx, "eventsource.GetOrCreate", id)
+	agg, created, err := r.getOrCreate(ctx, id, cmd)
+	span.SetAttribute("eventsource.created", created)
+	endSpan(span, agg, err)
+	return agg, created, err
+}
+
+func (r *AggregateRepository[T, R]) getOrCreate(
+	ctx context.Context, id string, cmd Command,
+) (agg *Aggregate[T, R], created bool, err error) {
+	ctx = contextWithCommand(ctx, cmd)
+
 	if id == "" {
-		randomID, err := uuid.NewRandom()
+		newID, err := r.config.idGenerator.NewID()
 		if err != nil {
-			return nil, fmt.Errorf("generate ID: %w", err)
+			return nil, false, fmt.Errorf("generate ID: %w", err)
 		}
-		id = randomID.String()
+		id = newID
 	}
 
-	agg, err := r.Load(ctx, id)
+	agg, err = r.Load(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("load: %w", err)
+		return nil, false, fmt.Errorf("load: %w", err)
 	}
 
 	if agg.Version() > 0 {
-		return agg, nil
+		return agg, false, nil
 	}
 
-	if err := agg.ProcessCommand(ctx, cmd); err != nil {
-		return nil, fmt.Errorf("process command: %w", err)
+	if err := r.processCommand(ctx, agg, cmd, true); err != nil {
+		return nil, false, fmt.Errorf("process command: %w", err)
 	}
 
 	if err := r.Save(ctx, agg); err != nil {
 		if errors.Is(err, eventstore.ErrConcurrentUpdate) {
 			agg, err = r.Load(ctx, id)
 			if err != nil {
-				return nil, fmt.Errorf("load: %w", err)
+				return nil, false, fmt.Errorf("load: %w", err)
 			}
-			return agg, nil
+			return agg, false, nil
 		}
-		return nil, fmt.Errorf("save: %w", err)
+		return nil, false, fmt.Errorf("save: %w", err)
 	}
 
-	return agg, nil
+	return agg, true, nil
 }
 
+// Update processes cmd on the existing aggregate and saves the resulting
+// state changes. A command whose causation ID the aggregate has already
+// processed is skipped, and the aggregate is returned unchanged.
 func (r *AggregateRepository[T, R]) Update(
 	ctx context.Context, id string, cmd Command,
 ) (*Aggregate[T, R], error) {
-	agg, err := r.update(ctx, id, cmd)
+	ctx, span := r.startSpan(ctx, "eventsource.Update", id)
+	agg, err := r.updateWithRetry(ctx, id, cmd)
+	endSpan(span, agg, err)
+	return agg, err
+}
+
+func (r *AggregateRepository[T, R]) updateWithRetry(
+	ctx context.Context, id string, cmd Command,
+) (*Aggregate[T, R], error) {
+	return r.retryConflicts(ctx, func() (*Aggregate[T, R], error) {
+		return r.update(ctx, id, cmd, nil)
+	})
+}
+
+// retryConflicts attempts update as configured by WithConflictRetry.
+func (r *AggregateRepository[T, R]) retryConflicts(
+	ctx context.Context, update func() (*Aggregate[T, R], error),
+) (*Aggregate[T, R], error) {
+	for attempt := 1; ; attempt++ {
+		agg, err := update()
+		if err == nil {
+			return agg, nil
+		}
+		if !errors.Is(err, eventstore.ErrConcurrentUpdate) {
+			return nil, err
+		}
+		if attempt >= r.config.retryAttempts {
+			return nil, fmt.Errorf("%d attempts: %w", attempt, err)
+		}
+		if backoff := r.config.retryBackoff; backoff != nil {
+			timer := time.NewTimer(backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// UpdateBatch is like Update, but processes the commands in order on the
+// aggregate loaded once, and saves the state changes of all of them at
+// once. If any command fails, nothing is saved. Commands that the aggregate
+// had processed before the batch are skipped, so a batch retried with the
+// same causation ID in ctx is skipped as a whole. The events are saved with
+// the metadata of ctx, which means that the causation IDs carried by the
+// commands themselves are not recorded. A conflict on save is retried as by
+// Update, processing the whole batch again.
+func (r *AggregateRepository[T, R]) UpdateBatch(
+	ctx context.Context, id string, cmds []Command,
+) (*Aggregate[T, R], error) {
+	ctx, span := r.startSpan(ctx, "eventsource.UpdateBatch", id)
+	agg, err := r.retryConflicts(ctx, func() (*Aggregate[T, R], error) {
+		return r.updateBatch(ctx, id, cmds)
+	})
+	endSpan(span, agg, err)
+	return agg, err
+}
+
+func (r *AggregateRepository[T, R]) updateBatch(
+	ctx context.Context, id string, cmds []Command,
+) (*Aggregate[T, R], error) {
+	agg, err := r.Load(ctx, id)
 	if err != nil {
-		if errors.Is(err, eventstore.ErrConcurrentUpdate) {
-			return r.update(ctx, id, cmd)
+		return nil, fmt.Errorf("load: %w", err)
+	}
+
+	if agg.Version() == 0 {
+		return nil, doesNotExist(agg)
+	}
+
+	// Commands sharing the causation ID of ctx must not skip each other.
+	processed := make([]bool, len(cmds))
+	for i, cmd := range cmds {
+		processed[i] = agg.processed(contextWithCommand(ctx, cmd), cmd)
+	}
+
+	for i, cmd := range cmds {
+		if processed[i] {
+			continue
+		}
+		if err := r.processCommand(
+			contextWithCommand(ctx, cmd), agg, cmd, false,
+		); err != nil {
+			return nil, fmt.Errorf("process command %d: %w", i, err)
 		}
-		return nil, err
 	}
+
+	if err := r.Save(ctx, agg); err != nil {
+		return nil, fmt.Errorf("save: %w", err)
+	}
+
+	return agg, nil
+}
+
+// UpdateAt is like Update, but fails with eventstore.ErrConcurrentUpdate
+// without processing the command if the version of the aggregate differs
+// from the expected one. A command already processed is skipped whatever
+// the version, so that retrying it succeeds.
+func (r *AggregateRepository[T, R]) UpdateAt(
+	ctx context.Context, id string, expectedVersion int, cmd Command,
+) (*Aggregate[T, R], error) {
+	ctx, span := r.startSpan(ctx, "eventsource.UpdateAt", id)
+	span.SetAttribute("eventsource.expected_version", expectedVersion)
+	agg, err := r.update(ctx, id, cmd, func(agg *Aggregate[T, R]) bool {
+		return agg.Version() == expectedVersion
+	})
+	endSpan(span, agg, err)
+	return agg, err
+}
+
+// UpdateAtBusinessVersion is like UpdateAt, but compares the business
+// version of the aggregate. The business version is translated to the
+// storage version it was loaded at, which is then used for the optimistic
+// concurrency check on save.
+func (r *AggregateRepository[T, R]) UpdateAtBusinessVersion(
+	ctx context.Context, id string, expectedBusinessVersion int, cmd Command,
+) (*Aggregate[T, R], error) {
+	ctx, span := r.startSpan(ctx, "eventsource.UpdateAtBusinessVersion", id)
+	span.SetAttribute("eventsource.expected_business_version",
+		expectedBusinessVersion)
+	agg, err := r.update(ctx, id, cmd, func(agg *Aggregate[T, R]) bool {
+		return agg.BusinessVersion() == expectedBusinessVersion
+	})
+	endSpan(span, agg, err)
 	return agg, err
 }
 
 func (r *AggregateRepository[T, R]) update(
 	ctx context.Context, id string, cmd Command,
+	expected func(*Aggregate[T, R]) bool,
 ) (*Aggregate[T, R], error) {
+	ctx = contextWithCommand(ctx, cmd)
+
 	agg, err := r.Load(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("load: %w", err)
 	}
 
 	if agg.Version() == 0 {
-		return nil, ErrAggregateDoesNotExist
+		return nil, doesNotExist(agg)
+	}
+
+	if expected != nil && !expected(agg) && !agg.processed(ctx, cmd) {
+		return nil, eventstore.ErrConcurrentUpdate
 	}
 
-	if err := agg.ProcessCommand(ctx, cmd); err != nil {
+	if err := r.processCommand(ctx, agg, cmd, true); err != nil {
+		if errors.Is(err, ErrCommandAlreadyProcessed) {
+			return agg, nil
+		}
 		return nil, fmt.Errorf("process command: %w", err)
 	}
 
@@ -147,15 +418,625 @@ func (r *AggregateRepository[T, R]) update(
 	return agg, nil
 }
 
+// CreateCommandHandler returns a CommandHandler creating aggregates with
+// the commands it is given, see Create.
+func (r *AggregateRepository[T, R]) CreateCommandHandler() CommandHandler {
+	return func(ctx context.Context, aggregateID string, cmd Command) error {
+		_, err := r.Create(ctx, aggregateID, cmd)
+		return err
+	}
+}
+
+// UpdateCommandHandler returns a CommandHandler loading the aggregate,
+// applying the command to it and saving it, see Update.
+func (r *AggregateRepository[T, R]) UpdateCommandHandler() CommandHandler {
+	return func(ctx context.Context, aggregateID string, cmd Command) error {
+		_, err := r.Update(ctx, aggregateID, cmd)
+		return err
+	}
+}
+
+// Delete deletes the aggregate, or marks it deleted with WithSoftDelete. It
+// fails with ErrAggregateDoesNotExist if the aggregate has no events.
+func (r *AggregateRepository[T, R]) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return ErrEmptyAggregateID
+	}
+
+	if !r.config.softDelete {
+		if err := r.eventStore.DeleteStream(ctx, id); err != nil {
+			if errors.Is(err, eventstore.ErrStreamNotFound) {
+				return fmt.Errorf("%w: %w", ErrAggregateDoesNotExist, err)
+			}
+			return fmt.Errorf("delete stream: %w", err)
+		}
+		return nil
+	}
+
+	agg, err := r.Load(ctx, id)
+	if err != nil {
+		return fmt.Errorf("load: %w", err)
+	}
+
+	if agg.Version() == 0 {
+		return doesNotExist(agg)
+	}
+
+	eventID, err := r.config.idGenerator.NewID()
+	if err != nil {
+		return fmt.Errorf("generate event ID: %w", err)
+	}
+
+	if err := r.eventStore.SaveEvents(ctx, id, agg.Version(), eventstore.Events{
+		{
+			ID:               eventID,
+			AggregateID:      id,
+			AggregateType:    r.aggregateType,
+			AggregateVersion: agg.Version() + 1,
+			Timestamp:        r.config.clock.Now(),
+			Metadata:         eventstore.MetadataFromContext(ctx),
+			Type:             eventstore.TombstoneEventType,
+			Data:             []byte{},
+		},
+	}); err != nil {
+		return fmt.Errorf("save tombstone: %w", err)
+	}
+
+	return nil
+}
+
+// ListIDs lists, in order, up to limit IDs greater than afterID of the
+// aggregates of the type of the repository. A limit of 0 means no limit.
+// Aggregates whose events were saved before their type was recorded are
+// not listed.
+func (r *AggregateRepository[T, R]) ListIDs(
+	ctx context.Context, afterID string, limit int,
+) ([]string, error) {
+	ids, err := r.eventStore.ListAggregateIDs(
+		ctx, r.aggregateType, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list aggregate IDs: %w", err)
+	}
+
+	return ids, nil
+}
+
+// ChangedSince takes the versions a client last saw and returns the current
+// versions of those aggregates that have advanced since. Aggregates that do
+// not exist are at version 0.
+func (r *AggregateRepository[T, R]) ChangedSince(
+	ctx context.Context, knownVersions map[string]int,
+) (map[string]int, error) {
+	ids := make([]string, 0, len(knownVersions))
+	for id := range knownVersions {
+		ids = append(ids, id)
+	}
+
+	versions, err := r.eventStore.CurrentVersions(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("current versions: %w", err)
+	}
+
+	changed := make(map[string]int)
+	for id, version := range versions {
+		if version > knownVersions[id] {
+			changed[id] = version
+		}
+	}
+
+	return changed, nil
+}
+
+// StreamLength returns the number of events of the aggregate, without
+// loading them.
+func (r *AggregateRepository[T, R]) StreamLength(
+	ctx context.Context, id string,
+) (int, error) {
+	if id == "" {
+		return 0, ErrEmptyAggregateID
+	}
+
+	n, err := r.eventStore.CountEvents(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("count events: %w", err)
+	}
+
+	return n, nil
+}
+
+// StreamPages iterates over the events of the aggregate in pages of up to
+// pageSize events, so that long streams can be read without holding them in
+// memory. The events are yielded as stored, neither upcast nor decrypted.
+// Iteration stops after the first error.
+func (r *AggregateRepository[T, R]) StreamPages(
+	ctx context.Context, id string, pageSize int,
+) iter.Seq2[eventstore.Events, error] {
+	return func(yield func(eventstore.Events, error) bool) {
+		if id == "" {
+			yield(nil, ErrEmptyAggregateID)
+			return
+		}
+
+		afterVersion := 0
+		for {
+			events, more, err := r.eventStore.ListEventsPage(
+				ctx, id, afterVersion, pageSize)
+			if errors.Is(err, eventstore.ErrStreamNotFound) {
+				return
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("list events page: %w", err))
+				return
+			}
+			if len(events) == 0 {
+				return
+			}
+			if !yield(events, nil) || !more {
+				return
+			}
+			afterVersion = events[len(events)-1].AggregateVersion
+		}
+	}
+}
+
+func (r *AggregateRepository[T, R]) processCommand(
+	ctx context.Context, agg *Aggregate[T, R], cmd Command, dedupe bool,
+) error {
+	pending := len(agg.stateChanges)
+
+	err := agg.processCommand(ctx, cmd, dedupe)
+
+	if auditor := r.config.commandAuditor; auditor != nil {
+		if auditErr := auditor.AuditCommand(ctx, CommandAuditRecord{
+			AggregateID:  agg.ID(),
+			Command:      cmd,
+			Metadata:     eventstore.MetadataFromContext(ctx),
+			Timestamp:    r.config.clock.Now(),
+			StateChanges: agg.stateChanges[pending:],
+			Err:          err,
+		}); auditErr != nil {
+			return fmt.Errorf("audit command: %w", auditErr)
+		}
+	}
+
+	return err
+}
+
 func (r *AggregateRepository[T, R]) Load(
 	ctx context.Context, id string,
 ) (*Aggregate[T, R], error) {
+	ctx, span := r.startSpan(ctx, "eventsource.Load", id)
+	agg, err := r.load(ctx, id)
+	endSpan(span, agg, err)
+	return agg, err
+}
+
+func (r *AggregateRepository[T, R]) load(
+	ctx context.Context, id string,
+) (*Aggregate[T, R], error) {
+	if id == "" {
+		return nil, ErrEmptyAggregateID
+	}
+
+	defer func(start time.Time) {
+		r.config.metrics.ObserveLoad(r.aggregateType, time.Since(start))
+	}(time.Now())
+
+	agg, err := r.loadSnapshot(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if agg != nil {
+		return agg, nil
+	}
+
 	events, err := r.eventStore.ListEvents(ctx, id)
+	if err != nil && !errors.Is(err, eventstore.ErrStreamNotFound) {
+		return nil, fmt.Errorf("list events: %w", err)
+	}
+
+	agg, err = r.rehydrate(ctx, id, 0, newAggregateRoot[T, R](id), events)
+	if err != nil {
+		return nil, fmt.Errorf("rehydrate: %w", err)
+	}
+
+	return agg, nil
+}
+
+// LoadStreaming is like Load but applies the events as they are streamed
+// from the event store, instead of listing them all first, which keeps
+// memory low when loading aggregates with long streams.
+func (r *AggregateRepository[T, R]) LoadStreaming(
+	ctx context.Context, id string,
+) (*Aggregate[T, R], error) {
+	if id == "" {
+		return nil, ErrEmptyAggregateID
+	}
+
+	defer func(start time.Time) {
+		r.config.metrics.ObserveLoad(r.aggregateType, time.Since(start))
+	}(time.Now())
+
+	agg, err := r.loadSnapshot(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if agg != nil {
+		return agg, nil
+	}
+
+	tombstoned := false
+	events := func(yield func(*eventstore.Event, error) bool) {
+		for event, err := range r.eventStore.StreamEvents(ctx, id) {
+			if errors.Is(err, eventstore.ErrStreamNotFound) {
+				return
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("stream events: %w", err))
+				return
+			}
+			// Tombstones are the last events of their streams.
+			if event.Type == eventstore.TombstoneEventType {
+				tombstoned = true
+				return
+			}
+			if !yield(event, nil) {
+				return
+			}
+		}
+	}
+
+	agg, err = r.rehydrateSeq(ctx, id, 0, newAggregateRoot[T, R](id), events)
+	if err != nil {
+		return nil, fmt.Errorf("rehydrate: %w", err)
+	}
+
+	if tombstoned {
+		return newDeletedAggregate[T, R](id), nil
+	}
+
+	return agg, nil
+}
+
+// Fold replays the state changes of the aggregate through fn, starting from
+// init, without rehydrating the aggregate. The events are streamed as by
+// LoadStreaming, and decoded as by Load. Snapshots are not used, and the
+// state changes of a deleted aggregate are folded up to its deletion. Fold
+// is a function rather than a method as methods cannot have type
+// parameters.
+func Fold[S any, T any, R aggregateRoot[T]](
+	ctx context.Context, r *AggregateRepository[T, R], id string, init S,
+	fn func(S, StateChange) (S, error),
+) (S, error) {
+	if id == "" {
+		return init, ErrEmptyAggregateID
+	}
+
+	acc := init
+	for event, err := range r.prepareEvents(r.eventStore.StreamEvents(ctx, id)) {
+		if errors.Is(err, eventstore.ErrStreamNotFound) {
+			break
+		}
+		if err != nil {
+			return init, err
+		}
+		if event.Type == eventstore.TombstoneEventType {
+			break
+		}
+		stateChange, err := r.decode(ctx, event)
+		if err != nil {
+			return init, fmt.Errorf("decode event %s: %w", event.ID, err)
+		}
+		if stateChange == nil {
+			continue
+		}
+		if acc, err = fn(acc, stateChange); err != nil {
+			return init, fmt.Errorf("fold event %s: %w", event.ID, err)
+		}
+	}
+
+	return acc, nil
+}
+
+// LoadMany loads the aggregates with the given IDs, listing their events in
+// one call to the event store. Aggregates without events are included at
+// version 0. With WithSnapshots, each aggregate is loaded separately, as by
+// Load, so that its snapshot is used.
+func (r *AggregateRepository[T, R]) LoadMany(
+	ctx context.Context, ids []string,
+) (map[string]*Aggregate[T, R], error) {
+	if slices.Contains(ids, "") {
+		return nil, ErrEmptyAggregateID
+	}
+
+	aggs := make(map[string]*Aggregate[T, R], len(ids))
+
+	if r.config.snapshotStore != nil {
+		for _, id := range ids {
+			agg, err := r.Load(ctx, id)
+			if err != nil {
+				return nil, fmt.Errorf("load %s: %w", id, err)
+			}
+			aggs[id] = agg
+		}
+		return aggs, nil
+	}
+
+	defer func(start time.Time) {
+		r.config.metrics.ObserveLoad(r.aggregateType, time.Since(start))
+	}(time.Now())
+
+	eventsByAggregate, err := r.eventStore.ListEventsMany(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list events: %w", err)
+	}
+
+	for _, id := range ids {
+		agg, err := r.rehydrate(ctx, id, 0, newAggregateRoot[T, R](id),
+			eventsByAggregate[id])
+		if err != nil {
+			return nil, fmt.Errorf("rehydrate %s: %w", id, err)
+		}
+		aggs[id] = agg
+	}
+
+	return aggs, nil
+}
+
+func (r *AggregateRepository[T, R]) rehydrate(
+	ctx context.Context, id string, version int, root R,
+	events eventstore.Events,
+) (*Aggregate[T, R], error) {
+	if n := len(events); n > 0 &&
+		events[n-1].Type == eventstore.TombstoneEventType {
+		return newDeletedAggregate[T, R](id), nil
+	}
+
+	return r.rehydrateSeq(ctx, id, version, root, eventsSeq(events))
+}
+
+func (r *AggregateRepository[T, R]) rehydrateSeq(
+	ctx context.Context, id string, version int, root R,
+	events iter.Seq2[*eventstore.Event, error],
+) (*Aggregate[T, R], error) {
+	return rehydrateAggregate(id, version, root,
+		func(event *eventstore.Event) (StateChange, error) {
+			return r.decode(ctx, event)
+		},
+		r.prepareEvents(events))
+}
+
+// prepareEvents checks that events belong to aggregates of the type of the
+// repository, and copies those that decode is going to modify, so that the
+// events as listed from the store are left untouched.
+func (r *AggregateRepository[T, R]) prepareEvents(
+	events iter.Seq2[*eventstore.Event, error],
+) iter.Seq2[*eventstore.Event, error] {
+	copying := r.config.upcaster != nil || r.config.encryption != nil
+
+	return func(yield func(*eventstore.Event, error) bool) {
+		for event, err := range events {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if event.AggregateType != "" &&
+				event.AggregateType != r.aggregateType {
+				yield(nil, fmt.Errorf("%w: %s is a %s, not a %s",
+					ErrAggregateTypeMismatch, event.AggregateID,
+					event.AggregateType, r.aggregateType))
+				return
+			}
+			if copying {
+				copied := *event
+				event = &copied
+			}
+			if !yield(event, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (r *AggregateRepository[T, R]) upcast(
+	event *eventstore.Event,
+) (string, []byte, error) {
+	if v, ok := r.config.upcaster.(VersionedUpcaster); ok {
+		typeURL, _, data, err := v.UpcastVersion(
+			event.Type, event.EffectiveSchemaVersion(), event.Data)
+		return typeURL, data, err
+	}
+	return r.config.upcaster.Upcast(event.Type, event.Data)
+}
+
+func (r *AggregateRepository[T, R]) decode(
+	ctx context.Context, event *eventstore.Event,
+) (StateChange, error) {
+	if r.config.upcaster != nil {
+		typeURL, data, err := r.upcast(event)
+		if err != nil {
+			return nil, fmt.Errorf("upcast event %s: %w", event.ID, err)
+		}
+		event.Type = typeURL
+		event.Data = data
+	}
+
+	stateChange, err := r.config.codec.Unmarshal(event.Type, event.Data)
+	if err != nil {
+		if !r.config.skipUnreadable {
+			return nil, err
+		}
+		if r.config.logger.Enabled(LogLevelWarn) {
+			r.config.logger.Warn("skipped unreadable event",
+				"aggregate_id", event.AggregateID,
+				"aggregate_version", event.AggregateVersion,
+				"event_type", event.Type,
+				"error", err)
+		}
+		return nil, nil
+	}
+
+	if r.config.encryption != nil {
+		shredded, err := decryptFields(
+			ctx, r.config.encryption, event.AggregateID, stateChange)
+		if err != nil {
+			return nil, err
+		}
+		event.Shredded = shredded
+	}
+
+	return stateChange, nil
+}
+
+func (r *AggregateRepository[T, R]) loadSnapshot(
+	ctx context.Context, id string,
+) (*Aggregate[T, R], error) {
+	if r.config.snapshotStore == nil {
+		return nil, nil
+	}
+
+	root := newAggregateRoot[T, R](id)
+	snapshotter, ok := any(root).(aggregateRootSnapshotter)
+	if !ok {
+		return nil, nil
+	}
+
+	snapshot, err := r.config.snapshotStore.GetLatestSnapshot(ctx, id)
+	if err != nil {
+		if r.config.strictSnapshots {
+			return nil, fmt.Errorf("get latest snapshot: %w", err)
+		}
+		if r.config.logger.Enabled(LogLevelWarn) {
+			r.config.logger.Warn("failed to get latest snapshot",
+				"aggregate_id", id, "error", err)
+		}
+		return nil, nil
+	}
+	if snapshot == nil {
+		return nil, nil
+	}
+
+	state, err := snapshot.Data.UnmarshalNew()
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal snapshot: %w", err)
+	}
+	snapshotter.RestoreSnapshot(state)
+
+	events, err := r.eventStore.ListEventsRange(
+		ctx, id, snapshot.AggregateVersion+1, 0)
 	if err != nil {
 		return nil, fmt.Errorf("list events: %w", err)
 	}
 
-	agg, err := RehydrateAggregate[T, R](id, events)
+	if len(events) > 0 &&
+		events[0].AggregateVersion != snapshot.AggregateVersion+1 {
+		return nil, fmt.Errorf("%w: snapshot at version %d, next event at %d",
+			ErrSnapshotMisaligned, snapshot.AggregateVersion,
+			events[0].AggregateVersion)
+	}
+
+	agg, err := r.rehydrate(ctx, id, snapshot.AggregateVersion, root, events)
+	if err != nil {
+		return nil, fmt.Errorf("rehydrate: %w", err)
+	}
+
+	return agg, nil
+}
+
+// LoadVersion reconstructs the aggregate as it was right after the given
+// version. The result is meant for inspection and must not be saved.
+func (r *AggregateRepository[T, R]) LoadVersion(
+	ctx context.Context, id string, version int,
+) (*Aggregate[T, R], error) {
+	if id == "" {
+		return nil, ErrEmptyAggregateID
+	}
+
+	if version < 1 {
+		return nil, fmt.Errorf("%w: %d", eventstore.ErrInvalidVersionRange, version)
+	}
+
+	events, err := r.eventStore.ListEventsRange(ctx, id, 1, version)
+	if err != nil {
+		return nil, fmt.Errorf("list events: %w", err)
+	}
+
+	agg, err := r.rehydrate(ctx, id, 0, newAggregateRoot[T, R](id), events)
+	if err != nil {
+		return nil, fmt.Errorf("rehydrate: %w", err)
+	}
+
+	if agg.Version() < version {
+		return nil, fmt.Errorf("%w: requested %d, latest %d",
+			ErrVersionNotReached, version, agg.Version())
+	}
+
+	return agg, nil
+}
+
+// LoadAsOf reconstructs the aggregate from the events whose timestamps are
+// not after t. Timestamps come from the clocks of the writers, so with clock
+// skew between them the result is approximate around t.
+func (r *AggregateRepository[T, R]) LoadAsOf(
+	ctx context.Context, id string, t time.Time,
+) (*Aggregate[T, R], error) {
+	if id == "" {
+		return nil, ErrEmptyAggregateID
+	}
+
+	var events eventstore.Events
+	var err error
+	if lister, ok := r.eventStore.(eventsUntilLister); ok {
+		events, err = lister.ListEventsUntil(ctx, id, t)
+	} else {
+		events, err = r.eventStore.ListEvents(ctx, id)
+	}
+	if err != nil && !errors.Is(err, eventstore.ErrStreamNotFound) {
+		return nil, fmt.Errorf("list events: %w", err)
+	}
+
+	for i, event := range events {
+		if event.Timestamp.After(t) {
+			events = events[:i]
+			break
+		}
+	}
+
+	agg, err := r.rehydrate(ctx, id, 0, newAggregateRoot[T, R](id), events)
+	if err != nil {
+		return nil, fmt.Errorf("rehydrate: %w", err)
+	}
+
+	return agg, nil
+}
+
+type eventsUntilLister interface {
+	ListEventsUntil(
+		ctx context.Context, aggregateID string, until time.Time,
+	) (eventstore.Events, error)
+}
+
+// LoadFrom applies to a fresh root only the events starting at fromVersion.
+// The resulting root lacks the effect of earlier events, so this is meant
+// for roots whose earlier state is restored by other means.
+func (r *AggregateRepository[T, R]) LoadFrom(
+	ctx context.Context, id string, fromVersion int,
+) (*Aggregate[T, R], error) {
+	if id == "" {
+		return nil, ErrEmptyAggregateID
+	}
+
+	if fromVersion < 1 {
+		fromVersion = 1
+	}
+
+	events, err := r.eventStore.ListEventsRange(ctx, id, fromVersion, 0)
+	if err != nil {
+		return nil, fmt.Errorf("list events: %w", err)
+	}
+
+	agg, err := r.rehydrate(
+		ctx, id, fromVersion-1, newAggregateRoot[T, R](id), events)
 	if err != nil {
 		return nil, fmt.Errorf("rehydrate: %w", err)
 	}
@@ -166,40 +1047,246 @@ func (r *AggregateRepository[T, R]) Load(
 func (r *AggregateRepository[T, R]) Save(
 	ctx context.Context, agg *Aggregate[T, R],
 ) error {
+	_, err := r.SaveAndReturn(ctx, agg)
+	return err
+}
+
+// SaveAndReturn is like Save but returns the events it saved, none if there
+// were no pending state changes. Their global positions are set only if the
+// event store assigns them on save, see eventstore.Event.
+func (r *AggregateRepository[T, R]) SaveAndReturn(
+	ctx context.Context, agg *Aggregate[T, R],
+) (eventstore.Events, error) {
+	ctx, span := r.startSpan(ctx, "eventsource.Save", agg.ID())
+	span.SetAttribute("eventsource.event_count", len(agg.stateChanges))
+	events, err := r.save(ctx, agg)
+	endSpan(span, agg, err)
+	return events, err
+}
+
+func (r *AggregateRepository[T, R]) save(
+	ctx context.Context, agg *Aggregate[T, R],
+) (eventstore.Events, error) {
+	defer agg.acquire()()
+
+	if agg.poisoned != nil {
+		return nil, agg.poisoned
+	}
+
 	if len(agg.stateChanges) == 0 {
-		return nil
+		return nil, nil
+	}
+
+	originalVersion := agg.Version() - len(agg.stateChanges)
+
+	events, err := r.newEvents(ctx, agg)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	if err := r.eventStore.SaveEvents(
+		ctx, agg.ID(), originalVersion, events,
+	); err != nil {
+		if errors.Is(err, eventstore.ErrConcurrentUpdate) {
+			r.config.metrics.IncConcurrentUpdates(r.aggregateType)
+			if r.config.logger.Enabled(LogLevelInfo) {
+				r.config.logger.Info("concurrent update",
+					"aggregate_id", agg.ID(),
+					"expected_version", originalVersion)
+			}
+		}
+		return nil, fmt.Errorf("save events: %w", err)
+	}
+	r.config.metrics.ObserveSave(r.aggregateType, time.Since(start), len(events))
+
+	if r.config.logger.Enabled(LogLevelDebug) {
+		r.config.logger.Debug("saved events",
+			"aggregate_id", agg.ID(),
+			"from_version", originalVersion,
+			"to_version", agg.Version(),
+			"event_count", len(events))
+	}
+
+	r.saved(ctx, agg, originalVersion, events)
+
+	return events, nil
+}
+
+// UncommittedEvents returns the events Save would write for the pending state
+// changes of agg given the metadata in ctx. Save generates new event IDs
+// and timestamps, so those of the events returned differ.
+func (r *AggregateRepository[T, R]) UncommittedEvents(
+	ctx context.Context, agg *Aggregate[T, R],
+) (eventstore.Events, error) {
+	return r.newEvents(ctx, agg)
+}
+
+// Track adds agg to uow, so that its pending state changes, including those
+// made after Track, are saved when uow is flushed or committed. The
+// repository must use the same event store as uow.
+func (r *AggregateRepository[T, R]) Track(
+	uow *UnitOfWork, agg *Aggregate[T, R],
+) {
+	uow.track(agg.ID(), trackedAggregate{
+		prepare: func(ctx context.Context) (eventstore.AggregateEvents, error) {
+			if agg.poisoned != nil {
+				return eventstore.AggregateEvents{}, agg.poisoned
+			}
+			if len(agg.stateChanges) == 0 {
+				return eventstore.AggregateEvents{AggregateID: agg.ID()}, nil
+			}
+			events, err := r.newEvents(ctx, agg)
+			if err != nil {
+				return eventstore.AggregateEvents{}, err
+			}
+			return eventstore.AggregateEvents{
+				AggregateID:              agg.ID(),
+				ExpectedAggregateVersion: agg.Version() - len(agg.stateChanges),
+				Events:                   events,
+			}, nil
+		},
+		saved: func(ctx context.Context, ae eventstore.AggregateEvents) {
+			r.saved(ctx, agg, ae.ExpectedAggregateVersion, ae.Events)
+		},
+	})
+}
+
+func (r *AggregateRepository[T, R]) newEvents(
+	ctx context.Context, agg *Aggregate[T, R],
+) (eventstore.Events, error) {
+	if agg.poisoned != nil {
+		return nil, agg.poisoned
 	}
 
 	originalVersion := agg.Version() - len(agg.stateChanges)
 	metadata := eventstore.MetadataFromContext(ctx)
+	if !r.config.reservedOverride {
+		if err := eventstore.ValidateReservedMetadata(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if len(r.config.requiredMetadata) > 0 {
+		if err := metadata.Validate(r.config.requiredMetadata...); err != nil {
+			return nil, err
+		}
+	}
+	if beforeSaver, ok := any(agg.root).(aggregateRootBeforeSaver); ok {
+		beforeSaver.BeforeSave()
+	}
+
 	events := make(eventstore.Events, 0, len(agg.stateChanges))
 
+	datas := make([][]byte, 0, len(agg.stateChanges))
+	typeURLs := make([]string, 0, len(agg.stateChanges))
 	for i, stateChange := range agg.stateChanges {
-		id, err := uuid.NewRandom()
+		data, typeURL, err := r.marshal(ctx, agg.ID(), stateChange)
 		if err != nil {
-			return fmt.Errorf("generate event ID: %w", err)
+			return nil, fmt.Errorf("marshal state change %d (%T): %w",
+				i, stateChange, err)
 		}
-		data, err := anypb.New(stateChange)
+		datas = append(datas, data)
+		typeURLs = append(typeURLs, typeURL)
+	}
+
+	for i, data := range datas {
+		id, err := r.config.idGenerator.NewID()
 		if err != nil {
-			return fmt.Errorf("marshal state change: %w", err)
+			return nil, fmt.Errorf("generate event ID: %w", err)
 		}
+		eventMetadata := make(eventstore.Metadata, len(metadata)+2)
+		maps.Copy(eventMetadata, metadata)
+		// The request that caused the first event of a chain seeds its
+		// correlation.
+		if eventMetadata.CorrelationID() == "" {
+			if causationID := eventMetadata.CausationID(); causationID != "" {
+				eventMetadata[eventstore.CorrelationID] = causationID
+			}
+		}
+		// The schema version is recorded in the event itself only.
+		delete(eventMetadata, eventstore.SchemaVersion)
+		r.config.tracer.Inject(ctx, eventMetadata)
 		events = append(events, &eventstore.Event{
-			ID:               id.String(),
+			ID:               id,
 			AggregateID:      agg.ID(),
+			AggregateType:    r.aggregateType,
 			AggregateVersion: originalVersion + i + 1,
-			Timestamp:        time.Now(),
-			Metadata:         metadata,
+			Timestamp:        r.config.clock.Now(),
+			Metadata:         eventMetadata,
+			Type:             typeURLs[i],
 			Data:             data,
+			SchemaVersion:    r.config.schemaVersion(agg.stateChanges[i]),
 		})
 	}
 
-	if err := r.eventStore.SaveEvents(
-		ctx, agg.ID(), originalVersion, events,
-	); err != nil {
-		return fmt.Errorf("save events: %w", err)
+	return events, nil
+}
+
+func (r *AggregateRepository[T, R]) marshal(
+	ctx context.Context, aggregateID string, stateChange StateChange,
+) ([]byte, string, error) {
+	if r.config.encryption != nil {
+		restore, err := encryptFields(
+			ctx, r.config.encryption, aggregateID, stateChange)
+		if err != nil {
+			return nil, "", err
+		}
+		defer restore()
 	}
 
+	return r.config.codec.Marshal(stateChange)
+}
+
+func (r *AggregateRepository[T, R]) saved(
+	ctx context.Context, agg *Aggregate[T, R], originalVersion int,
+	events eventstore.Events,
+) {
 	agg.stateChanges = nil
 
-	return nil
+	r.saveSnapshot(ctx, agg, originalVersion)
+
+	if hook := r.config.postCommitHook; hook != nil {
+		if err := hook(ctx, agg.ID(), events); err != nil {
+			if r.config.logger.Enabled(LogLevelWarn) {
+				r.config.logger.Warn("post-commit hook failed",
+					"aggregate_id", agg.ID(), "error", err)
+			}
+		}
+	}
+}
+
+func (r *AggregateRepository[T, R]) saveSnapshot(
+	ctx context.Context, agg *Aggregate[T, R], originalVersion int,
+) {
+	if r.config.snapshotStore == nil {
+		return
+	}
+
+	snapshotter, ok := any(agg.root).(aggregateRootSnapshotter)
+	if !ok {
+		return
+	}
+
+	frequency := r.config.snapshotFrequency
+	if agg.Version()/frequency == originalVersion/frequency {
+		return
+	}
+
+	data, err := anypb.New(snapshotter.Snapshot())
+	if err != nil {
+		if r.config.logger.Enabled(LogLevelWarn) {
+			r.config.logger.Warn("failed to marshal snapshot",
+				"aggregate_id", agg.ID(), "error", err)
+		}
+		return
+	}
+
+	if err := r.config.snapshotStore.SaveSnapshot(
+		ctx, agg.ID(), agg.Version(), data,
+	); err != nil {
+		if r.config.logger.Enabled(LogLevelWarn) {
+			r.config.logger.Warn("failed to save snapshot",
+				"aggregate_id", agg.ID(), "error", err)
+		}
+	}
 }
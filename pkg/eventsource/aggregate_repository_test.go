@@ -0,0 +1,612 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoreinmemory"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoretest"
+)
+
+// failingSnapshotStore fails to get snapshots with err.
+type failingSnapshotStore struct {
+	eventstore.SnapshotStore
+	err error
+}
+
+func (s failingSnapshotStore) GetLatestSnapshot(
+	context.Context, string,
+) (*eventstore.Snapshot, error) {
+	return nil, s.err
+}
+
+var errSnapshotStoreDown = errors.New("snapshot store down")
+
+func TestLoadReplaysWhenSnapshotStoreFails(t *testing.T) {
+	ctx := context.Background()
+	store := eventstoreinmemory.New()
+	logger := &recordingLogger{}
+	repo := NewAggregateRepository[snapshottedCounter](store,
+		WithSnapshots(failingSnapshotStore{store, errSnapshotStoreDown}, 1),
+		WithLogger(logger))
+
+	if _, err := repo.Create(ctx, "c", counterAdd{N: 2}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := repo.Update(ctx, "c", counterAdd{N: 3}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	agg, err := repo.Load(ctx, "c")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if agg.Version() != 2 || agg.Root().total != 5 {
+		t.Fatalf("got version %d and total %d, want 2 and 5",
+			agg.Version(), agg.Root().total)
+	}
+	if !slices.Contains(logger.warnings, "failed to get latest snapshot") {
+		t.Fatalf("warnings: got %q", logger.warnings)
+	}
+}
+
+func TestLoadWithStrictSnapshotsFailsWhenSnapshotStoreFails(t *testing.T) {
+	ctx := context.Background()
+	store := eventstoreinmemory.New()
+	if _, err := NewAggregateRepository[snapshottedCounter](store).Create(
+		ctx, "c", counterAdd{N: 2},
+	); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	repo := NewAggregateRepository[snapshottedCounter](store,
+		WithSnapshots(failingSnapshotStore{store, errSnapshotStoreDown}, 1),
+		WithStrictSnapshots())
+	if _, err := repo.Load(ctx, "c"); !errors.Is(err, errSnapshotStoreDown) {
+		t.Fatalf("load: got %v, want %v", err, errSnapshotStoreDown)
+	}
+}
+
+// shiftingEventStore lists event ranges starting shift versions off.
+type shiftingEventStore struct {
+	eventstore.Interface
+	shift int
+}
+
+func (s shiftingEventStore) ListEventsRange(
+	ctx context.Context, aggregateID string, fromVersion int, toVersion int,
+) (eventstore.Events, error) {
+	return s.Interface.ListEventsRange(
+		ctx, aggregateID, fromVersion+s.shift, toVersion)
+}
+
+func TestLoadFailsWhenEventsDoNotContinueSnapshot(t *testing.T) {
+	for name, shift := range map[string]int{"gap": 1, "overlap": -1} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := eventstoreinmemory.New()
+			repo := NewAggregateRepository[snapshottedCounter](store,
+				WithSnapshots(store, 3))
+			if _, err := repo.Create(ctx, "c", counterAdd{N: 1}); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			for range 4 {
+				if _, err := repo.Update(ctx, "c", counterAdd{N: 1}); err != nil {
+					t.Fatalf("update: %v", err)
+				}
+			}
+
+			repo = NewAggregateRepository[snapshottedCounter](
+				shiftingEventStore{store, shift}, WithSnapshots(store, 3))
+			_, err := repo.Load(ctx, "c")
+			if !errors.Is(err, ErrSnapshotMisaligned) {
+				t.Fatalf("load: got %v, want %v", err, ErrSnapshotMisaligned)
+			}
+			if errors.Is(err, ErrEventVersionMisaligned) {
+				t.Fatalf("load: got %v, want it not to be %v",
+					err, ErrEventVersionMisaligned)
+			}
+		})
+	}
+}
+
+// countingIDGenerator generates sequential IDs and counts them.
+type countingIDGenerator struct {
+	n int
+}
+
+func (g *countingIDGenerator) NewID() (string, error) {
+	g.n++
+	return fmt.Sprintf("id-%d", g.n), nil
+}
+
+func TestSaveMarshalsAllStateChangesFirst(t *testing.T) {
+	ctx := context.Background()
+	ids := &countingIDGenerator{}
+	store, repo := newCounterRepository(t, WithIDGenerator(ids))
+	if _, err := repo.Create(ctx, "c", counterAdd{N: 1}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	generated := ids.n
+
+	// legacyRenamed is not a protobuf message, so the default codec fails
+	// to marshal it.
+	_, err := repo.UpdateBatch(ctx, "c", []Command{
+		counterAdd{N: 1}, counterRenameLegacy{Name: "x"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "state change 1 ") {
+		t.Fatalf("update: got %v, want a failure to marshal state change 1",
+			err)
+	}
+	if ids.n != generated {
+		t.Fatalf("generated %d IDs before failing, want 0", ids.n-generated)
+	}
+
+	events, err := store.ListEvents(ctx, "c")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+}
+
+func TestUpdateAtFailsFastOnVersionMismatch(t *testing.T) {
+	ctx := context.Background()
+	store, repo := newCounterRepository(t)
+	if _, err := repo.Create(ctx, "c", counterAdd{N: 1}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := repo.Update(ctx, "c", counterAdd{N: 1}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	// The command would be rejected if it were processed.
+	if _, err := repo.UpdateAt(ctx, "c", 1, counterRename{}); !errors.Is(
+		err, eventstore.ErrConcurrentUpdate,
+	) {
+		t.Fatalf("update at stale version: got %v, want %v",
+			err, eventstore.ErrConcurrentUpdate)
+	}
+
+	agg, err := repo.UpdateAt(ctx, "c", 2, counterAdd{N: 1})
+	if err != nil {
+		t.Fatalf("update at current version: %v", err)
+	}
+	if agg.Version() != 3 || agg.Root().total != 3 {
+		t.Fatalf("got version %d and total %d, want 3 and 3",
+			agg.Version(), agg.Root().total)
+	}
+
+	events, err := store.ListEvents(ctx, "c")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+}
+
+func TestUpdateSkipsCommandsAlreadyProcessed(t *testing.T) {
+	ctx := context.Background()
+	store, repo := newCounterRepository(t)
+	if _, err := repo.Create(ctx, "c", counterAdd{N: 1}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	for range 2 {
+		agg, err := repo.Update(ctx, "c", counterAddOnce{N: 2, ID: "cmd-1"})
+		if err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if agg.Version() != 2 || agg.Root().total != 3 {
+			t.Fatalf("got version %d and total %d, want 2 and 3",
+				agg.Version(), agg.Root().total)
+		}
+	}
+
+	events, err := store.ListEvents(ctx, "c")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if got := events[1].Metadata.CausationID(); got != "cmd-1" {
+		t.Fatalf("causation ID: got %q, want cmd-1", got)
+	}
+}
+
+func TestStreamPagesEndingAtPageSize(t *testing.T) {
+	ctx := context.Background()
+	_, repo := newCounterRepository(t)
+	if _, err := repo.Create(ctx, "c", counterAdd{N: 1}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for range 5 {
+		if _, err := repo.Update(ctx, "c", counterAdd{N: 1}); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+
+	var pages [][]int
+	for page, err := range repo.StreamPages(ctx, "c", 3) {
+		if err != nil {
+			t.Fatalf("stream pages: %v", err)
+		}
+		var versions []int
+		for _, event := range page {
+			versions = append(versions, event.AggregateVersion)
+		}
+		pages = append(pages, versions)
+	}
+	if got := fmt.Sprint(pages); got != "[[1 2 3] [4 5 6]]" {
+		t.Fatalf("pages: got %s, want [[1 2 3] [4 5 6]]", got)
+	}
+}
+
+func TestSavedEventsCarryMetadataOfAllLayers(t *testing.T) {
+	store, repo := newCounterRepository(t)
+
+	// Each layer adds its own metadata before calling the next one.
+	type handler func(ctx context.Context) error
+	withMetadata := func(md eventstore.Metadata, next handler) handler {
+		return func(ctx context.Context) error {
+			return next(eventstore.WithMetadata(ctx, md))
+		}
+	}
+	h := withMetadata(eventstore.Metadata{
+		eventstore.UserID: "u",
+		"X-Source":        "edge",
+	}, func(ctx context.Context) error {
+		return withMetadata(eventstore.Metadata{
+			"X-Reason": "r",
+			"X-Source": "handler",
+		}, func(ctx context.Context) error {
+			_, err := repo.Create(ctx, "c", counterAdd{N: 1})
+			return err
+		})(eventstore.WithCorrelationID(ctx, "corr"))
+	})
+	if err := h(context.Background()); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	events, err := store.ListEvents(context.Background(), "c")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	md := events[0].Metadata
+	for key, want := range map[string]string{
+		eventstore.UserID:        "u",
+		eventstore.CorrelationID: "corr",
+		"X-Reason":               "r",
+		"X-Source":               "handler",
+	} {
+		if got, _ := md[key].(string); got != want {
+			t.Fatalf("metadata %s: got %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLoadUnreadableEvents(t *testing.T) {
+	ctx := context.Background()
+	logger := &recordingLogger{}
+	store, skipping := newCounterRepository(t,
+		WithSkipUnreadableEvents(), WithLogger(logger))
+	strict := NewAggregateRepository[counter](store)
+
+	// The type of the second event has since been dropped from the binary.
+	const gone = "type.googleapis.com/gone.Removed"
+	if _, err := skipping.Create(ctx, "c", counterAdd{N: 1}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := store.SaveEvents(ctx, "c", 1, eventstore.Events{{
+		ID:               "c-2",
+		AggregateID:      "c",
+		AggregateVersion: 2,
+		Timestamp:        time.Now(),
+		Metadata:         eventstore.Metadata{},
+		Type:             gone,
+		Data:             []byte("{}"),
+	}}); err != nil {
+		t.Fatalf("save unreadable event: %v", err)
+	}
+	if _, err := skipping.Update(ctx, "c", counterAdd{N: 1}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	_, err := strict.Load(ctx, "c")
+	if !errors.Is(err, ErrUnknownEventType) {
+		t.Fatalf("strict load: got %v, want %v", err, ErrUnknownEventType)
+	}
+	if want := "aggregate c at version 2 (" + gone + ")"; !strings.Contains(
+		err.Error(), want,
+	) {
+		t.Fatalf("strict load: got %q, want it to contain %q", err, want)
+	}
+
+	agg, err := skipping.Load(ctx, "c")
+	if err != nil {
+		t.Fatalf("skipping load: %v", err)
+	}
+	if agg.Version() != 3 || agg.Root().total != 2 {
+		t.Fatalf("got version %d and total %d, want 3 and 2",
+			agg.Version(), agg.Root().total)
+	}
+	if !slices.Contains(logger.warnings, "skipped unreadable event") {
+		t.Fatalf("warnings: got %q", logger.warnings)
+	}
+}
+
+// racingEventStore runs race once, right before the first save.
+type racingEventStore struct {
+	eventstore.Interface
+	race func()
+}
+
+func (s *racingEventStore) SaveEvents(
+	ctx context.Context, aggregateID string, expectedAggregateVersion int,
+	events eventstore.Events,
+) error {
+	if race := s.race; race != nil {
+		s.race = nil
+		race()
+	}
+	return s.Interface.SaveEvents(ctx, aggregateID, expectedAggregateVersion,
+		events)
+}
+
+func TestGetOrCreateWithStatus(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("new and existing", func(t *testing.T) {
+		repo := NewAggregateRepository[counter](eventstoreinmemory.New())
+
+		agg, created, err := repo.GetOrCreateWithStatus(
+			ctx, "c", counterAdd{N: 2})
+		if err != nil || !created || agg.Root().total != 2 {
+			t.Fatalf("first call: got %v, %t, %v", agg, created, err)
+		}
+
+		agg, created, err = repo.GetOrCreateWithStatus(
+			ctx, "c", counterAdd{N: 3})
+		if err != nil || created || agg.Root().total != 2 {
+			t.Fatalf("second call: got %v, %t, %v", agg, created, err)
+		}
+	})
+
+	t.Run("created concurrently", func(t *testing.T) {
+		inner := eventstoreinmemory.New()
+		store := &racingEventStore{Interface: inner}
+		store.race = func() {
+			if _, err := NewAggregateRepository[counter](inner).Create(
+				ctx, "c", counterAdd{N: 5},
+			); err != nil {
+				t.Fatalf("create concurrently: %v", err)
+			}
+		}
+		repo := NewAggregateRepository[counter](store)
+
+		agg, created, err := repo.GetOrCreateWithStatus(
+			ctx, "c", counterAdd{N: 2})
+		if err != nil || created || agg.Root().total != 5 {
+			t.Fatalf("got %v, %t, %v", agg, created, err)
+		}
+	})
+}
+
+func TestCreateRacingRetry(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name    string
+		racing  Command
+		wantErr error
+	}{
+		{"same causation ID", counterAddOnce{N: 5, ID: "cmd-1"}, nil},
+		{"other causation ID", counterAddOnce{N: 5, ID: "cmd-2"},
+			ErrAggregateAlreadyExists},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := eventstoreinmemory.New()
+			store := &racingEventStore{Interface: inner}
+			store.race = func() {
+				if _, err := NewAggregateRepository[counter](inner).Create(
+					ctx, "c", tc.racing,
+				); err != nil {
+					t.Fatalf("create concurrently: %v", err)
+				}
+			}
+			repo := NewAggregateRepository[counter](store)
+
+			agg, err := repo.Create(ctx, "c", counterAddOnce{N: 2, ID: "cmd-1"})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("create: got %v, want %v", err, tc.wantErr)
+			}
+			if err == nil && agg.Root().total != 5 {
+				t.Fatalf("total: got %d, want 5", agg.Root().total)
+			}
+		})
+	}
+}
+
+func TestGetOrCreateIsTraced(t *testing.T) {
+	ctx := context.Background()
+	tracer := &eventstoretest.RecordingTracer{}
+	_, repo := newCounterRepository(t, WithTracer(tracer))
+
+	if _, err := repo.GetOrCreate(ctx, "c", counterAdd{N: 2}); err != nil {
+		t.Fatalf("get or create: %v", err)
+	}
+	if _, _, err := repo.GetOrCreateWithStatus(
+		ctx, "c", counterAdd{N: 2},
+	); err != nil {
+		t.Fatalf("get or create with status: %v", err)
+	}
+
+	var created []any
+	for _, span := range tracer.Spans() {
+		if span.Name == "eventsource.GetOrCreate" {
+			if !span.Ended {
+				t.Fatalf("span not ended")
+			}
+			created = append(created, span.Attributes["eventsource.created"])
+		}
+	}
+	if !slices.Equal(created, []any{true, false}) {
+		t.Fatalf("created attributes: got %v, want [true false]", created)
+	}
+}
+
+func TestUpdateAtIsTraced(t *testing.T) {
+	ctx := context.Background()
+	tracer := &eventstoretest.RecordingTracer{}
+	_, repo := newCounterRepository(t, WithTracer(tracer))
+
+	if _, err := repo.Create(ctx, "c", counterAdd{N: 2}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := repo.UpdateAt(ctx, "c", 1, counterAdd{N: 3}); err != nil {
+		t.Fatalf("update at: %v", err)
+	}
+	if _, err := repo.UpdateAtBusinessVersion(
+		ctx, "c", 1, counterAdd{N: 4},
+	); !errors.Is(err, eventstore.ErrConcurrentUpdate) {
+		t.Fatalf("update at business version: got %v, want %v",
+			err, eventstore.ErrConcurrentUpdate)
+	}
+
+	span, ok := tracer.Span("eventsource.UpdateAt")
+	if !ok || !span.Ended || span.Err != nil ||
+		span.Attributes["eventsource.aggregate_version"] != 2 {
+		t.Fatalf("UpdateAt span: got %+v", span)
+	}
+	span, ok = tracer.Span("eventsource.UpdateAtBusinessVersion")
+	if !ok || !span.Ended ||
+		!errors.Is(span.Err, eventstore.ErrConcurrentUpdate) {
+		t.Fatalf("UpdateAtBusinessVersion span: got %+v", span)
+	}
+}
+
+func TestAggregatePoisonedByFailingApply(t *testing.T) {
+	ctx := context.Background()
+	store, repo := newCounterRepository(t)
+	if _, err := repo.Create(ctx, "c", counterAdd{N: 2}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	agg, err := repo.Load(ctx, "c")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := agg.ProcessCommand(
+		ctx, counterAddBroken{N: 3},
+	); !errors.Is(err, ErrAggregatePoisoned) {
+		t.Fatalf("process broken command: got %v, want %v",
+			err, ErrAggregatePoisoned)
+	}
+	if !agg.Poisoned() {
+		t.Fatalf("aggregate not poisoned")
+	}
+
+	if err := agg.ProcessCommand(
+		ctx, counterAdd{N: 1},
+	); !errors.Is(err, ErrAggregatePoisoned) {
+		t.Fatalf("process command: got %v, want %v", err, ErrAggregatePoisoned)
+	}
+	if err := repo.Save(ctx, agg); !errors.Is(err, ErrAggregatePoisoned) {
+		t.Fatalf("save: got %v, want %v", err, ErrAggregatePoisoned)
+	}
+	uow := NewUnitOfWork(store)
+	repo.Track(uow, agg)
+	if err := uow.Commit(ctx); !errors.Is(err, ErrAggregatePoisoned) {
+		t.Fatalf("commit: got %v, want %v", err, ErrAggregatePoisoned)
+	}
+	if _, err := repo.Update(
+		ctx, "c", counterAddBroken{N: 3},
+	); !errors.Is(err, ErrAggregatePoisoned) {
+		t.Fatalf("update: got %v, want %v", err, ErrAggregatePoisoned)
+	}
+	assertStreamLength(t, store, "c", 1)
+
+	agg, err = repo.Load(ctx, "c")
+	if err != nil {
+		t.Fatalf("load again: %v", err)
+	}
+	if agg.Poisoned() || agg.Root().total != 2 {
+		t.Fatalf("reloaded: got poisoned %t and total %d, want false and 2",
+			agg.Poisoned(), agg.Root().total)
+	}
+}
+
+// blockingCounter is a counter whose ProcessCommand reports it was entered
+// and then waits to be released.
+type blockingCounter struct {
+	counter
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingCounter) ProcessCommand(command Command) (StateChanges, error) {
+	c.entered <- struct{}{}
+	<-c.release
+	return c.counter.ProcessCommand(command)
+}
+
+func TestConcurrentUseOfAggregatePanics(t *testing.T) {
+	ctx := context.Background()
+	agg := NewAggregate[blockingCounter]("c")
+	agg.Root().entered = make(chan struct{})
+	agg.Root().release = make(chan struct{})
+
+	done := make(chan error)
+	go func() {
+		done <- agg.ProcessCommand(ctx, counterAdd{N: 1})
+	}()
+	<-agg.Root().entered
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("concurrent ProcessCommand did not panic")
+			}
+		}()
+		_ = agg.ProcessCommand(ctx, counterAdd{N: 2})
+	}()
+
+	close(agg.Root().release)
+	if err := <-done; err != nil {
+		t.Fatalf("process command: %v", err)
+	}
+
+	// Once the first goroutine is done, the aggregate can be handed over.
+	go func() {
+		done <- agg.ProcessCommand(ctx, counterAdd{N: 3})
+	}()
+	<-agg.Root().entered
+	if err := <-done; err != nil {
+		t.Fatalf("process command after hand-over: %v", err)
+	}
+	if agg.Version() != 2 || agg.Root().total != 4 {
+		t.Fatalf("got version %d and total %d, want 2 and 4",
+			agg.Version(), agg.Root().total)
+	}
+}
+
+func TestGetOfMissingAggregate(t *testing.T) {
+	ctx := context.Background()
+	_, repo := newCounterRepository(t)
+
+	_, err := repo.Get(ctx, "missing")
+	if !errors.Is(err, ErrAggregateDoesNotExist) ||
+		!errors.Is(err, eventstore.ErrStreamNotFound) {
+		t.Fatalf("get missing aggregate: got %v, want %v and %v",
+			err, ErrAggregateDoesNotExist, eventstore.ErrStreamNotFound)
+	}
+}
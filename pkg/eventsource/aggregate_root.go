@@ -1,7 +1,58 @@
 package eventsource
 
+import "google.golang.org/protobuf/proto"
+
 type aggregateRoot[T any] interface {
 	*T
 	ProcessCommand(Command) (StateChanges, error)
-	ApplyStateChange(StateChange)
+	// ApplyStateChange fails, with an error wrapping ErrUnknownStateChange
+	// for state changes it does not know, rather than panicking, so that
+	// loading events written by newer code fails gracefully.
+	ApplyStateChange(StateChange) error
+}
+
+// aggregateRootInitializer is implemented by roots that need to know their
+// aggregate ID. Init is called on a freshly constructed root before any
+// state changes are applied.
+type aggregateRootInitializer interface {
+	Init(id string)
+}
+
+// aggregateRootTypeNamer is implemented by roots naming their aggregate
+// type, which otherwise is the name of the root type. The name is recorded
+// on every event, so it must not change once events have been saved.
+type aggregateRootTypeNamer interface {
+	TypeName() string
+}
+
+// aggregateRootBusinessVersioner is implemented by roots exposing a
+// client-facing version distinct from the number of stored events. The
+// business version must be derived solely from the applied state changes,
+// so that replaying the same events always yields the same value, and must
+// never decrease as state changes are applied.
+type aggregateRootBusinessVersioner interface {
+	BusinessVersion() int
+}
+
+// aggregateRootSnapshotter is implemented by roots that can be snapshotted.
+// RestoreSnapshot is called on a freshly initialized root with a message
+// previously returned by Snapshot.
+type aggregateRootSnapshotter interface {
+	Snapshot() proto.Message
+	RestoreSnapshot(proto.Message)
+}
+
+// aggregateRootAfterLoader is implemented by roots deriving state that is
+// costly to maintain per state change. AfterLoad is called once the stored
+// state changes have all been applied, also when there are none, and not
+// for the state changes applied while processing commands.
+type aggregateRootAfterLoader interface {
+	AfterLoad()
+}
+
+// aggregateRootBeforeSaver is implemented by roots that need to act before
+// their pending state changes are marshaled into events, be it for saving
+// or for UncommittedEvents.
+type aggregateRootBeforeSaver interface {
+	BeforeSave()
 }
@@ -0,0 +1,344 @@
+package eventsource
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// CachingRepository keeps the streams of the aggregates recently loaded
+// through it in an LRU cache and serves Get and Update from them, so that
+// hot aggregates are not listed from the event store on every request.
+// Every call rehydrates a fresh aggregate from the cached events, owned by
+// the caller like one returned by the wrapped repository. Snapshots are not
+// used.
+//
+// Each cached stream is at a version, which Update saves at, so that
+// writes bypassing the cache, through the wrapped repository or by other
+// processes, fail it with eventstore.ErrConcurrentUpdate, evicting the
+// stream to be listed again when the update is retried as configured by
+// WithConflictRetry. Get serves such streams stale until they expire, see
+// WithCacheMaxAge. Create, Save and Delete evict the stream. It is safe for
+// concurrent use.
+type CachingRepository[T any, R aggregateRoot[T]] struct {
+	repository *AggregateRepository[T, R]
+	config     cacheConfig
+	mu         sync.Mutex
+	entries    map[cacheKey]*list.Element
+	lru        *list.List
+	loads      map[cacheKey]uint64
+	loadSeq    uint64
+}
+
+func NewCachingRepository[T any, R aggregateRoot[T]](
+	repository *AggregateRepository[T, R], opts ...cacheOption,
+) *CachingRepository[T, R] {
+	return &CachingRepository[T, R]{
+		repository: repository,
+		config:     newCacheConfig(opts...),
+		entries:    make(map[cacheKey]*list.Element),
+		lru:        list.New(),
+		loads:      make(map[cacheKey]uint64),
+	}
+}
+
+type cacheConfig struct {
+	maxEntries int
+	maxAge     time.Duration
+}
+
+func newCacheConfig(opts ...cacheOption) cacheConfig {
+	cfg := cacheConfig{
+		maxEntries: 1000,
+		maxAge:     time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+type cacheOption func(*cacheConfig)
+
+// WithCacheMaxEntries sets how many streams are kept, the least recently
+// used being evicted first.
+func WithCacheMaxEntries(n int) cacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.maxEntries = max(n, 1)
+	}
+}
+
+// WithCacheMaxAge sets for how long a stream is served after it was last
+// listed or saved, as told by the clock of the wrapped repository. This
+// bounds how stale Get can be when the stream is written to bypassing the
+// cache. An age of 0 means no limit.
+func WithCacheMaxAge(age time.Duration) cacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.maxAge = age
+	}
+}
+
+type cacheKey struct {
+	tenantID    string
+	aggregateID string
+}
+
+func newCacheKey(ctx context.Context, id string) cacheKey {
+	return cacheKey{
+		tenantID:    eventstore.MetadataFromContext(ctx).TenantID(),
+		aggregateID: id,
+	}
+}
+
+type cacheEntry struct {
+	key       cacheKey
+	events    eventstore.Events
+	updatedAt time.Time
+}
+
+func (e *cacheEntry) version() int {
+	if len(e.events) == 0 {
+		return 0
+	}
+	return e.events[len(e.events)-1].AggregateVersion
+}
+
+// Repository returns the wrapped repository, writes through which bypass
+// the cache.
+func (c *CachingRepository[T, R]) Repository() *AggregateRepository[T, R] {
+	return c.repository
+}
+
+// Get is like AggregateRepository.Get, but serves the aggregate from the
+// cache if its stream is there.
+func (c *CachingRepository[T, R]) Get(
+	ctx context.Context, id string,
+) (*Aggregate[T, R], error) {
+	ctx, span := c.repository.startSpan(ctx, "eventsource.CachedGet", id)
+	agg, _, err := c.load(ctx, id)
+	if err == nil && agg.Version() == 0 {
+		agg, err = nil, doesNotExist(agg)
+	}
+	endSpan(span, agg, err)
+	return agg, err
+}
+
+// Update is like AggregateRepository.Update, but processes cmd on the
+// aggregate as cached, and caches the saved events.
+func (c *CachingRepository[T, R]) Update(
+	ctx context.Context, id string, cmd Command,
+) (*Aggregate[T, R], error) {
+	ctx, span := c.repository.startSpan(ctx, "eventsource.CachedUpdate", id)
+	agg, err := c.repository.retryConflicts(ctx,
+		func() (*Aggregate[T, R], error) {
+			return c.update(ctx, id, cmd)
+		})
+	endSpan(span, agg, err)
+	return agg, err
+}
+
+func (c *CachingRepository[T, R]) update(
+	ctx context.Context, id string, cmd Command,
+) (*Aggregate[T, R], error) {
+	ctx = contextWithCommand(ctx, cmd)
+
+	agg, events, err := c.load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("load: %w", err)
+	}
+
+	if agg.Version() == 0 {
+		return nil, doesNotExist(agg)
+	}
+
+	if err := c.repository.processCommand(ctx, agg, cmd, true); err != nil {
+		if errors.Is(err, ErrCommandAlreadyProcessed) {
+			return agg, nil
+		}
+		return nil, fmt.Errorf("process command: %w", err)
+	}
+
+	saved, err := c.repository.SaveAndReturn(ctx, agg)
+	if err != nil {
+		c.invalidate(newCacheKey(ctx, id))
+		return nil, fmt.Errorf("save: %w", err)
+	}
+
+	c.store(newCacheKey(ctx, id), slices.Concat(events, saved))
+
+	return agg, nil
+}
+
+// Create is AggregateRepository.Create evicting the stream.
+func (c *CachingRepository[T, R]) Create(
+	ctx context.Context, id string, cmd Command,
+) (*Aggregate[T, R], error) {
+	agg, err := c.repository.Create(ctx, id, cmd)
+	if agg != nil {
+		id = agg.ID()
+	}
+	c.invalidate(newCacheKey(ctx, id))
+	return agg, err
+}
+
+// Save is AggregateRepository.Save evicting the stream.
+func (c *CachingRepository[T, R]) Save(
+	ctx context.Context, agg *Aggregate[T, R],
+) error {
+	defer c.invalidate(newCacheKey(ctx, agg.ID()))
+
+	return c.repository.Save(ctx, agg)
+}
+
+// Delete is AggregateRepository.Delete evicting the stream.
+func (c *CachingRepository[T, R]) Delete(ctx context.Context, id string) error {
+	defer c.invalidate(newCacheKey(ctx, id))
+
+	return c.repository.Delete(ctx, id)
+}
+
+// load rehydrates the aggregate from its stream, listing the stream from
+// the event store and caching it if it is not cached. It returns the events
+// the aggregate was rehydrated from.
+func (c *CachingRepository[T, R]) load(
+	ctx context.Context, id string,
+) (*Aggregate[T, R], eventstore.Events, error) {
+	if id == "" {
+		return nil, nil, ErrEmptyAggregateID
+	}
+
+	key := newCacheKey(ctx, id)
+
+	events, ok := c.get(key)
+	if !ok {
+		load := c.startLoad(key)
+
+		var err error
+		events, err = c.repository.eventStore.ListEvents(ctx, id)
+		if err != nil && !errors.Is(err, eventstore.ErrStreamNotFound) {
+			c.finishLoad(key, load, nil)
+			return nil, nil, fmt.Errorf("list events: %w", err)
+		}
+
+		c.finishLoad(key, load, events)
+	}
+
+	agg, err := c.repository.rehydrate(
+		ctx, id, 0, newAggregateRoot[T, R](id), events)
+	if err != nil {
+		return nil, nil, fmt.Errorf("rehydrate: %w", err)
+	}
+
+	return agg, events, nil
+}
+
+func (c *CachingRepository[T, R]) get(key cacheKey) (eventstore.Events, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := elem.Value.(*cacheEntry)
+	if c.expired(e) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+
+	return e.events, true
+}
+
+// startLoad marks the stream as being listed, so that a write meanwhile
+// can keep the listed events, which may predate it, from being cached.
+func (c *CachingRepository[T, R]) startLoad(key cacheKey) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.loadSeq++
+	c.loads[key] = c.loadSeq
+
+	return c.loadSeq
+}
+
+// finishLoad caches the listed events, if any, unless the stream was
+// written to or listed again since load started.
+func (c *CachingRepository[T, R]) finishLoad(
+	key cacheKey, load uint64, events eventstore.Events,
+) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.loads[key] != load {
+		return
+	}
+	delete(c.loads, key)
+
+	if events != nil {
+		c.put(key, events)
+	}
+}
+
+// store caches the events of a stream just saved, unless a later version
+// is cached already.
+func (c *CachingRepository[T, R]) store(key cacheKey, events eventstore.Events) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.loads, key)
+	c.put(key, events)
+}
+
+func (c *CachingRepository[T, R]) invalidate(key cacheKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.loads, key)
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// put requires c.mu to be held. The cached events are never modified, as
+// they are handed out to rehydrate aggregates without copying.
+func (c *CachingRepository[T, R]) put(key cacheKey, events eventstore.Events) {
+	e := &cacheEntry{
+		key:       key,
+		events:    slices.Clip(events),
+		updatedAt: c.repository.config.clock.Now(),
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		if elem.Value.(*cacheEntry).version() > e.version() {
+			return
+		}
+		elem.Value = e
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(e)
+
+	for c.lru.Len() > c.config.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *CachingRepository[T, R]) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
+
+func (c *CachingRepository[T, R]) expired(e *cacheEntry) bool {
+	return c.config.maxAge > 0 &&
+		c.repository.config.clock.Now().Sub(e.updatedAt) > c.config.maxAge
+}
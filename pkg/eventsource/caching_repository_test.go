@@ -0,0 +1,271 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoreinmemory"
+)
+
+// listingStore counts the streams listed from the store it wraps, and calls
+// listed, if set, once a stream has been listed.
+type listingStore struct {
+	eventstore.Interface
+	lists  atomic.Int64
+	listed func()
+}
+
+func (s *listingStore) ListEvents(
+	ctx context.Context, aggregateID string,
+) (eventstore.Events, error) {
+	s.lists.Add(1)
+	events, err := s.Interface.ListEvents(ctx, aggregateID)
+	if listed := s.listed; listed != nil {
+		s.listed = nil
+		listed()
+	}
+	return events, err
+}
+
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newCachingCounterRepository(
+	t testing.TB, opts []option, cacheOpts ...cacheOption,
+) (*listingStore, *CachingRepository[counter, *counter]) {
+	t.Helper()
+
+	store := &listingStore{Interface: eventstoreinmemory.New()}
+	repo := NewAggregateRepository[counter](store, opts...)
+	return store, NewCachingRepository(repo, cacheOpts...)
+}
+
+func assertCachedTotal(
+	t testing.TB, cache *CachingRepository[counter, *counter], want int64,
+) {
+	t.Helper()
+
+	agg, err := cache.Get(context.Background(), "c")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got := agg.Root().total; got != want {
+		t.Fatalf("total: got %d, want %d", got, want)
+	}
+}
+
+func TestCachedReadsDoNotHitStore(t *testing.T) {
+	ctx := context.Background()
+	store, cache := newCachingCounterRepository(t, nil)
+
+	if _, err := cache.Create(ctx, "c", counterAdd{N: 1}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for range 3 {
+		assertCachedTotal(t, cache, 1)
+	}
+	for range 2 {
+		if _, err := cache.Update(ctx, "c", counterAdd{N: 1}); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+	assertCachedTotal(t, cache, 3)
+
+	// Create lists the stream to check it does not exist, then the first
+	// Get lists it again, as Create evicts it.
+	if n := store.lists.Load(); n != 2 {
+		t.Fatalf("listed %d times from the store, want 2", n)
+	}
+}
+
+func TestCachedUpdateReloadsStaleStream(t *testing.T) {
+	ctx := context.Background()
+	store, cache := newCachingCounterRepository(t, nil)
+
+	if _, err := cache.Create(ctx, "c", counterAdd{N: 1}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	assertCachedTotal(t, cache, 1)
+
+	// A write bypassing the cache makes the cached stream stale.
+	if _, err := cache.Repository().Update(
+		ctx, "c", counterAdd{N: 10},
+	); err != nil {
+		t.Fatalf("update bypassing cache: %v", err)
+	}
+	assertCachedTotal(t, cache, 1)
+
+	agg, err := cache.Update(ctx, "c", counterAdd{N: 100})
+	if err != nil {
+		t.Fatalf("update stale stream: %v", err)
+	}
+	if agg.Version() != 3 || agg.Root().total != 111 {
+		t.Fatalf("updated stale stream: got version %d and total %d, "+
+			"want 3 and 111", agg.Version(), agg.Root().total)
+	}
+	assertCachedTotal(t, cache, 111)
+
+	lists := store.lists.Load()
+	assertCachedTotal(t, cache, 111)
+	if n := store.lists.Load(); n != lists {
+		t.Fatalf("listed %d times from the store after reload, want 0",
+			n-lists)
+	}
+}
+
+func TestCachedUpdateWithConcurrentWriters(t *testing.T) {
+	ctx := context.Background()
+	_, cache := newCachingCounterRepository(t,
+		[]option{WithConflictRetry(1000, nil)})
+
+	if _, err := cache.Create(ctx, "c", counterAdd{N: 1}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	const writers, updates = 4, 25
+	update := []func(context.Context, string, Command) (
+		*Aggregate[counter, *counter], error,
+	){
+		cache.Update,
+		cache.Repository().Update,
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*len(update))
+	for range writers {
+		for _, update := range update {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range updates {
+					if _, err := update(ctx, "c", counterAdd{N: 1}); err != nil {
+						errs <- err
+						return
+					}
+					if _, err := cache.Get(ctx, "c"); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("update: %v", err)
+	}
+
+	// The cached stream may be stale after the last write bypassing the
+	// cache, until an update through the cache conflicts with it.
+	if _, err := cache.Update(ctx, "c", counterAdd{N: 1}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	want := int64(1 + writers*len(update)*updates + 1)
+	assertCachedTotal(t, cache, want)
+
+	agg, err := cache.Repository().Get(ctx, "c")
+	if err != nil {
+		t.Fatalf("get bypassing cache: %v", err)
+	}
+	if agg.Root().total != want || int64(agg.Version()) != want {
+		t.Fatalf("stored: got version %d and total %d, want %d",
+			agg.Version(), agg.Root().total, want)
+	}
+}
+
+func TestWriteDuringLoadIsNotCachedOver(t *testing.T) {
+	ctx := context.Background()
+	store, cache := newCachingCounterRepository(t, nil)
+
+	if _, err := cache.Repository().Create(
+		ctx, "c", counterAdd{N: 1},
+	); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// The stream is deleted through the cache after Get lists it, but
+	// before Get caches it.
+	store.listed = func() {
+		if err := cache.Delete(ctx, "c"); err != nil {
+			t.Errorf("delete: %v", err)
+		}
+	}
+	assertCachedTotal(t, cache, 1)
+
+	if _, err := cache.Get(ctx, "c"); !errors.Is(
+		err, ErrAggregateDoesNotExist,
+	) {
+		t.Fatalf("get deleted: got %v, want %v", err, ErrAggregateDoesNotExist)
+	}
+}
+
+func TestCachedStreamExpires(t *testing.T) {
+	ctx := context.Background()
+	clock := &manualClock{now: time.Now()}
+	_, cache := newCachingCounterRepository(t,
+		[]option{WithClock(clock)}, WithCacheMaxAge(time.Minute))
+
+	if _, err := cache.Create(ctx, "c", counterAdd{N: 1}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	assertCachedTotal(t, cache, 1)
+
+	if _, err := cache.Repository().Update(
+		ctx, "c", counterAdd{N: 1},
+	); err != nil {
+		t.Fatalf("update bypassing cache: %v", err)
+	}
+
+	clock.Advance(time.Minute)
+	assertCachedTotal(t, cache, 1)
+
+	clock.Advance(time.Second)
+	assertCachedTotal(t, cache, 2)
+}
+
+func TestLeastRecentlyUsedStreamIsEvicted(t *testing.T) {
+	ctx := context.Background()
+	store, cache := newCachingCounterRepository(t, nil,
+		WithCacheMaxEntries(2))
+
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := cache.Repository().Create(
+			ctx, id, counterAdd{N: 1},
+		); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	lists := store.lists.Load()
+
+	for _, id := range []string{"a", "b", "a", "c", "a", "b"} {
+		if _, err := cache.Get(ctx, id); err != nil {
+			t.Fatalf("get %s: %v", id, err)
+		}
+	}
+
+	// b is evicted by c, having been used less recently than a.
+	if n := store.lists.Load() - lists; n != 4 {
+		t.Fatalf("listed %d times from the store, want 4", n)
+	}
+}
@@ -0,0 +1,15 @@
+package eventsource
+
+import "time"
+
+// Clock tells the time stamped on events, e.g. a fake one in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the real clock.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
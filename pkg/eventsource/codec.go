@@ -0,0 +1,200 @@
+package eventsource
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+var (
+	_ Codec = ProtoCodec{}
+	_ Codec = JSONCodec{}
+	_ Codec = (*DispatchingCodec)(nil)
+)
+
+// Codec serializes state changes into event data. Marshal returns a type
+// discriminator alongside the data, which is stored with the event and
+// passed back to Unmarshal.
+type Codec interface {
+	Marshal(stateChange StateChange) (data []byte, typeURL string, err error)
+	Unmarshal(typeURL string, data []byte) (StateChange, error)
+}
+
+// ProtoCodec is the default codec. It requires state changes to be protobuf
+// messages, encodes them as protojson and discriminates them by type URL.
+// Types are resolved against Types if set, and against the global protobuf
+// registry otherwise.
+type ProtoCodec struct {
+	Types *EventTypeRegistry
+}
+
+const protoTypeURLPrefix = "type.googleapis.com/"
+
+func (ProtoCodec) Marshal(stateChange StateChange) ([]byte, string, error) {
+	msg, ok := stateChange.(proto.Message)
+	if !ok {
+		return nil, "", fmt.Errorf("%T is not a proto message", stateChange)
+	}
+
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, protoTypeURLPrefix + string(proto.MessageName(msg)), nil
+}
+
+func (c ProtoCodec) Unmarshal(typeURL string, data []byte) (StateChange, error) {
+	msg, err := c.newMessage(typeURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown fields are discarded so that data stored as a JSON encoded
+	// anypb.Any, which carries an extra @type field, still decodes.
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(
+		data, msg,
+	); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func (c ProtoCodec) newMessage(typeURL string) (proto.Message, error) {
+	if c.Types == nil {
+		msgType, err := protoregistry.GlobalTypes.FindMessageByURL(typeURL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, typeURL)
+		}
+		return msgType.New().Interface(), nil
+	}
+
+	name := strings.TrimPrefix(typeURL, protoTypeURLPrefix)
+	t, ok := c.Types.lookupType(name)
+	if !ok || t.Kind() != reflect.Pointer {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, typeURL)
+	}
+
+	msg, ok := reflect.New(t.Elem()).Interface().(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a proto message", t)
+	}
+
+	return msg, nil
+}
+
+// JSONCodec encodes state changes with encoding/json. Every state change type
+// has to be registered in types, and is discriminated by its name there.
+type JSONCodec struct {
+	types *EventTypeRegistry
+}
+
+func NewJSONCodec(types *EventTypeRegistry) JSONCodec {
+	return JSONCodec{types: types}
+}
+
+func (c JSONCodec) Marshal(stateChange StateChange) ([]byte, string, error) {
+	name, ok := c.types.lookupName(reflect.TypeOf(stateChange))
+	if !ok {
+		return nil, "", fmt.Errorf("%T is not registered", stateChange)
+	}
+
+	data, err := json.Marshal(stateChange)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, name, nil
+}
+
+func (c JSONCodec) Unmarshal(name string, data []byte) (StateChange, error) {
+	t, ok := c.types.lookupType(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, name)
+	}
+
+	if t.Kind() == reflect.Pointer {
+		v := reflect.New(t.Elem())
+		if err := json.Unmarshal(data, v.Interface()); err != nil {
+			return nil, err
+		}
+		return v.Interface(), nil
+	}
+
+	v := reflect.New(t)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
+
+// DispatchingCodec serializes each state change with the codec registered
+// for its type, and the others with the default codec. As the type
+// discriminator is stored apart from the data, events are routed back to
+// the codec that produced them by their discriminator, which lets event
+// types encoded differently, e.g. legacy JSON ones, share a stream with
+// protobuf ones.
+type DispatchingCodec struct {
+	defaultCodec Codec
+	mu           sync.RWMutex
+	byType       map[reflect.Type]Codec
+	byTypeURL    map[string]Codec
+}
+
+func NewDispatchingCodec(defaultCodec Codec) *DispatchingCodec {
+	return &DispatchingCodec{
+		defaultCodec: defaultCodec,
+		byType:       make(map[reflect.Type]Codec),
+		byTypeURL:    make(map[string]Codec),
+	}
+}
+
+// Register makes codec serialize state changes of the type of prototype.
+// The discriminator codec gives prototype routes events back to it, so
+// codec must give the same discriminator to all values of the type. It
+// panics if codec cannot marshal prototype.
+func (c *DispatchingCodec) Register(codec Codec, prototype StateChange) {
+	_, typeURL, err := codec.Marshal(prototype)
+	if err != nil {
+		panic(fmt.Sprintf("eventsource: register %T: %v", prototype, err))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.byType[reflect.TypeOf(prototype)] = codec
+	c.byTypeURL[typeURL] = codec
+}
+
+func (c *DispatchingCodec) Marshal(
+	stateChange StateChange,
+) ([]byte, string, error) {
+	c.mu.RLock()
+	codec, ok := c.byType[reflect.TypeOf(stateChange)]
+	c.mu.RUnlock()
+
+	if !ok {
+		codec = c.defaultCodec
+	}
+	return codec.Marshal(stateChange)
+}
+
+func (c *DispatchingCodec) Unmarshal(
+	typeURL string, data []byte,
+) (StateChange, error) {
+	c.mu.RLock()
+	codec, ok := c.byTypeURL[typeURL]
+	c.mu.RUnlock()
+
+	if !ok {
+		codec = c.defaultCodec
+	}
+	return codec.Unmarshal(typeURL, data)
+}
@@ -0,0 +1,46 @@
+package eventsource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoretest"
+)
+
+func TestDispatchingCodecRoundTripsProtoAndJSON(t *testing.T) {
+	ctx := context.Background()
+
+	types := NewEventTypeRegistry()
+	types.Register("legacy.Renamed", legacyRenamed{})
+	codec := NewDispatchingCodec(ProtoCodec{})
+	codec.Register(NewJSONCodec(types), legacyRenamed{})
+
+	store, repo := newCounterRepository(t, WithCodec(codec))
+
+	if _, err := repo.Create(ctx, "c", counterAdd{N: 2}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := repo.Update(ctx, "c", counterRenameLegacy{Name: "x"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	eventstoretest.AssertEventTypes(t, store, "c",
+		"type.googleapis.com/google.protobuf.Int64Value", "legacy.Renamed")
+
+	events, err := store.ListEvents(ctx, "c")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if got := string(events[1].Data); got != `{"Name":"x"}` {
+		t.Fatalf("data of legacy event: got %s", got)
+	}
+
+	agg, err := repo.Load(ctx, "c")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if agg.Root().total != 2 || agg.Root().name != "x" {
+		t.Fatalf("got total %d and name %q, want 2 and x",
+			agg.Root().total, agg.Root().name)
+	}
+}
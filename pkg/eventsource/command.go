@@ -1,3 +1,42 @@
 package eventsource
 
+import (
+	"context"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
 type Command any
+
+// CausationIDCarrier is implemented by commands carrying their own causation
+// ID, e.g. the ID of the message they were delivered in. It takes precedence
+// over the causation ID in the context metadata, both for deduplicating the
+// command and for stamping the resulting events.
+type CausationIDCarrier interface {
+	CausationID() string
+}
+
+// Validatable is implemented by commands that can check themselves for
+// structural errors, independently of the state of the aggregate. Commands
+// failing validation are rejected before the aggregate root sees them.
+type Validatable interface {
+	Validate() error
+}
+
+func commandCausationID(ctx context.Context, cmd Command) string {
+	if carrier, ok := cmd.(CausationIDCarrier); ok {
+		if cid := carrier.CausationID(); cid != "" {
+			return cid
+		}
+	}
+	return eventstore.MetadataFromContext(ctx).CausationID()
+}
+
+func contextWithCommand(ctx context.Context, cmd Command) context.Context {
+	carrier, ok := cmd.(CausationIDCarrier)
+	if !ok || carrier.CausationID() == "" {
+		return ctx
+	}
+
+	return eventstore.WithCausationID(ctx, carrier.CausationID())
+}
@@ -0,0 +1,24 @@
+package eventsource
+
+import (
+	"context"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// CommandAuditor records every command processed by a repository, whether
+// it was accepted or rejected. An error returned by AuditCommand fails the
+// operation, so that no state change is saved without being audited.
+type CommandAuditor interface {
+	AuditCommand(ctx context.Context, record CommandAuditRecord) error
+}
+
+type CommandAuditRecord struct {
+	AggregateID  string
+	Command      Command
+	Metadata     eventstore.Metadata
+	Timestamp    time.Time
+	StateChanges StateChanges
+	Err          error
+}
@@ -0,0 +1,78 @@
+package eventsource
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// CommandHandler handles a command addressed to the aggregate with the
+// given ID.
+type CommandHandler = func(
+	ctx context.Context, aggregateID string, cmd Command,
+) error
+
+// CommandMiddleware wraps a handler, e.g. to log, measure or validate the
+// commands passing through it.
+type CommandMiddleware = func(next CommandHandler) CommandHandler
+
+// CommandBus routes commands to the handlers registered for their types.
+type CommandBus struct {
+	mu         sync.RWMutex
+	handlers   map[reflect.Type]CommandHandler
+	middleware []CommandMiddleware
+}
+
+// NewCommandBus returns a bus wrapping every dispatch in middleware, the
+// first one being the outermost.
+func NewCommandBus(middleware ...CommandMiddleware) *CommandBus {
+	return &CommandBus{
+		handlers:   make(map[reflect.Type]CommandHandler),
+		middleware: middleware,
+	}
+}
+
+// Register routes commands of the type of cmd to handler, replacing the
+// handler registered for that type before, if any.
+func (b *CommandBus) Register(cmd Command, handler CommandHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[reflect.TypeOf(cmd)] = handler
+}
+
+// RegisterCommandHandler is a typed Register.
+func RegisterCommandHandler[C Command](
+	bus *CommandBus,
+	handler func(ctx context.Context, aggregateID string, cmd C) error,
+) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.handlers[reflect.TypeFor[C]()] = func(
+		ctx context.Context, aggregateID string, cmd Command,
+	) error {
+		return handler(ctx, aggregateID, cmd.(C))
+	}
+}
+
+// Dispatch passes cmd to the handler registered for its type through the
+// middleware. It fails with ErrCommandUnknown if there is no such handler.
+func (b *CommandBus) Dispatch(
+	ctx context.Context, aggregateID string, cmd Command,
+) error {
+	b.mu.RLock()
+	handler, ok := b.handlers[reflect.TypeOf(cmd)]
+	b.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrCommandUnknown, cmd)
+	}
+
+	for i := len(b.middleware) - 1; i >= 0; i-- {
+		handler = b.middleware[i](handler)
+	}
+
+	return handler(ctx, aggregateID, cmd)
+}
@@ -0,0 +1,251 @@
+package eventsource
+
+import (
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+type config struct {
+	logger            Logger
+	codec             Codec
+	schemaVersion     func(StateChange) int
+	commandAuditor    CommandAuditor
+	snapshotStore     eventstore.SnapshotStore
+	snapshotFrequency int
+	strictSnapshots   bool
+	upcaster          Upcaster
+	retryAttempts     int
+	retryBackoff      func(attempt int) time.Duration
+	softDelete        bool
+	encryption        EncryptionProvider
+	metrics           eventstore.MetricsCollector
+	tracer            eventstore.Tracer
+	requiredMetadata  []string
+	reservedOverride  bool
+	skipUnreadable    bool
+	clock             Clock
+	idGenerator       IDGenerator
+	postCommitHook    PostCommitHook
+	checkedChanges    []StateChange
+}
+
+func newConfig(opts ...option) config {
+	cfg := config{
+		logger:        nopLogger{},
+		codec:         ProtoCodec{},
+		schemaVersion: defaultSchemaVersion,
+		retryAttempts: 2,
+		metrics:       eventstore.NopMetricsCollector{},
+		tracer:        eventstore.NopTracer{},
+		clock:         SystemClock{},
+		idGenerator:   UUIDGenerator{},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+type option func(*config)
+
+// WithLogger makes the repository log saves, conflicts and failures to save
+// snapshots to logger. Use NewSlogLogger to log to a *slog.Logger.
+func WithLogger(logger Logger) option {
+	return func(cfg *config) {
+		cfg.logger = logger
+	}
+}
+
+// WithCodec overrides how state changes are serialized into event data. It
+// must be able to decode the events already stored.
+func WithCodec(codec Codec) option {
+	return func(cfg *config) {
+		cfg.codec = codec
+	}
+}
+
+// WithEventTypeRegistry makes the repository use a ProtoCodec resolving event
+// types against types instead of the global protobuf registry.
+func WithEventTypeRegistry(types *EventTypeRegistry) option {
+	return func(cfg *config) {
+		cfg.codec = ProtoCodec{Types: types}
+	}
+}
+
+// WithSchemaVersionFunc overrides how the schema version stamped on each
+// saved event is determined. By default state changes implementing
+// SchemaVersion() int are asked, and all others are assumed to be version 1.
+func WithSchemaVersionFunc(f func(StateChange) int) option {
+	return func(cfg *config) {
+		cfg.schemaVersion = f
+	}
+}
+
+// WithCommandAuditor makes the repository report every command it processes
+// to auditor, including rejected ones.
+func WithCommandAuditor(auditor CommandAuditor) option {
+	return func(cfg *config) {
+		cfg.commandAuditor = auditor
+	}
+}
+
+// WithSnapshots makes the repository snapshot roots implementing Snapshot
+// and RestoreSnapshot into store whenever a save crosses a multiple of
+// frequency events, and load from the latest snapshot. Failing to save a
+// snapshot does not fail the save, and failing to get the latest snapshot
+// makes loading replay all events instead, unless WithStrictSnapshots;
+// either is only logged. Commands whose events precede the snapshot are not
+// deduplicated by causation ID on roots loaded from it.
+func WithSnapshots(store eventstore.SnapshotStore, frequency int) option {
+	return func(cfg *config) {
+		cfg.snapshotStore = store
+		cfg.snapshotFrequency = max(frequency, 1)
+	}
+}
+
+// WithSnapshotEveryCommit is like WithSnapshots, but snapshots after every
+// save, so that loading never replays events. Every save then writes a
+// snapshot besides the events, which suits aggregates read much more often
+// than they are written; otherwise WithSnapshots with a frequency bounding
+// the events replayed on load writes far less.
+func WithSnapshotEveryCommit(store eventstore.SnapshotStore) option {
+	return WithSnapshots(store, 1)
+}
+
+// WithStrictSnapshots makes loading fail when the snapshot store fails to
+// get the latest snapshot, rather than replay all events.
+func WithStrictSnapshots() option {
+	return func(cfg *config) {
+		cfg.strictSnapshots = true
+	}
+}
+
+// WithUpcaster makes the repository pass the data of every loaded event
+// through upcaster before applying it, along with the schema version of the
+// event if upcaster is a VersionedUpcaster.
+func WithUpcaster(upcaster Upcaster) option {
+	return func(cfg *config) {
+		cfg.upcaster = upcaster
+	}
+}
+
+// WithConflictRetry makes Update attempt the command up to maxAttempts times
+// when saving fails with eventstore.ErrConcurrentUpdate, each time against
+// freshly loaded state, waiting backoff(attempt) in between unless backoff
+// is nil. By default Update makes 2 attempts without waiting.
+func WithConflictRetry(
+	maxAttempts int, backoff func(attempt int) time.Duration,
+) option {
+	return func(cfg *config) {
+		cfg.retryAttempts = max(maxAttempts, 1)
+		cfg.retryBackoff = backoff
+	}
+}
+
+// WithSoftDelete makes Delete append a tombstone event instead of deleting
+// the events. Aggregates ending with a tombstone load as if they did not
+// exist, while their events remain listable in the event store, e.g. for
+// audits. Their IDs cannot be reused.
+func WithSoftDelete() option {
+	return func(cfg *config) {
+		cfg.softDelete = true
+	}
+}
+
+// WithEncryption makes the repository encrypt the fields named by state
+// changes implementing EncryptedFields() []string before saving them, and
+// decrypt them on load. Once the key of an aggregate is shredded, it still
+// loads, but with those fields blanked. Snapshots are not encrypted, so they
+// have to be discarded along with the key.
+func WithEncryption(provider EncryptionProvider) option {
+	return func(cfg *config) {
+		cfg.encryption = provider
+	}
+}
+
+// WithMetricsCollector makes the repository report its loads, saves and
+// concurrent update conflicts to collector, labeled by the name of the
+// aggregate root type.
+func WithMetricsCollector(collector eventstore.MetricsCollector) option {
+	return func(cfg *config) {
+		cfg.metrics = collector
+	}
+}
+
+// WithTracer makes the repository trace its operations with tracer and
+// inject the trace context into the metadata of saved events.
+func WithTracer(tracer eventstore.Tracer) option {
+	return func(cfg *config) {
+		cfg.tracer = tracer
+	}
+}
+
+// WithClock makes the repository stamp events and command audit records
+// with the time told by clock instead of the system clock.
+func WithClock(clock Clock) option {
+	return func(cfg *config) {
+		cfg.clock = clock
+	}
+}
+
+// WithIDGenerator makes the repository generate the IDs of events, and of
+// aggregates created without one, with generator instead of as random UUIDs.
+func WithIDGenerator(generator IDGenerator) option {
+	return func(cfg *config) {
+		cfg.idGenerator = generator
+	}
+}
+
+// WithPostCommitHook makes the repository call hook after every successful
+// save, including those of a UnitOfWork, but never after a failed one. The
+// events are already committed by then, so an error returned by the hook is
+// only logged, and the hook is called at most once: events are missed if the
+// process stops in between, which makes it suitable for in-process concerns
+// like invalidating caches, while subscriptions suit anything that must see
+// every event.
+func WithPostCommitHook(hook PostCommitHook) option {
+	return func(cfg *config) {
+		cfg.postCommitHook = hook
+	}
+}
+
+// WithRequiredMetadata makes the repository refuse to save events unless
+// the context metadata has all of the keys and passes Metadata.Validate.
+func WithRequiredMetadata(keys ...string) option {
+	return func(cfg *config) {
+		cfg.requiredMetadata = keys
+	}
+}
+
+// WithSkipUnreadableEvents makes loading skip, logging a warning, events
+// the codec cannot unmarshal, e.g. of types dropped from the binary, rather
+// than fail. Skipped events still count towards the version of the
+// aggregate, so that it can be saved.
+func WithSkipUnreadableEvents() option {
+	return func(cfg *config) {
+		cfg.skipUnreadable = true
+	}
+}
+
+// WithReservedMetadataOverride lets saves go through with causation and
+// correlation IDs set in the context metadata by other means than the
+// dedicated helpers, which eventstore.ValidateReservedMetadata otherwise
+// rejects.
+func WithReservedMetadataOverride() option {
+	return func(cfg *config) {
+		cfg.reservedOverride = true
+	}
+}
+
+// WithStateChangeCheck makes NewAggregateRepository apply each of the
+// prototypes to a fresh root, and panic if ApplyStateChange fails with
+// ErrUnknownStateChange for any of them, so that a state change produced by
+// ProcessCommand but missing from ApplyStateChange is caught at startup.
+// Other errors and panics are ignored, as state changes may not apply to a
+// fresh root. It is meant for development and tests.
+func WithStateChangeCheck(prototypes ...StateChange) option {
+	return func(cfg *config) {
+		cfg.checkedChanges = prototypes
+	}
+}
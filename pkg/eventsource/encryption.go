@@ -0,0 +1,216 @@
+package eventsource
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/rnovatorov/go-eventsource/pkg/keystore"
+)
+
+// EncryptionProvider encrypts personal data with keys specific to the
+// aggregate it belongs to. Decrypt must fail with keystore.ErrKeyShredded
+// once the key of the aggregate has been shredded.
+type EncryptionProvider interface {
+	Encrypt(
+		ctx context.Context, aggregateID string, plaintext []byte,
+	) ([]byte, error)
+	Decrypt(
+		ctx context.Context, aggregateID string, ciphertext []byte,
+	) ([]byte, error)
+}
+
+// encryptedFielder is implemented by state changes carrying personal data.
+// EncryptedFields names the string and []byte fields of the struct the
+// state change points to that are to be encrypted.
+type encryptedFielder interface {
+	EncryptedFields() []string
+}
+
+// AESEncryptionProvider encrypts with AES-GCM using 256-bit keys kept in a
+// key store.
+type AESEncryptionProvider struct {
+	keys keystore.Interface
+}
+
+func NewAESEncryptionProvider(keys keystore.Interface) *AESEncryptionProvider {
+	return &AESEncryptionProvider{keys: keys}
+}
+
+func (p *AESEncryptionProvider) Encrypt(
+	ctx context.Context, aggregateID string, plaintext []byte,
+) ([]byte, error) {
+	key, err := p.keys.GetOrCreateKey(ctx, aggregateID)
+	if err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, []byte(aggregateID)), nil
+}
+
+func (p *AESEncryptionProvider) Decrypt(
+	ctx context.Context, aggregateID string, ciphertext []byte,
+) ([]byte, error) {
+	key, err := p.keys.GetKey(ctx, aggregateID)
+	if err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonceSize := gcm.NonceSize()
+
+	return gcm.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:],
+		[]byte(aggregateID))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("new cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptFields replaces the encrypted fields of stateChange with their
+// ciphertexts, strings being base64 encoded. The returned function restores
+// the plaintexts.
+func encryptFields(
+	ctx context.Context, provider EncryptionProvider, aggregateID string,
+	stateChange StateChange,
+) (restore func(), err error) {
+	fields, err := encryptedFields(stateChange)
+	if err != nil || len(fields) == 0 {
+		return func() {}, err
+	}
+
+	originals := make([]reflect.Value, 0, len(fields))
+	restore = func() {
+		for i, original := range originals {
+			fields[i].Set(original)
+		}
+	}
+
+	for _, field := range fields {
+		original := reflect.New(field.Type()).Elem()
+		original.Set(field)
+		originals = append(originals, original)
+
+		var plaintext []byte
+		if field.Kind() == reflect.String {
+			plaintext = []byte(field.String())
+		} else {
+			plaintext = field.Bytes()
+		}
+
+		ciphertext, err := provider.Encrypt(ctx, aggregateID, plaintext)
+		if err != nil {
+			restore()
+			return nil, fmt.Errorf("encrypt: %w", err)
+		}
+
+		if field.Kind() == reflect.String {
+			field.SetString(base64.StdEncoding.EncodeToString(ciphertext))
+		} else {
+			field.SetBytes(ciphertext)
+		}
+	}
+
+	return restore, nil
+}
+
+// decryptFields decrypts the encrypted fields of stateChange in place. If
+// the key of the aggregate has been shredded, the fields are blanked and
+// shredded is true.
+func decryptFields(
+	ctx context.Context, provider EncryptionProvider, aggregateID string,
+	stateChange StateChange,
+) (shredded bool, err error) {
+	fields, err := encryptedFields(stateChange)
+	if err != nil {
+		return false, err
+	}
+
+	for _, field := range fields {
+		var ciphertext []byte
+		if field.Kind() == reflect.String {
+			ciphertext, err = base64.StdEncoding.DecodeString(field.String())
+			if err != nil {
+				return false, fmt.Errorf("decode ciphertext: %w", err)
+			}
+		} else {
+			ciphertext = field.Bytes()
+		}
+
+		plaintext, err := provider.Decrypt(ctx, aggregateID, ciphertext)
+		if errors.Is(err, keystore.ErrKeyShredded) {
+			shredded = true
+			field.SetZero()
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("decrypt: %w", err)
+		}
+
+		if field.Kind() == reflect.String {
+			field.SetString(string(plaintext))
+		} else {
+			field.SetBytes(plaintext)
+		}
+	}
+
+	return shredded, nil
+}
+
+func encryptedFields(stateChange StateChange) ([]reflect.Value, error) {
+	fielder, ok := stateChange.(encryptedFielder)
+	if !ok {
+		return nil, nil
+	}
+
+	v := reflect.ValueOf(stateChange)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T is not a pointer to struct", stateChange)
+	}
+	v = v.Elem()
+
+	names := fielder.EncryptedFields()
+	fields := make([]reflect.Value, 0, len(names))
+	for _, name := range names {
+		field := v.FieldByName(name)
+		switch {
+		case !field.IsValid():
+			return nil, fmt.Errorf("%T has no field %s", stateChange, name)
+		case field.Kind() == reflect.String,
+			field.Kind() == reflect.Slice &&
+				field.Type().Elem().Kind() == reflect.Uint8:
+			fields = append(fields, field)
+		default:
+			return nil, fmt.Errorf("%T field %s is neither string nor bytes",
+				stateChange, name)
+		}
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,63 @@
+package eventsource
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/rnovatorov/go-eventsource/pkg/keystore/keystoreinmemory"
+)
+
+func TestLoadBlanksShreddedFields(t *testing.T) {
+	ctx := context.Background()
+	types := NewEventTypeRegistry()
+	types.Register("private.Renamed", &privateRenamed{})
+	codec := NewDispatchingCodec(ProtoCodec{})
+	codec.Register(NewJSONCodec(types), &privateRenamed{})
+	keys := keystoreinmemory.New()
+	store, repo := newCounterRepository(t, WithCodec(codec),
+		WithEncryption(NewAESEncryptionProvider(keys)))
+
+	if _, err := repo.Create(ctx, "c", counterAdd{N: 2}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := repo.Update(
+		ctx, "c", counterRenamePrivately{Name: "alice"},
+	); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	events, err := store.ListEvents(ctx, "c")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if bytes.Contains(events[1].Data, []byte("alice")) {
+		t.Fatalf("stored data is not encrypted: %s", events[1].Data)
+	}
+
+	agg, err := repo.Load(ctx, "c")
+	if err != nil {
+		t.Fatalf("load before shredding: %v", err)
+	}
+	if agg.Shredded() || agg.Root().name != "alice" {
+		t.Fatalf("before shredding: got shredded %t and name %q",
+			agg.Shredded(), agg.Root().name)
+	}
+
+	if err := keys.ShredKey(ctx, "c"); err != nil {
+		t.Fatalf("shred key: %v", err)
+	}
+
+	agg, err = repo.Load(ctx, "c")
+	if err != nil {
+		t.Fatalf("load after shredding: %v", err)
+	}
+	if !agg.Shredded() || agg.Root().name != "" {
+		t.Fatalf("after shredding: got shredded %t and name %q",
+			agg.Shredded(), agg.Root().name)
+	}
+	if agg.Version() != 2 || agg.Root().total != 2 {
+		t.Fatalf("after shredding: got version %d and total %d, want 2 and 2",
+			agg.Version(), agg.Root().total)
+	}
+}
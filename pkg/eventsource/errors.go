@@ -5,6 +5,16 @@ import "errors"
 var (
 	ErrAggregateAlreadyExists  = errors.New("aggregate already exists")
 	ErrAggregateDoesNotExist   = errors.New("aggregate does not exist")
+	ErrAggregateDeleted        = errors.New("aggregate deleted")
+	ErrEmptyAggregateID        = errors.New("empty aggregate ID")
 	ErrCommandUnknown          = errors.New("command unknown")
+	ErrUnknownStateChange      = errors.New("unknown state change")
 	ErrCommandAlreadyProcessed = errors.New("command already processed")
+	ErrCommandInvalid          = errors.New("command invalid")
+	ErrEventVersionMisaligned  = errors.New("event version misaligned")
+	ErrSnapshotMisaligned      = errors.New("snapshot misaligned")
+	ErrVersionNotReached       = errors.New("version not reached")
+	ErrUnknownEventType        = errors.New("unknown event type")
+	ErrAggregateTypeMismatch   = errors.New("aggregate type mismatch")
+	ErrAggregatePoisoned       = errors.New("aggregate poisoned")
 )
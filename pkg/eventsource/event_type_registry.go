@@ -0,0 +1,48 @@
+package eventsource
+
+import (
+	"reflect"
+	"sync"
+)
+
+// EventTypeRegistry maps event type names to state change types. It lets
+// codecs resolve event types without relying on global registries.
+type EventTypeRegistry struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+}
+
+func NewEventTypeRegistry() *EventTypeRegistry {
+	return &EventTypeRegistry{
+		types: make(map[string]reflect.Type),
+		names: make(map[reflect.Type]string),
+	}
+}
+
+// Register associates name with the type of prototype. Protobuf messages
+// used with ProtoCodec are to be registered under their full name.
+func (r *EventTypeRegistry) Register(name string, prototype StateChange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := reflect.TypeOf(prototype)
+	r.types[name] = t
+	r.names[t] = name
+}
+
+func (r *EventTypeRegistry) lookupType(name string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.types[name]
+	return t, ok
+}
+
+func (r *EventTypeRegistry) lookupName(t reflect.Type) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name, ok := r.names[t]
+	return name, ok
+}
@@ -0,0 +1,9 @@
+package eventsourcetest
+
+import "github.com/rnovatorov/go-eventsource/pkg/eventsource"
+
+type aggregateRoot[T any] interface {
+	*T
+	ProcessCommand(eventsource.Command) (eventsource.StateChanges, error)
+	ApplyStateChange(eventsource.StateChange) error
+}
@@ -0,0 +1,239 @@
+package eventsourcetest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource/eventsourcetest"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoreinmemory"
+	"github.com/rnovatorov/go-eventsource/pkg/processmanager"
+	"github.com/rnovatorov/go-eventsource/pkg/processmanager/processmanagerinmemory"
+	"github.com/rnovatorov/go-eventsource/pkg/projection/projectioninmemory"
+	"github.com/rnovatorov/go-eventsource/pkg/scheduler"
+	"github.com/rnovatorov/go-eventsource/pkg/scheduler/schedulerinmemory"
+)
+
+type tally struct {
+	total int64
+}
+
+type tallyAdd struct {
+	N int64
+}
+
+func (t *tally) ProcessCommand(
+	command eventsource.Command,
+) (eventsource.StateChanges, error) {
+	switch cmd := command.(type) {
+	case tallyAdd:
+		return eventsource.StateChanges{wrapperspb.Int64(cmd.N)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", eventsource.ErrCommandUnknown, cmd)
+	}
+}
+
+func (t *tally) ApplyStateChange(stateChange eventsource.StateChange) error {
+	switch sc := stateChange.(type) {
+	case *wrapperspb.Int64Value:
+		t.total += sc.Value
+	default:
+		return fmt.Errorf("%w: %T", eventsource.ErrUnknownStateChange, sc)
+	}
+	return nil
+}
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// clocked is a repository of tallies and a command bus routing to it, with
+// events stamped by a fake clock.
+type clocked struct {
+	clock      *eventsourcetest.FakeClock
+	eventStore *eventstoreinmemory.Store
+	repository *eventsource.AggregateRepository[tally, *tally]
+	bus        *eventsource.CommandBus
+}
+
+func newClocked(t testing.TB) *clocked {
+	t.Helper()
+
+	c := &clocked{
+		clock:      eventsourcetest.NewFakeClock(epoch),
+		eventStore: eventstoreinmemory.New(),
+		bus:        eventsource.NewCommandBus(),
+	}
+	c.repository = eventsource.NewAggregateRepository[tally](
+		c.eventStore, eventsource.WithClock(c.clock))
+	c.bus.Register(tallyAdd{}, c.repository.UpdateCommandHandler())
+
+	if _, err := c.repository.Create(
+		context.Background(), "t", tallyAdd{N: 0},
+	); err != nil {
+		t.Fatalf("create tally: %v", err)
+	}
+
+	return c
+}
+
+// timestamps returns the timestamps of the events of the tally.
+func (c *clocked) timestamps(t testing.TB) []time.Time {
+	t.Helper()
+
+	events, err := c.eventStore.ListEvents(context.Background(), "t")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	timestamps := make([]time.Time, 0, len(events))
+	for _, event := range events {
+		timestamps = append(timestamps, event.Timestamp)
+	}
+	return timestamps
+}
+
+// runInBackground runs run until the test ends.
+func runInBackground(t testing.TB, run func(context.Context) error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if err := run(ctx); err != nil {
+			t.Errorf("run: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+}
+
+func eventually(t testing.TB, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFakeClockStampsEvents(t *testing.T) {
+	c := newClocked(t)
+
+	c.clock.Advance(time.Hour)
+	if _, err := c.repository.Update(
+		context.Background(), "t", tallyAdd{N: 1},
+	); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	want := fmt.Sprint([]time.Time{epoch, epoch.Add(time.Hour)})
+	if got := fmt.Sprint(c.timestamps(t)); got != want {
+		t.Fatalf("timestamps: got %s, want %s", got, want)
+	}
+}
+
+func TestFakeClockFiresSchedulerTimer(t *testing.T) {
+	c := newClocked(t)
+
+	s := scheduler.NewScheduler(schedulerinmemory.NewStore(), c.bus,
+		scheduler.WithClock(c.clock),
+		scheduler.WithPollInterval(time.Millisecond))
+	s.Register(tallyAdd{})
+
+	if _, err := s.Schedule(
+		context.Background(), epoch.Add(24*time.Hour), "t", tallyAdd{N: 1},
+	); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	runInBackground(t, s.Run)
+
+	// A day passes in an instant, the timer firing at its due time.
+	c.clock.Set(epoch.Add(24 * time.Hour))
+	eventually(t, "timer to fire", func() bool {
+		return len(c.timestamps(t)) == 2
+	})
+
+	if got := c.timestamps(t)[1]; !got.Equal(epoch.Add(24 * time.Hour)) {
+		t.Fatalf("timestamp of fired command: got %s, want a day later", got)
+	}
+}
+
+// reminder adds 1 to the tally an hour after it was created.
+type reminder struct{}
+
+func (reminder) Name() string {
+	return "reminder"
+}
+
+func (reminder) ProcessID(event *eventstore.Event) string {
+	if event.AggregateVersion != 1 {
+		return ""
+	}
+	return event.AggregateID
+}
+
+func (reminder) Handle(
+	ctx context.Context, process *processmanager.Process,
+	event *eventstore.Event,
+) error {
+	process.ScheduleAfter("remind", time.Hour)
+	return nil
+}
+
+func (reminder) HandleTimeout(
+	ctx context.Context, process *processmanager.Process,
+	timeout processmanager.Timeout,
+) error {
+	if err := process.Dispatch(ctx, process.ID, tallyAdd{N: 1}); err != nil {
+		return err
+	}
+	process.Complete()
+	return nil
+}
+
+func TestFakeClockFiresProcessTimeout(t *testing.T) {
+	c := newClocked(t)
+	processes := processmanagerinmemory.NewStore()
+
+	coordinator := processmanager.NewCoordinator(c.eventStore, c.bus,
+		processes, projectioninmemory.NewCheckpointStore(),
+		processmanager.WithClock(c.clock),
+		processmanager.WithTimeoutInterval(time.Millisecond))
+	runInBackground(t, func(ctx context.Context) error {
+		return coordinator.Run(ctx, reminder{})
+	})
+
+	eventually(t, "timeout to be scheduled", func() bool {
+		process, err := processes.LoadProcess(
+			context.Background(), reminder{}.Name(), "t")
+		if err != nil {
+			t.Fatalf("load process: %v", err)
+		}
+		return process != nil && len(process.Timeouts) == 1
+	})
+
+	c.clock.Advance(time.Hour - time.Nanosecond)
+	time.Sleep(20 * time.Millisecond)
+	if n := len(c.timestamps(t)); n != 1 {
+		t.Fatalf("got %d events before the timeout, want 1", n)
+	}
+
+	c.clock.Advance(time.Nanosecond)
+	eventually(t, "timeout to fire", func() bool {
+		return len(c.timestamps(t)) == 2
+	})
+
+	if got := c.timestamps(t)[1]; !got.Equal(epoch.Add(time.Hour)) {
+		t.Fatalf("timestamp of timeout command: got %s, want an hour later",
+			got)
+	}
+}
@@ -0,0 +1,34 @@
+package eventsourcetest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+)
+
+var _ eventsource.IDGenerator = (*SequentialIDGenerator)(nil)
+
+// SequentialIDGenerator generates the IDs prefix-1, prefix-2 and so on.
+type SequentialIDGenerator struct {
+	mu     sync.Mutex
+	prefix string
+	next   int
+}
+
+func NewSequentialIDGenerator(prefix string) *SequentialIDGenerator {
+	return &SequentialIDGenerator{
+		prefix: prefix,
+		next:   1,
+	}
+}
+
+func (g *SequentialIDGenerator) NewID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := fmt.Sprintf("%s-%d", g.prefix, g.next)
+	g.next++
+
+	return id, nil
+}
@@ -0,0 +1,58 @@
+package eventsourcetest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+)
+
+// AssertRoundTrip creates an aggregate with the first command, processes
+// the remaining ones on the same instance saving after each, then reloads
+// the aggregate from the repository and checks that the reloaded root and
+// version equal the in-memory ones. A mismatch usually means a state change
+// is handled differently when processed and when applied.
+func AssertRoundTrip[T any, R aggregateRoot[T]](
+	t testing.TB, repo *eventsource.AggregateRepository[T, R], id string,
+	cmds ...eventsource.Command,
+) *eventsource.Aggregate[T, R] {
+	t.Helper()
+
+	if len(cmds) == 0 {
+		t.Fatal("round trip: no commands")
+	}
+
+	ctx := context.Background()
+
+	agg, err := repo.Create(ctx, id, cmds[0])
+	if err != nil {
+		t.Fatalf("round trip: create: %v", err)
+	}
+
+	for i, cmd := range cmds[1:] {
+		if err := agg.ProcessCommand(ctx, cmd); err != nil {
+			t.Fatalf("round trip: process command %d (%T): %v", i+1, cmd, err)
+		}
+		if err := repo.Save(ctx, agg); err != nil {
+			t.Fatalf("round trip: save after command %d (%T): %v", i+1, cmd, err)
+		}
+	}
+
+	reloaded, err := repo.Load(ctx, agg.ID())
+	if err != nil {
+		t.Fatalf("round trip: load: %v", err)
+	}
+
+	if reloaded.Version() != agg.Version() {
+		t.Fatalf("round trip: version: reloaded %d, in memory %d",
+			reloaded.Version(), agg.Version())
+	}
+
+	if !reflect.DeepEqual(reloaded.Root(), agg.Root()) {
+		t.Fatalf("round trip: root:\nreloaded:  %+v\nin memory: %+v",
+			reloaded.Root(), agg.Root())
+	}
+
+	return reloaded
+}
@@ -0,0 +1,197 @@
+package eventsourcetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoreinmemory"
+)
+
+const scenarioAggregateID = "scenario"
+
+// Scenario tests an aggregate in the Given/When/Then style:
+//
+//	eventsourcetest.Given[model.Book](t, &accountingpb.BookCreated{}).
+//		When(model.BookClose{}).
+//		Then(&accountingpb.BookClosed{})
+//
+// The given state changes are saved to an in-memory event store, the command
+// is processed by an aggregate loaded from it with an AggregateRepository,
+// and the state changes it produces are compared with the expected ones,
+// protobuf messages with proto.Equal.
+type Scenario[T any, R aggregateRoot[T]] struct {
+	t     testing.TB
+	given eventsource.StateChanges
+	when  eventsource.Command
+}
+
+func Given[T any, R aggregateRoot[T]](
+	t testing.TB, stateChanges ...eventsource.StateChange,
+) *Scenario[T, R] {
+	return &Scenario[T, R]{
+		t:     t,
+		given: stateChanges,
+	}
+}
+
+func (s *Scenario[T, R]) When(cmd eventsource.Command) *Scenario[T, R] {
+	s.when = cmd
+	return s
+}
+
+// Then checks that the command succeeds and produces exactly the expected
+// state changes.
+func (s *Scenario[T, R]) Then(expected ...eventsource.StateChange) {
+	s.t.Helper()
+
+	got, err := s.run()
+	if err != nil {
+		s.t.Fatalf("%T: unexpected error: %v", s.when, err)
+	}
+
+	if diff := diffStateChanges(got, expected); diff != "" {
+		s.t.Fatalf("%T: state changes differ:\n%s", s.when, diff)
+	}
+}
+
+// ThenError checks that the command fails with an error matching target
+// according to errors.Is.
+func (s *Scenario[T, R]) ThenError(target error) {
+	s.t.Helper()
+
+	got, err := s.run()
+	if err == nil {
+		s.t.Fatalf("%T: expected error %q, got state changes:\n%s",
+			s.when, target, formatStateChanges(got))
+	}
+
+	if !errors.Is(err, target) {
+		s.t.Fatalf("%T: expected error %q, got %q", s.when, target, err)
+	}
+}
+
+func (s *Scenario[T, R]) run() (eventsource.StateChanges, error) {
+	s.t.Helper()
+
+	if s.when == nil {
+		s.t.Fatal("scenario: no command, see When")
+	}
+
+	ctx := context.Background()
+	eventStore := eventstoreinmemory.New()
+	repo := eventsource.NewAggregateRepository[T, R](eventStore)
+
+	if len(s.given) > 0 {
+		events, err := newEvents(s.given)
+		if err != nil {
+			s.t.Fatalf("scenario: given: %v", err)
+		}
+		if err := eventStore.SaveEvents(
+			ctx, scenarioAggregateID, 0, events,
+		); err != nil {
+			s.t.Fatalf("scenario: given: save events: %v", err)
+		}
+	}
+
+	agg, err := repo.Load(ctx, scenarioAggregateID)
+	if err != nil {
+		s.t.Fatalf("scenario: load: %v", err)
+	}
+
+	if err := agg.ProcessCommand(ctx, s.when); err != nil {
+		return nil, err
+	}
+
+	stateChanges := agg.PendingStateChanges()
+
+	// Saving checks that the state changes can be encoded.
+	if err := repo.Save(ctx, agg); err != nil {
+		s.t.Fatalf("scenario: save: %v", err)
+	}
+
+	return stateChanges, nil
+}
+
+func newEvents(stateChanges eventsource.StateChanges) (eventstore.Events, error) {
+	events := make(eventstore.Events, 0, len(stateChanges))
+
+	for i, stateChange := range stateChanges {
+		data, typeURL, err := eventsource.ProtoCodec{}.Marshal(stateChange)
+		if err != nil {
+			return nil, fmt.Errorf("marshal state change %d (%T): %w",
+				i, stateChange, err)
+		}
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return nil, fmt.Errorf("generate event ID: %w", err)
+		}
+		events = append(events, &eventstore.Event{
+			ID:               id.String(),
+			AggregateID:      scenarioAggregateID,
+			AggregateVersion: i + 1,
+			Timestamp:        time.Now(),
+			Metadata:         eventstore.Metadata{},
+			Type:             typeURL,
+			Data:             data,
+		})
+	}
+
+	return events, nil
+}
+
+func diffStateChanges(got, expected eventsource.StateChanges) string {
+	var b strings.Builder
+
+	for i := range max(len(got), len(expected)) {
+		switch {
+		case i >= len(got):
+			fmt.Fprintf(&b, "  %d: missing\n     want: %s\n",
+				i, formatStateChange(expected[i]))
+		case i >= len(expected):
+			fmt.Fprintf(&b, "  %d: unexpected\n     got:  %s\n",
+				i, formatStateChange(got[i]))
+		case !equalStateChanges(got[i], expected[i]):
+			fmt.Fprintf(&b, "  %d: got:  %s\n     want: %s\n",
+				i, formatStateChange(got[i]), formatStateChange(expected[i]))
+		}
+	}
+
+	return b.String()
+}
+
+func equalStateChanges(x, y eventsource.StateChange) bool {
+	xm, xok := x.(proto.Message)
+	ym, yok := y.(proto.Message)
+	if xok && yok {
+		return proto.Equal(xm, ym)
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+func formatStateChanges(stateChanges eventsource.StateChanges) string {
+	var b strings.Builder
+	for i, stateChange := range stateChanges {
+		fmt.Fprintf(&b, "  %d: %s\n", i, formatStateChange(stateChange))
+	}
+	return b.String()
+}
+
+func formatStateChange(stateChange eventsource.StateChange) string {
+	if m, ok := stateChange.(proto.Message); ok {
+		if data, err := protojson.Marshal(m); err == nil {
+			return fmt.Sprintf("%T %s", stateChange, data)
+		}
+	}
+	return fmt.Sprintf("%T %+v", stateChange, stateChange)
+}
@@ -0,0 +1,20 @@
+package eventsource
+
+import "github.com/google/uuid"
+
+// IDGenerator generates the IDs of events, and of aggregates created without
+// one, e.g. sortable ULIDs, or predictable IDs in tests.
+type IDGenerator interface {
+	NewID() (string, error)
+}
+
+// UUIDGenerator generates random UUIDs.
+type UUIDGenerator struct{}
+
+func (UUIDGenerator) NewID() (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
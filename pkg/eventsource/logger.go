@@ -0,0 +1,67 @@
+package eventsource
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Logger receives structured log records as a message followed by
+// alternating keys and values. Callers check Enabled first, so that nothing
+// is allocated for disabled levels.
+type Logger interface {
+	Enabled(level LogLevel) bool
+	Debug(msg string, keyvals ...any)
+	Info(msg string, keyvals ...any)
+	Warn(msg string, keyvals ...any)
+}
+
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Enabled(LogLevel) bool { return false }
+func (nopLogger) Debug(string, ...any)  {}
+func (nopLogger) Info(string, ...any)   {}
+func (nopLogger) Warn(string, ...any)   {}
+
+// SlogLogger adapts a *slog.Logger.
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+func NewSlogLogger(logger *slog.Logger) SlogLogger {
+	return SlogLogger{logger: logger}
+}
+
+func (l SlogLogger) Enabled(level LogLevel) bool {
+	return l.logger.Enabled(context.Background(), slogLevel(level))
+}
+
+func (l SlogLogger) Debug(msg string, keyvals ...any) {
+	l.logger.Debug(msg, keyvals...)
+}
+
+func (l SlogLogger) Info(msg string, keyvals ...any) {
+	l.logger.Info(msg, keyvals...)
+}
+
+func (l SlogLogger) Warn(msg string, keyvals ...any) {
+	l.logger.Warn(msg, keyvals...)
+}
+
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogLevelDebug:
+		return slog.LevelDebug
+	case LogLevelInfo:
+		return slog.LevelInfo
+	default:
+		return slog.LevelWarn
+	}
+}
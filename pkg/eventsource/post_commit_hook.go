@@ -0,0 +1,13 @@
+package eventsource
+
+import (
+	"context"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// PostCommitHook is called with the events of an aggregate once they have
+// been saved.
+type PostCommitHook = func(
+	ctx context.Context, aggregateID string, events eventstore.Events,
+) error
@@ -0,0 +1,82 @@
+package eventsource
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// Repositories creates the repositories of an application on first use, one
+// per aggregate type, sharing an event store and options. Repositories are
+// keyed by aggregate type name, which is the name of the root type unless
+// the root has a TypeName method, and retrieved with Repository.
+type Repositories struct {
+	eventStore   eventstore.Interface
+	opts         []option
+	mu           sync.Mutex
+	repositories map[string]any
+}
+
+func NewRepositories(
+	eventStore eventstore.Interface, opts ...option,
+) *Repositories {
+	return &Repositories{
+		eventStore:   eventStore,
+		opts:         opts,
+		repositories: make(map[string]any),
+	}
+}
+
+// Repository returns the repository of the aggregates of type T, creating
+// it on first use with the options of the registry followed by opts, which
+// are ignored afterwards. It panics if another root type has been
+// registered under the same aggregate type name, as their events would
+// clash.
+func Repository[T any, R aggregateRoot[T]](
+	rs *Repositories, opts ...option,
+) *AggregateRepository[T, R] {
+	name := aggregateTypeName[T, R]()
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if r, ok := rs.repositories[name]; ok {
+		repository, ok := r.(*AggregateRepository[T, R])
+		if !ok {
+			panic(fmt.Sprintf(
+				"eventsource: aggregate type %s registered as %T, not %T",
+				name, r, repository))
+		}
+		return repository
+	}
+
+	repository := NewAggregateRepository[T, R](rs.eventStore,
+		slices.Concat(rs.opts, opts)...)
+	rs.repositories[name] = repository
+
+	return repository
+}
+
+// RegisterCreateCommands routes the commands of the types of cmds on bus to
+// the repository of the aggregates of type T, see CreateCommandHandler.
+func RegisterCreateCommands[T any, R aggregateRoot[T]](
+	bus *CommandBus, rs *Repositories, cmds ...Command,
+) {
+	handler := Repository[T, R](rs).CreateCommandHandler()
+	for _, cmd := range cmds {
+		bus.Register(cmd, handler)
+	}
+}
+
+// RegisterUpdateCommands routes the commands of the types of cmds on bus to
+// the repository of the aggregates of type T, see UpdateCommandHandler.
+func RegisterUpdateCommands[T any, R aggregateRoot[T]](
+	bus *CommandBus, rs *Repositories, cmds ...Command,
+) {
+	handler := Repository[T, R](rs).UpdateCommandHandler()
+	for _, cmd := range cmds {
+		bus.Register(cmd, handler)
+	}
+}
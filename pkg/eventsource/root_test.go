@@ -0,0 +1,158 @@
+package eventsource
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoreinmemory"
+)
+
+// counter is the aggregate root the tests of the package use. Adding is
+// recorded as an Int64Value and renaming as a StringValue.
+type counter struct {
+	total int64
+	name  string
+}
+
+type counterAdd struct {
+	N int64
+}
+
+func (cmd counterAdd) Validate() error {
+	if cmd.N == 0 {
+		return errZeroAmount
+	}
+	return nil
+}
+
+type counterRename struct {
+	Name string
+}
+
+// counterRenameLegacy renames the counter with a legacyRenamed, which is
+// meant to be encoded as JSON.
+type counterRenameLegacy struct {
+	Name string
+}
+
+type legacyRenamed struct {
+	Name string
+}
+
+// counterRenamePrivately renames the counter with a privateRenamed, whose
+// name is personal data.
+type counterRenamePrivately struct {
+	Name string
+}
+
+type privateRenamed struct {
+	Name string
+}
+
+func (*privateRenamed) EncryptedFields() []string {
+	return []string{"Name"}
+}
+
+// counterAddBroken adds N, then produces a state change the counter cannot
+// apply.
+type counterAddBroken struct {
+	N int64
+}
+
+// counterAddOnce carries its causation ID, so that it is processed once.
+type counterAddOnce struct {
+	N  int64
+	ID string
+}
+
+func (cmd counterAddOnce) CausationID() string {
+	return cmd.ID
+}
+
+var (
+	errZeroAmount  = errors.New("zero amount")
+	errNegativeSum = errors.New("negative sum")
+	errSameName    = errors.New("same name")
+)
+
+func (c *counter) ProcessCommand(command Command) (StateChanges, error) {
+	switch cmd := command.(type) {
+	case counterAdd:
+		if c.total+cmd.N < 0 {
+			return nil, errNegativeSum
+		}
+		return StateChanges{wrapperspb.Int64(cmd.N)}, nil
+	case counterAddOnce:
+		return StateChanges{wrapperspb.Int64(cmd.N)}, nil
+	case counterAddBroken:
+		return StateChanges{wrapperspb.Int64(cmd.N), wrapperspb.Bool(true)}, nil
+	case counterRename:
+		if cmd.Name == c.name {
+			return nil, errSameName
+		}
+		return StateChanges{wrapperspb.String(cmd.Name)}, nil
+	case counterRenameLegacy:
+		return StateChanges{legacyRenamed{Name: cmd.Name}}, nil
+	case counterRenamePrivately:
+		return StateChanges{&privateRenamed{Name: cmd.Name}}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrCommandUnknown, cmd)
+	}
+}
+
+func (c *counter) ApplyStateChange(stateChange StateChange) error {
+	switch sc := stateChange.(type) {
+	case *wrapperspb.Int64Value:
+		c.total += sc.Value
+	case *wrapperspb.StringValue:
+		c.name = sc.Value
+	case legacyRenamed:
+		c.name = sc.Name
+	case *privateRenamed:
+		c.name = sc.Name
+	default:
+		return fmt.Errorf("%w: %T", ErrUnknownStateChange, sc)
+	}
+	return nil
+}
+
+// snapshottedCounter is a counter that can be snapshotted. Its snapshots
+// only keep the total.
+type snapshottedCounter struct {
+	counter
+}
+
+func (c *snapshottedCounter) Snapshot() proto.Message {
+	return wrapperspb.Int64(c.total)
+}
+
+func (c *snapshottedCounter) RestoreSnapshot(snapshot proto.Message) {
+	c.total = snapshot.(*wrapperspb.Int64Value).Value
+}
+
+func newCounterRepository(
+	t testing.TB, opts ...option,
+) (*eventstoreinmemory.Store, *AggregateRepository[counter, *counter]) {
+	t.Helper()
+
+	store := eventstoreinmemory.New()
+	return store, NewAggregateRepository[counter](store, opts...)
+}
+
+// recordingLogger keeps the messages logged at the warning level.
+type recordingLogger struct {
+	nopLogger
+	warnings []string
+}
+
+func (l *recordingLogger) Enabled(level LogLevel) bool {
+	return level == LogLevelWarn
+}
+
+func (l *recordingLogger) Warn(msg string, keyvals ...any) {
+	l.warnings = append(l.warnings, msg)
+}
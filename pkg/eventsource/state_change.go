@@ -1,7 +1,18 @@
 package eventsource
 
-import "google.golang.org/protobuf/proto"
-
-type StateChange proto.Message
+// StateChange is any value the configured Codec can serialize. With the
+// default ProtoCodec state changes must be protobuf messages.
+type StateChange any
 
 type StateChanges []StateChange
+
+type schemaVersioner interface {
+	SchemaVersion() int
+}
+
+func defaultSchemaVersion(stateChange StateChange) int {
+	if v, ok := stateChange.(schemaVersioner); ok {
+		return v.SchemaVersion()
+	}
+	return 1
+}
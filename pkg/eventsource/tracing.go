@@ -0,0 +1,28 @@
+package eventsource
+
+import (
+	"context"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+func (r *AggregateRepository[T, R]) startSpan(
+	ctx context.Context, name string, id string,
+) (context.Context, eventstore.Span) {
+	ctx, span := r.config.tracer.Start(ctx, name)
+	span.SetAttribute("eventsource.aggregate_id", id)
+	span.SetAttribute("eventsource.aggregate_type", r.aggregateType)
+	return ctx, span
+}
+
+func endSpan[T any, R aggregateRoot[T]](
+	span eventstore.Span, agg *Aggregate[T, R], err error,
+) {
+	if agg != nil {
+		span.SetAttribute("eventsource.aggregate_version", agg.Version())
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,87 @@
+package eventsource
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// UnitOfWork saves the state changes of several aggregates, possibly of
+// different types, atomically. Aggregates are added to it with
+// AggregateRepository.Track. Needing it is often a sign that the aggregate
+// boundaries are drawn wrong: a transaction spanning aggregates couples
+// them, and spanning bounded contexts couples those too.
+type UnitOfWork struct {
+	eventStore eventstore.Interface
+	ids        []string
+	aggregates map[string]trackedAggregate
+}
+
+type trackedAggregate struct {
+	prepare func(context.Context) (eventstore.AggregateEvents, error)
+	saved   func(context.Context, eventstore.AggregateEvents)
+}
+
+func NewUnitOfWork(eventStore eventstore.Interface) *UnitOfWork {
+	return &UnitOfWork{
+		eventStore: eventStore,
+		aggregates: make(map[string]trackedAggregate),
+	}
+}
+
+func (u *UnitOfWork) track(id string, agg trackedAggregate) {
+	if _, ok := u.aggregates[id]; !ok {
+		u.ids = append(u.ids, id)
+	}
+	u.aggregates[id] = agg
+}
+
+// Flush saves the pending state changes of all tracked aggregates in a
+// single batch, and keeps tracking them, so that state changes made after it
+// are saved by the next Flush or Commit. If the version of any of them has
+// changed meanwhile, nothing is saved and the error wraps
+// eventstore.ErrConcurrentUpdate. Nothing is saved either if preparing the
+// events of any of them fails, and in both cases the aggregates keep their
+// pending state changes. Flushing with nothing pending, e.g. twice in a row,
+// saves nothing and succeeds.
+func (u *UnitOfWork) Flush(ctx context.Context) error {
+	batch := make([]eventstore.AggregateEvents, 0, len(u.ids))
+	for _, id := range u.ids {
+		ae, err := u.aggregates[id].prepare(ctx)
+		if err != nil {
+			return fmt.Errorf("aggregate %s: %w", id, err)
+		}
+		if len(ae.Events) > 0 {
+			batch = append(batch, ae)
+		}
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := u.eventStore.SaveBatch(ctx, batch); err != nil {
+		return fmt.Errorf("save batch: %w", err)
+	}
+
+	for _, ae := range batch {
+		u.aggregates[ae.AggregateID].saved(ctx, ae)
+	}
+
+	return nil
+}
+
+// Commit is like Flush, but the unit of work is empty again once committed,
+// so that committing again saves nothing and succeeds. On failure the
+// aggregates stay tracked, so that it can be retried.
+func (u *UnitOfWork) Commit(ctx context.Context) error {
+	if err := u.Flush(ctx); err != nil {
+		return err
+	}
+
+	u.ids = nil
+	clear(u.aggregates)
+
+	return nil
+}
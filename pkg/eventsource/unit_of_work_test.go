@@ -0,0 +1,119 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+func processCommand(
+	t testing.TB, agg *Aggregate[counter, *counter], cmd Command,
+) {
+	t.Helper()
+
+	if err := agg.ProcessCommand(context.Background(), cmd); err != nil {
+		t.Fatalf("process %T: %v", cmd, err)
+	}
+}
+
+func assertStreamLength(
+	t testing.TB, store eventstore.Interface, id string, want int,
+) {
+	t.Helper()
+
+	events, err := store.ListEvents(context.Background(), id)
+	if errors.Is(err, eventstore.ErrStreamNotFound) && want == 0 {
+		return
+	}
+	if err != nil {
+		t.Fatalf("list events of %s: %v", id, err)
+	}
+	if len(events) != want {
+		t.Fatalf("events of %s: got %d, want %d", id, len(events), want)
+	}
+}
+
+func TestUnitOfWorkFlushIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	store, repo := newCounterRepository(t)
+	uow := NewUnitOfWork(store)
+
+	if err := uow.Flush(ctx); err != nil {
+		t.Fatalf("flush empty: %v", err)
+	}
+
+	agg := NewAggregate[counter]("a")
+	repo.Track(uow, agg)
+	if err := uow.Flush(ctx); err != nil {
+		t.Fatalf("flush without changes: %v", err)
+	}
+	assertStreamLength(t, store, "a", 0)
+
+	processCommand(t, agg, counterAdd{N: 1})
+	for range 2 {
+		if err := uow.Flush(ctx); err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+		assertStreamLength(t, store, "a", 1)
+	}
+
+	// The aggregate is still tracked after a flush.
+	processCommand(t, agg, counterAdd{N: 2})
+	if err := uow.Commit(ctx); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if err := uow.Commit(ctx); err != nil {
+		t.Fatalf("commit again: %v", err)
+	}
+	assertStreamLength(t, store, "a", 2)
+
+	loaded, err := repo.Load(ctx, "a")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if loaded.Version() != 2 || loaded.Root().total != 3 {
+		t.Fatalf("got version %d and total %d, want 2 and 3",
+			loaded.Version(), loaded.Root().total)
+	}
+}
+
+func TestUnitOfWorkCommitSavesNothingWhenPreparingFails(t *testing.T) {
+	ctx := context.Background()
+	store, plain := newCounterRepository(t)
+	strict := NewAggregateRepository[counter](store,
+		WithRequiredMetadata(eventstore.UserID))
+	uow := NewUnitOfWork(store)
+
+	a := NewAggregate[counter]("a")
+	processCommand(t, a, counterAdd{N: 1})
+	plain.Track(uow, a)
+	b := NewAggregate[counter]("b")
+	processCommand(t, b, counterAdd{N: 1})
+	strict.Track(uow, b)
+
+	if err := uow.Commit(ctx); !errors.Is(
+		err, eventstore.ErrMetadataKeyMissing,
+	) {
+		t.Fatalf("commit: got %v, want %v",
+			err, eventstore.ErrMetadataKeyMissing)
+	}
+	assertStreamLength(t, store, "a", 0)
+	assertStreamLength(t, store, "b", 0)
+	if n := len(a.PendingStateChanges()); n != 1 {
+		t.Fatalf("pending state changes of a: got %d, want 1", n)
+	}
+
+	ctx = eventstore.WithMetadata(ctx, eventstore.Metadata{
+		eventstore.UserID: "u",
+	})
+	if err := uow.Commit(ctx); err != nil {
+		t.Fatalf("commit again: %v", err)
+	}
+	assertStreamLength(t, store, "a", 1)
+	assertStreamLength(t, store, "b", 1)
+	if n := len(a.PendingStateChanges()); n != 0 {
+		t.Fatalf("pending state changes of a: got %d, want 0", n)
+	}
+}
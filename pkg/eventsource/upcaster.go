@@ -0,0 +1,103 @@
+package eventsource
+
+import "fmt"
+
+var (
+	_ VersionedUpcaster = UpcasterChain(nil)
+	_ VersionedUpcaster = UpcasterSteps(nil)
+)
+
+// Upcaster migrates stored state changes to their current schema before they
+// are decoded. Data it does not recognize must be returned unchanged.
+type Upcaster interface {
+	Upcast(typeURL string, data []byte) (string, []byte, error)
+}
+
+// VersionedUpcaster is implemented by upcasters that tell the schema
+// versions of a type apart. The repository passes it the schema version the
+// event was written with, see eventstore.Event.SchemaVersion, in place of
+// calling Upcast.
+type VersionedUpcaster interface {
+	Upcaster
+	UpcastVersion(
+		typeURL string, schemaVersion int, data []byte,
+	) (string, int, []byte, error)
+}
+
+// UpcasterChain runs its upcasters in order, each on the output of the
+// previous one.
+type UpcasterChain []Upcaster
+
+func (c UpcasterChain) Upcast(
+	typeURL string, data []byte,
+) (string, []byte, error) {
+	for _, upcaster := range c {
+		var err error
+		typeURL, data, err = upcaster.Upcast(typeURL, data)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	return typeURL, data, nil
+}
+
+// UpcastVersion passes the schema version to the upcasters that implement
+// VersionedUpcaster. Other upcasters leave it unchanged.
+func (c UpcasterChain) UpcastVersion(
+	typeURL string, schemaVersion int, data []byte,
+) (string, int, []byte, error) {
+	for _, upcaster := range c {
+		var err error
+		if v, ok := upcaster.(VersionedUpcaster); ok {
+			typeURL, schemaVersion, data, err = v.UpcastVersion(
+				typeURL, schemaVersion, data)
+		} else {
+			typeURL, data, err = upcaster.Upcast(typeURL, data)
+		}
+		if err != nil {
+			return "", 0, nil, err
+		}
+	}
+
+	return typeURL, schemaVersion, data, nil
+}
+
+// SchemaKey identifies a schema version of a state change type.
+type SchemaKey struct {
+	TypeURL       string
+	SchemaVersion int
+}
+
+// UpcasterSteps migrates data one schema version at a time: the step keyed
+// by a type and version turns data of that version into the next one. Steps
+// are applied until there is none for the version reached, so that events
+// of any version end up at the current one.
+type UpcasterSteps map[SchemaKey]func(data []byte) ([]byte, error)
+
+// Upcast assumes schema version 1, like events written before versions
+// were stamped.
+func (s UpcasterSteps) Upcast(
+	typeURL string, data []byte,
+) (string, []byte, error) {
+	typeURL, _, data, err := s.UpcastVersion(typeURL, 1, data)
+	return typeURL, data, err
+}
+
+func (s UpcasterSteps) UpcastVersion(
+	typeURL string, schemaVersion int, data []byte,
+) (string, int, []byte, error) {
+	for {
+		step, ok := s[SchemaKey{TypeURL: typeURL, SchemaVersion: schemaVersion}]
+		if !ok {
+			return typeURL, schemaVersion, data, nil
+		}
+		var err error
+		data, err = step(data)
+		if err != nil {
+			return "", 0, nil, fmt.Errorf("%s version %d: %w",
+				typeURL, schemaVersion, err)
+		}
+		schemaVersion++
+	}
+}
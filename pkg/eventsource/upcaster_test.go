@@ -0,0 +1,214 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// renamingUpcaster migrates state changes of type from to type to. The
+// wrappers share their JSON encoding, so the data is left as is.
+type renamingUpcaster struct {
+	from, to string
+}
+
+func (u renamingUpcaster) Upcast(
+	typeURL string, data []byte,
+) (string, []byte, error) {
+	if typeURL != u.from {
+		return typeURL, data, nil
+	}
+	return u.to, data, nil
+}
+
+func TestLoadUpcastsStoredEvents(t *testing.T) {
+	ctx := context.Background()
+
+	// Adding was first recorded as a UInt32Value, then as an Int32Value,
+	// and is now recorded as an Int64Value.
+	const (
+		v1 = "type.googleapis.com/google.protobuf.UInt32Value"
+		v2 = "type.googleapis.com/google.protobuf.Int32Value"
+		v3 = "type.googleapis.com/google.protobuf.Int64Value"
+	)
+	store, repo := newCounterRepository(t, WithUpcaster(UpcasterChain{
+		renamingUpcaster{from: v1, to: v2},
+		renamingUpcaster{from: v2, to: v3},
+	}))
+
+	data, typeURL, err := ProtoCodec{}.Marshal(wrapperspb.UInt32(5))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if typeURL != v1 {
+		t.Fatalf("type URL: got %s, want %s", typeURL, v1)
+	}
+	if err := store.SaveEvents(ctx, "c", 0, eventstore.Events{{
+		ID:               "c-1",
+		AggregateID:      "c",
+		AggregateVersion: 1,
+		Timestamp:        time.Now(),
+		Metadata:         eventstore.Metadata{},
+		Type:             typeURL,
+		Data:             data,
+	}}); err != nil {
+		t.Fatalf("save events: %v", err)
+	}
+
+	agg, err := repo.Load(ctx, "c")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if agg.Root().total != 5 {
+		t.Fatalf("total: got %d, want 5", agg.Root().total)
+	}
+}
+
+// rescale returns an upcaster step applying f to an Int64Value.
+func rescale(f func(int64) int64) func([]byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		stateChange, err := ProtoCodec{}.Unmarshal(int64TypeURL, data)
+		if err != nil {
+			return nil, err
+		}
+		data, _, err = ProtoCodec{}.Marshal(
+			wrapperspb.Int64(f(stateChange.(*wrapperspb.Int64Value).Value)))
+		return data, err
+	}
+}
+
+const int64TypeURL = "type.googleapis.com/google.protobuf.Int64Value"
+
+// Adding was first recorded in tens, then in units but one short, and is
+// now recorded as is.
+var addingSteps = UpcasterSteps{
+	{TypeURL: int64TypeURL, SchemaVersion: 1}: rescale(
+		func(n int64) int64 { return n * 10 }),
+	{TypeURL: int64TypeURL, SchemaVersion: 2}: rescale(
+		func(n int64) int64 { return n + 1 }),
+}
+
+func TestUpcasterStepsUpcastVersion(t *testing.T) {
+	for _, tc := range []struct {
+		typeURL       string
+		schemaVersion int
+		n             int64
+		wantVersion   int
+		wantN         int64
+	}{
+		{int64TypeURL, 1, 1, 3, 11},
+		{int64TypeURL, 2, 1, 3, 2},
+		{int64TypeURL, 3, 1, 3, 1},
+		{int64TypeURL, 4, 1, 4, 1},
+		{"type.googleapis.com/google.protobuf.Int32Value", 1, 1, 1, 1},
+	} {
+		data, _, err := ProtoCodec{}.Marshal(wrapperspb.Int64(tc.n))
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+
+		typeURL, version, data, err := addingSteps.UpcastVersion(
+			tc.typeURL, tc.schemaVersion, data)
+		if err != nil {
+			t.Fatalf("%s version %d: upcast: %v",
+				tc.typeURL, tc.schemaVersion, err)
+		}
+		stateChange, err := ProtoCodec{}.Unmarshal(int64TypeURL, data)
+		if err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		n := stateChange.(*wrapperspb.Int64Value).Value
+		if typeURL != tc.typeURL || version != tc.wantVersion || n != tc.wantN {
+			t.Fatalf("%s version %d: got %d at version %d of %s, "+
+				"want %d at version %d", tc.typeURL, tc.schemaVersion,
+				n, version, typeURL, tc.wantN, tc.wantVersion)
+		}
+	}
+
+	if _, _, _, err := addingSteps.UpcastVersion(
+		int64TypeURL, 1, []byte("garbage"),
+	); err == nil {
+		t.Fatal("upcast garbage: got nil error")
+	}
+}
+
+func TestLoadUpcastsEventsOfEverySchemaVersion(t *testing.T) {
+	ctx := context.Background()
+	store, repo := newCounterRepository(t,
+		WithUpcaster(UpcasterChain{addingSteps}))
+
+	for i, event := range []struct {
+		n             int64
+		schemaVersion int
+		metadata      eventstore.Metadata
+	}{
+		{1, 1, eventstore.Metadata{}},
+		{5, 2, eventstore.Metadata{}},
+		{100, 3, eventstore.Metadata{}},
+		// Saved before the version was recorded in the event itself.
+		{7, 0, eventstore.Metadata{eventstore.SchemaVersion: float64(2)}},
+		// Saved before versions were recorded at all.
+		{3, 0, eventstore.Metadata{}},
+	} {
+		data, typeURL, err := ProtoCodec{}.Marshal(wrapperspb.Int64(event.n))
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		if err := store.SaveEvents(ctx, "c", i, eventstore.Events{{
+			ID:               fmt.Sprintf("c-%d", i+1),
+			AggregateID:      "c",
+			AggregateVersion: i + 1,
+			Timestamp:        time.Now(),
+			Metadata:         event.metadata,
+			Type:             typeURL,
+			Data:             data,
+			SchemaVersion:    event.schemaVersion,
+		}}); err != nil {
+			t.Fatalf("save event %d: %v", i+1, err)
+		}
+	}
+
+	agg, err := repo.Load(ctx, "c")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if want := int64(11 + 6 + 100 + 8 + 31); agg.Root().total != want {
+		t.Fatalf("total: got %d, want %d", agg.Root().total, want)
+	}
+}
+
+func TestSaveRecordsSchemaVersionInEvents(t *testing.T) {
+	ctx := context.Background()
+	store, repo := newCounterRepository(t,
+		WithSchemaVersionFunc(func(StateChange) int { return 3 }))
+
+	if _, err := repo.Create(ctx, "c", counterAdd{N: 1}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	events, err := store.ListEvents(ctx, "c")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if got := events[0].SchemaVersion; got != 3 {
+		t.Fatalf("schema version: got %d, want 3", got)
+	}
+	if _, ok := events[0].Metadata[eventstore.SchemaVersion]; ok {
+		t.Fatalf("schema version in metadata: %v", events[0].Metadata)
+	}
+
+	// The version is the repository's to record.
+	if _, err := repo.Update(eventstore.WithMetadata(ctx, eventstore.Metadata{
+		eventstore.SchemaVersion: 1,
+	}), "c", counterAdd{N: 1}); !errors.Is(
+		err, eventstore.ErrReservedMetadataKey,
+	) {
+		t.Fatalf("update with schema version in metadata: got %v, want %v",
+			err, eventstore.ErrReservedMetadataKey)
+	}
+}
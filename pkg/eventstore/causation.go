@@ -0,0 +1,50 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// TraceCausation returns the event with the ID along with the events that
+// caused it, following causation IDs back, ordered from the first cause to
+// the event. The chain ends at an event without a causation ID, or whose
+// causation ID does not refer to an event, e.g. because it is the ID of the
+// request or command that started the chain.
+func TraceCausation(
+	ctx context.Context, store Interface, eventID string,
+) (Events, error) {
+	event, err := store.GetEventByID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := Events{event}
+	seen := map[string]struct{}{event.ID: {}}
+
+	for {
+		causationID := event.Metadata.CausationID()
+		if causationID == "" {
+			break
+		}
+		if _, ok := seen[causationID]; ok {
+			return nil, fmt.Errorf("causation cycle at event %s", causationID)
+		}
+
+		event, err = store.GetEventByID(ctx, causationID)
+		if err != nil {
+			if errors.Is(err, ErrEventDoesNotExist) {
+				break
+			}
+			return nil, fmt.Errorf("get event %s: %w", causationID, err)
+		}
+
+		chain = append(chain, event)
+		seen[event.ID] = struct{}{}
+	}
+
+	slices.Reverse(chain)
+
+	return chain, nil
+}
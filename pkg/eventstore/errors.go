@@ -2,4 +2,18 @@ package eventstore
 
 import "errors"
 
-var ErrConcurrentUpdate = errors.New("concurrent update")
+var (
+	ErrConcurrentUpdate         = errors.New("concurrent update")
+	ErrEventHandlerPanicked     = errors.New("event handler panicked")
+	ErrDuplicateEventID         = errors.New("duplicate event ID")
+	ErrSubscriptionDoesNotExist = errors.New("subscription does not exist")
+	ErrPositionOutOfRange       = errors.New("position out of range")
+	ErrInvalidVersionRange      = errors.New("invalid version range")
+	ErrInvalidPageLimit         = errors.New("invalid page limit")
+	ErrStreamNotFound           = errors.New("stream not found")
+	ErrEventDoesNotExist        = errors.New("event does not exist")
+	ErrMetadataKeyMissing       = errors.New("metadata key missing")
+	ErrMetadataValueInvalid     = errors.New("metadata value invalid")
+	ErrReservedMetadataKey      = errors.New("reserved metadata key")
+	ErrSubscriberLagged         = errors.New("subscriber lagged")
+)
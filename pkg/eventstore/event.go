@@ -1,10 +1,6 @@
 package eventstore
 
-import (
-	"time"
-
-	"google.golang.org/protobuf/types/known/anypb"
-)
+import "time"
 
 type Event struct {
 	ID               string
@@ -12,7 +8,60 @@ type Event struct {
 	AggregateVersion int
 	Timestamp        time.Time
 	Metadata         Metadata
-	Data             *anypb.Any
+	// Type discriminates the encoding of Data, e.g. a protobuf type URL.
+	Type string
+	Data []byte
+	// SchemaVersion is the version of the schema Data was encoded with, so
+	// that data of the same Type can be migrated step by step. It is 0 for
+	// events whose version is not known, which count as version 1.
+	SchemaVersion int
+	// GlobalPosition orders the event among the events of all aggregates.
+	// It is 0 for events the store has not assigned a position yet.
+	GlobalPosition int64
+	// AggregateType names the type of the aggregate, so that streams of
+	// different types can be told apart. It is empty for events saved
+	// before it was recorded.
+	AggregateType string
+	// Shredded is set by readers that found the data of the event partly
+	// erased because its encryption key had been shredded.
+	Shredded bool
+}
+
+// EffectiveSchemaVersion returns the schema version of the event, taken
+// from the metadata for events saved before SchemaVersion was recorded.
+func (e *Event) EffectiveSchemaVersion() int {
+	if e.SchemaVersion > 0 {
+		return e.SchemaVersion
+	}
+	return e.Metadata.SchemaVersion()
 }
 
 type Events []*Event
+
+// TombstoneEventType is the type of events marking an aggregate as deleted
+// while keeping its events. Tombstones carry no data.
+const TombstoneEventType = "eventstore.Tombstone"
+
+// AggregateEvents are events to be appended to an aggregate which is
+// expected to be at ExpectedAggregateVersion.
+type AggregateEvents struct {
+	AggregateID              string
+	ExpectedAggregateVersion int
+	Events                   Events
+}
+
+// DuplicateEventIDs reports the IDs that occur more than once in events. It
+// is meant as a diagnostic for streams written by a faulty ID generator.
+func DuplicateEventIDs(events Events) []string {
+	seen := make(map[string]int, len(events))
+	var duplicates []string
+
+	for _, event := range events {
+		seen[event.ID]++
+		if seen[event.ID] == 2 {
+			duplicates = append(duplicates, event.ID)
+		}
+	}
+
+	return duplicates
+}
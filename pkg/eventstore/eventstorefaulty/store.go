@@ -0,0 +1,173 @@
+package eventstorefaulty
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+var _ eventstore.Interface = (*Store)(nil)
+
+// Store passes every call through to the wrapped store, except that saves,
+// i.e. SaveEvents and SaveBatch, fail as configured, and that saves and
+// loads, i.e. ListEvents, ListEventsMany and StreamEvents, are delayed by
+// the configured latency. Saves are numbered from 1, counting from New or
+// the last Reset. A failed save does not reach the wrapped store. It is safe
+// for concurrent use.
+type Store struct {
+	eventstore.Interface
+	mu      sync.Mutex
+	saves   int
+	faults  map[int]error
+	latency time.Duration
+}
+
+func New(store eventstore.Interface) *Store {
+	return &Store{
+		Interface: store,
+		faults:    make(map[int]error),
+	}
+}
+
+// FailSave makes the nth save fail with err.
+func (s *Store) FailSave(n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults[n] = err
+}
+
+// FailNextSave makes the next save fail with err.
+func (s *Store) FailNextSave(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults[s.saves+1] = err
+}
+
+// ForceConflict makes the nth save fail with eventstore.ErrConcurrentUpdate.
+func (s *Store) ForceConflict(n int) {
+	s.FailSave(n, eventstore.ErrConcurrentUpdate)
+}
+
+// SetLatency delays every ListEvents, ListEventsMany and save by d.
+func (s *Store) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = d
+}
+
+// Saves returns the number of saves attempted so far.
+func (s *Store) Saves() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saves
+}
+
+// Reset removes all faults and the latency and restarts the numbering of
+// saves.
+func (s *Store) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.saves = 0
+	clear(s.faults)
+	s.latency = 0
+}
+
+func (s *Store) ListEvents(
+	ctx context.Context, aggregateID string,
+) (eventstore.Events, error) {
+	if err := s.delay(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.Interface.ListEvents(ctx, aggregateID)
+}
+
+func (s *Store) ListEventsMany(
+	ctx context.Context, aggregateIDs []string,
+) (map[string]eventstore.Events, error) {
+	if err := s.delay(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.Interface.ListEventsMany(ctx, aggregateIDs)
+}
+
+func (s *Store) StreamEvents(
+	ctx context.Context, aggregateID string,
+) iter.Seq2[*eventstore.Event, error] {
+	return func(yield func(*eventstore.Event, error) bool) {
+		if err := s.delay(ctx); err != nil {
+			yield(nil, err)
+			return
+		}
+		for event, err := range s.Interface.StreamEvents(ctx, aggregateID) {
+			if !yield(event, err) {
+				return
+			}
+		}
+	}
+}
+
+func (s *Store) SaveEvents(
+	ctx context.Context, aggregateID string, expectedAggregateVersion int,
+	events eventstore.Events,
+) error {
+	if err := s.save(ctx); err != nil {
+		return err
+	}
+
+	return s.Interface.SaveEvents(
+		ctx, aggregateID, expectedAggregateVersion, events)
+}
+
+func (s *Store) SaveBatch(
+	ctx context.Context, batch []eventstore.AggregateEvents,
+) error {
+	if err := s.save(ctx); err != nil {
+		return err
+	}
+
+	return s.Interface.SaveBatch(ctx, batch)
+}
+
+func (s *Store) save(ctx context.Context) error {
+	s.mu.Lock()
+	s.saves++
+	err := s.faults[s.saves]
+	delete(s.faults, s.saves)
+	s.mu.Unlock()
+
+	if err := s.delay(ctx); err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (s *Store) delay(ctx context.Context) error {
+	s.mu.Lock()
+	latency := s.latency
+	s.mu.Unlock()
+
+	if latency == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -1,6 +1,7 @@
 package eventstoreinmemory
 
 import (
+	"context"
 	"sync"
 
 	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
@@ -10,4 +11,20 @@ type aggregate struct {
 	sync.RWMutex
 	version int
 	events  eventstore.Events
+	// deleted is set when the aggregate is removed from the store, for
+	// writers that got hold of it before that.
+	deleted bool
+}
+
+// streamKey identifies an aggregate within the tenant it belongs to.
+type streamKey struct {
+	tenantID    string
+	aggregateID string
+}
+
+func newStreamKey(ctx context.Context, aggregateID string) streamKey {
+	return streamKey{
+		tenantID:    eventstore.MetadataFromContext(ctx).TenantID(),
+		aggregateID: aggregateID,
+	}
 }
@@ -0,0 +1,34 @@
+package eventstoreinmemory
+
+type config struct {
+	uniqueEventIDs       bool
+	subscriberBufferSize int
+}
+
+func newConfig(opts ...option) config {
+	cfg := config{
+		subscriberBufferSize: 100,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+type option func(*config)
+
+// WithUniqueEventIDs makes SaveEvents reject events whose ID has already
+// been saved, for any aggregate, with eventstore.ErrDuplicateEventID.
+func WithUniqueEventIDs() option {
+	return func(cfg *config) {
+		cfg.uniqueEventIDs = true
+	}
+}
+
+// WithSubscriberBufferSize sets how many new events SubscribeAll buffers for
+// a subscriber before dropping it as lagged. It defaults to 100.
+func WithSubscriberBufferSize(size int) option {
+	return func(cfg *config) {
+		cfg.subscriberBufferSize = max(size, 1)
+	}
+}
@@ -0,0 +1,235 @@
+package eventstoreinmemory
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// Fields of an event in a dump. Each event is written as a protobuf message
+// prefixed with its length, in global order.
+const (
+	dumpFieldID               protowire.Number = 1
+	dumpFieldAggregateID      protowire.Number = 2
+	dumpFieldAggregateVersion protowire.Number = 3
+	dumpFieldTimestampSeconds protowire.Number = 4
+	dumpFieldMetadata         protowire.Number = 5
+	dumpFieldType             protowire.Number = 6
+	dumpFieldData             protowire.Number = 7
+	dumpFieldGlobalPosition   protowire.Number = 8
+	dumpFieldTimestampNanos   protowire.Number = 9
+	dumpFieldTenantID         protowire.Number = 10
+	dumpFieldSchemaVersion    protowire.Number = 11
+)
+
+// DumpTo writes the events of all aggregates to w. Tags and snapshots are
+// not included.
+func (s *Store) DumpTo(w io.Writer) error {
+	s.mu.RLock()
+	log := s.log
+	s.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+
+	for _, entry := range log {
+		if entry.event == nil {
+			continue
+		}
+		msg, err := marshalDumpEvent(entry.event, entry.tenantID)
+		if err != nil {
+			return fmt.Errorf("event %s: %w", entry.event.ID, err)
+		}
+		if _, err := bw.Write(protowire.AppendBytes(nil, msg)); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// LoadFrom restores events written by DumpTo into the store, which must be
+// empty. Events keep their global positions, including the gaps left by
+// deleted streams.
+func (s *Store) LoadFrom(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var log []logEntry
+	for len(data) > 0 {
+		msg, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		event, tenantID, err := unmarshalDumpEvent(msg)
+		if err != nil {
+			return fmt.Errorf("event %d: %w", len(log)+1, err)
+		}
+		if event.GlobalPosition <= int64(len(log)) {
+			return fmt.Errorf("event %s: global position %d out of order",
+				event.ID, event.GlobalPosition)
+		}
+		for int64(len(log)) < event.GlobalPosition-1 {
+			log = append(log, logEntry{})
+		}
+		log = append(log, logEntry{tenantID: tenantID, event: event})
+	}
+
+	aggregates := make(map[streamKey]*aggregate)
+	for _, entry := range log {
+		event := entry.event
+		if event == nil {
+			continue
+		}
+		key := streamKey{
+			tenantID:    entry.tenantID,
+			aggregateID: event.AggregateID,
+		}
+		agg := aggregates[key]
+		if agg == nil {
+			agg = new(aggregate)
+			aggregates[key] = agg
+		}
+		if event.AggregateVersion != agg.version+1 {
+			return fmt.Errorf("event %s: aggregate version %d out of order",
+				event.ID, event.AggregateVersion)
+		}
+		agg.events = append(agg.events, event)
+		agg.version++
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.log) > 0 || len(s.aggregates) > 0 {
+		return errors.New("store is not empty")
+	}
+
+	s.aggregates = aggregates
+	if s.config.uniqueEventIDs {
+		for _, entry := range log {
+			if entry.event != nil {
+				s.eventIDs[entry.event.ID] = struct{}{}
+			}
+		}
+	}
+	s.log = log
+	s.publish(log)
+
+	return nil
+}
+
+func marshalDumpEvent(
+	event *eventstore.Event, tenantID string,
+) ([]byte, error) {
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("marshal metadata: %w", err)
+	}
+
+	var b []byte
+	b = protowire.AppendTag(b, dumpFieldID, protowire.BytesType)
+	b = protowire.AppendString(b, event.ID)
+	b = protowire.AppendTag(b, dumpFieldAggregateID, protowire.BytesType)
+	b = protowire.AppendString(b, event.AggregateID)
+	b = protowire.AppendTag(b, dumpFieldAggregateVersion, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(event.AggregateVersion))
+	b = protowire.AppendTag(b, dumpFieldTimestampSeconds, protowire.VarintType)
+	b = protowire.AppendVarint(b, protowire.EncodeZigZag(event.Timestamp.Unix()))
+	b = protowire.AppendTag(b, dumpFieldTimestampNanos, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(event.Timestamp.Nanosecond()))
+	b = protowire.AppendTag(b, dumpFieldMetadata, protowire.BytesType)
+	b = protowire.AppendBytes(b, metadata)
+	b = protowire.AppendTag(b, dumpFieldType, protowire.BytesType)
+	b = protowire.AppendString(b, event.Type)
+	b = protowire.AppendTag(b, dumpFieldData, protowire.BytesType)
+	b = protowire.AppendBytes(b, event.Data)
+	b = protowire.AppendTag(b, dumpFieldGlobalPosition, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(event.GlobalPosition))
+	b = protowire.AppendTag(b, dumpFieldTenantID, protowire.BytesType)
+	b = protowire.AppendString(b, tenantID)
+	b = protowire.AppendTag(b, dumpFieldSchemaVersion, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(event.SchemaVersion))
+
+	return b, nil
+}
+
+func unmarshalDumpEvent(b []byte) (*eventstore.Event, string, error) {
+	var (
+		event    eventstore.Event
+		tenantID string
+		seconds  int64
+		nanos    int64
+	)
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, "", protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, "", protowire.ParseError(n)
+			}
+			b = b[n:]
+			switch num {
+			case dumpFieldID:
+				event.ID = string(v)
+			case dumpFieldAggregateID:
+				event.AggregateID = string(v)
+			case dumpFieldMetadata:
+				if err := json.Unmarshal(v, &event.Metadata); err != nil {
+					return nil, "", fmt.Errorf("unmarshal metadata: %w", err)
+				}
+			case dumpFieldType:
+				event.Type = string(v)
+			case dumpFieldData:
+				event.Data = append([]byte{}, v...)
+			case dumpFieldTenantID:
+				tenantID = string(v)
+			}
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, "", protowire.ParseError(n)
+			}
+			b = b[n:]
+			switch num {
+			case dumpFieldAggregateVersion:
+				event.AggregateVersion = int(v)
+			case dumpFieldTimestampSeconds:
+				seconds = protowire.DecodeZigZag(v)
+			case dumpFieldTimestampNanos:
+				nanos = int64(v)
+			case dumpFieldGlobalPosition:
+				event.GlobalPosition = int64(v)
+			case dumpFieldSchemaVersion:
+				event.SchemaVersion = int(v)
+			}
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, "", protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+
+	event.Timestamp = time.Unix(seconds, nanos)
+
+	return &event, tenantID, nil
+}
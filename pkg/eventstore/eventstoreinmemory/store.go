@@ -1,80 +1,837 @@
 package eventstoreinmemory
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"reflect"
+	"slices"
 	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/anypb"
 
 	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
 )
 
-var _ eventstore.Interface = (*Store)(nil)
+var (
+	_ eventstore.Interface     = (*Store)(nil)
+	_ eventstore.SnapshotStore = (*Store)(nil)
+)
 
 type Store struct {
-	mu         sync.RWMutex
-	aggregates map[string]*aggregate
+	mu          sync.RWMutex
+	config      config
+	aggregates  map[streamKey]*aggregate
+	eventIDs    map[string]struct{}
+	tags        map[string]map[streamKey]struct{}
+	snapshots   map[streamKey]map[int]*eventstore.Snapshot
+	log         []logEntry
+	subscribers map[chan *eventstore.Event]tenantScope
+}
+
+// logEntry is an event of the global log along with the tenant of its
+// aggregate. The event is nil once its stream is deleted.
+type logEntry struct {
+	tenantID string
+	event    *eventstore.Event
+}
+
+// tenantScope tells whose events in the global log are read with a
+// context, see eventstore.WithAllTenants.
+type tenantScope struct {
+	tenantID   string
+	allTenants bool
+}
+
+func newTenantScope(ctx context.Context) tenantScope {
+	return tenantScope{
+		tenantID:   eventstore.MetadataFromContext(ctx).TenantID(),
+		allTenants: eventstore.AllTenants(ctx),
+	}
 }
 
-func New() *Store {
+func (s tenantScope) contains(entry logEntry) bool {
+	return entry.event != nil &&
+		(s.allTenants || entry.tenantID == s.tenantID)
+}
+
+func New(opts ...option) *Store {
 	return &Store{
-		aggregates: make(map[string]*aggregate),
+		config:      newConfig(opts...),
+		aggregates:  make(map[streamKey]*aggregate),
+		eventIDs:    make(map[string]struct{}),
+		tags:        make(map[string]map[streamKey]struct{}),
+		snapshots:   make(map[streamKey]map[int]*eventstore.Snapshot),
+		subscribers: make(map[chan *eventstore.Event]tenantScope),
 	}
 }
 
 func (s *Store) ListEvents(
 	ctx context.Context, aggregateID string,
 ) (eventstore.Events, error) {
-	agg := s.getAggregate(aggregateID)
+	agg, err := s.rlockStream(ctx, aggregateID)
+	if err != nil {
+		return nil, err
+	}
+	defer agg.RUnlock()
+
+	// Appending to the returned slice must not write into the backing array
+	// of the aggregate.
+	return slices.Clone(agg.events), nil
+}
+
+// StreamEvents yields the events the aggregate has when iteration starts.
+// The in-memory store holds them all anyway.
+func (s *Store) StreamEvents(
+	ctx context.Context, aggregateID string,
+) iter.Seq2[*eventstore.Event, error] {
+	return func(yield func(*eventstore.Event, error) bool) {
+		events, err := s.ListEvents(ctx, aggregateID)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, event := range events {
+			if !yield(event, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (s *Store) ListEventsMany(
+	ctx context.Context, aggregateIDs []string,
+) (map[string]eventstore.Events, error) {
+	eventsByAggregate := make(map[string]eventstore.Events)
+
+	for _, aggregateID := range aggregateIDs {
+		events, err := s.ListEvents(ctx, aggregateID)
+		if err != nil {
+			if errors.Is(err, eventstore.ErrStreamNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		eventsByAggregate[aggregateID] = events
+	}
+
+	return eventsByAggregate, nil
+}
+
+func (s *Store) CountEvents(
+	ctx context.Context, aggregateID string,
+) (int, error) {
+	agg := s.getAggregate(newStreamKey(ctx, aggregateID))
 	if agg == nil {
-		return nil, nil
+		return 0, nil
 	}
 
 	agg.RLock()
 	defer agg.RUnlock()
 
-	return agg.events, nil
+	return len(agg.events), nil
+}
+
+func (s *Store) ListEventsPage(
+	ctx context.Context, aggregateID string, afterVersion int, limit int,
+) (eventstore.Events, bool, error) {
+	if limit < 1 {
+		return nil, false, fmt.Errorf("%w: %d",
+			eventstore.ErrInvalidPageLimit, limit)
+	}
+
+	agg, err := s.rlockStream(ctx, aggregateID)
+	if err != nil {
+		return nil, false, err
+	}
+	defer agg.RUnlock()
+
+	start, _ := slices.BinarySearchFunc(agg.events, afterVersion+1,
+		func(event *eventstore.Event, version int) int {
+			return cmp.Compare(event.AggregateVersion, version)
+		})
+	end := min(start+limit, len(agg.events))
+
+	return slices.Clone(agg.events[start:end]), end < len(agg.events), nil
+}
+
+func (s *Store) ListEventsRange(
+	ctx context.Context, aggregateID string, fromVersion int, toVersion int,
+) (eventstore.Events, error) {
+	if toVersion != 0 && fromVersion > toVersion {
+		return nil, eventstore.ErrInvalidVersionRange
+	}
+
+	agg, err := s.rlockStream(ctx, aggregateID)
+	if err != nil {
+		return nil, err
+	}
+	defer agg.RUnlock()
+
+	var events eventstore.Events
+	for _, event := range agg.events {
+		if event.AggregateVersion < fromVersion {
+			continue
+		}
+		if toVersion != 0 && event.AggregateVersion > toVersion {
+			break
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// ListEventsByCorrelation returns all events sharing the correlation ID,
+// including the event whose ID seeded it, in causal order. Events are
+// ordered by causal depth first: events whose causation ID does not refer to
+// another event of the correlation have depth 0, and every other event is
+// one level deeper than the event that caused it. Events at the same depth
+// are ordered by global position, as with the Postgres store.
+func (s *Store) ListEventsByCorrelation(
+	ctx context.Context, correlationID string,
+) (eventstore.Events, error) {
+	tenantID := eventstore.MetadataFromContext(ctx).TenantID()
+
+	s.mu.RLock()
+	var aggregates []*aggregate
+	for key, agg := range s.aggregates {
+		if key.tenantID == tenantID {
+			aggregates = append(aggregates, agg)
+		}
+	}
+	s.mu.RUnlock()
+
+	var events eventstore.Events
+	for _, agg := range aggregates {
+		agg.RLock()
+		for _, event := range agg.events {
+			if event.ID == correlationID ||
+				event.Metadata.CorrelationID() == correlationID {
+				events = append(events, event)
+			}
+		}
+		agg.RUnlock()
+	}
+
+	byID := make(map[string]*eventstore.Event, len(events))
+	for _, event := range events {
+		byID[event.ID] = event
+	}
+
+	depths := make(map[string]int, len(events))
+	var depth func(event *eventstore.Event) int
+	depth = func(event *eventstore.Event) int {
+		if d, ok := depths[event.ID]; ok {
+			return d
+		}
+		d := 0
+		if cause, ok := byID[event.Metadata.CausationID()]; ok {
+			d = depth(cause) + 1
+		}
+		depths[event.ID] = d
+		return d
+	}
+
+	slices.SortFunc(events, func(a, b *eventstore.Event) int {
+		return cmp.Or(
+			cmp.Compare(depth(a), depth(b)),
+			cmp.Compare(a.GlobalPosition, b.GlobalPosition),
+		)
+	})
+
+	return events, nil
+}
+
+func (s *Store) CurrentVersions(
+	ctx context.Context, aggregateIDs []string,
+) (map[string]int, error) {
+	versions := make(map[string]int, len(aggregateIDs))
+
+	for _, id := range aggregateIDs {
+		agg := s.getAggregate(newStreamKey(ctx, id))
+		if agg == nil {
+			versions[id] = 0
+			continue
+		}
+		agg.RLock()
+		versions[id] = agg.version
+		agg.RUnlock()
+	}
+
+	return versions, nil
 }
 
 func (s *Store) SaveEvents(
 	ctx context.Context, aggregateID string, expectedAggregateVersion int,
 	events eventstore.Events,
 ) error {
-	agg := s.getOrCreateAggregate(aggregateID)
-
-	agg.Lock()
+	key := newStreamKey(ctx, aggregateID)
+	agg := s.lockAggregate(key)
 	defer agg.Unlock()
 
 	if agg.version != expectedAggregateVersion {
 		return eventstore.ErrConcurrentUpdate
 	}
 
+	if s.config.uniqueEventIDs {
+		if err := s.reserveEventIDs(events); err != nil {
+			return err
+		}
+	}
+
 	for _, event := range events {
 		agg.events = append(agg.events, event)
 		agg.version++
 	}
 
+	s.appendToLog(key.tenantID, events)
+
+	return nil
+}
+
+// DeleteStream removes the aggregate and its events, tags and snapshot. The
+// global positions of the events are left as gaps.
+func (s *Store) DeleteStream(
+	ctx context.Context, aggregateID string,
+) error {
+	agg := s.getAggregate(newStreamKey(ctx, aggregateID))
+	if agg == nil {
+		return eventstore.ErrStreamNotFound
+	}
+
+	agg.Lock()
+	defer agg.Unlock()
+
+	if len(agg.events) == 0 {
+		return eventstore.ErrStreamNotFound
+	}
+
+	agg.deleted = true
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, event := range agg.events {
+		s.log[event.GlobalPosition-1].event = nil
+	}
+
+	key := newStreamKey(ctx, aggregateID)
+	delete(s.aggregates, key)
+	delete(s.snapshots, key)
+	for tag, keys := range s.tags {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+
+	return nil
+}
+
+// SaveBatch locks all the aggregates of the batch, in the order of their IDs,
+// and checks all their versions before saving any events.
+func (s *Store) SaveBatch(
+	ctx context.Context, batch []eventstore.AggregateEvents,
+) error {
+	batch = slices.Clone(batch)
+	slices.SortFunc(batch, func(a, b eventstore.AggregateEvents) int {
+		return cmp.Compare(a.AggregateID, b.AggregateID)
+	})
+
+	keys := make([]streamKey, 0, len(batch))
+	for i, ae := range batch {
+		if i > 0 && batch[i-1].AggregateID == ae.AggregateID {
+			return fmt.Errorf("aggregate %s occurs more than once in batch",
+				ae.AggregateID)
+		}
+		keys = append(keys, newStreamKey(ctx, ae.AggregateID))
+	}
+
+	aggs := s.lockAggregates(keys)
+	defer func() {
+		for _, agg := range aggs {
+			agg.Unlock()
+		}
+	}()
+
+	var events eventstore.Events
+	for i, ae := range batch {
+		if aggs[i].version != ae.ExpectedAggregateVersion {
+			return fmt.Errorf("aggregate %s: %w",
+				ae.AggregateID, eventstore.ErrConcurrentUpdate)
+		}
+		events = append(events, ae.Events...)
+	}
+
+	if s.config.uniqueEventIDs {
+		if err := s.reserveEventIDs(events); err != nil {
+			return err
+		}
+	}
+
+	for i, ae := range batch {
+		for _, event := range ae.Events {
+			aggs[i].events = append(aggs[i].events, event)
+			aggs[i].version++
+		}
+	}
+
+	s.appendToLog(eventstore.MetadataFromContext(ctx).TenantID(), events)
+
 	return nil
 }
 
-func (s *Store) getAggregate(aggregateID string) *aggregate {
+// IngestEvents appends events that already carry IDs assigned upstream.
+// Events whose ID has already been saved for the aggregate are skipped, so
+// re-submitting them is a no-op. With WithUniqueEventIDs, an ID saved for
+// another aggregate fails with eventstore.ErrDuplicateEventID. Versions are
+// assigned by the store: the events that are not skipped get consecutive
+// versions following the current version of the aggregate, in the order
+// given, and their AggregateVersion fields are overwritten accordingly. It
+// returns the number of events actually saved.
+func (s *Store) IngestEvents(
+	ctx context.Context, aggregateID string, events eventstore.Events,
+) (int, error) {
+	key := newStreamKey(ctx, aggregateID)
+	agg := s.lockAggregate(key)
+	defer agg.Unlock()
+
+	savedEventIDs := make(map[string]struct{}, len(agg.events))
+	for _, event := range agg.events {
+		savedEventIDs[event.ID] = struct{}{}
+	}
+
+	newEvents := make(eventstore.Events, 0, len(events))
+	for _, event := range events {
+		if _, ok := savedEventIDs[event.ID]; ok {
+			continue
+		}
+		savedEventIDs[event.ID] = struct{}{}
+		newEvents = append(newEvents, event)
+	}
+
+	if s.config.uniqueEventIDs {
+		if err := s.reserveEventIDs(newEvents); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, event := range newEvents {
+		agg.version++
+		event.AggregateID = aggregateID
+		event.AggregateVersion = agg.version
+		agg.events = append(agg.events, event)
+	}
+
+	s.appendToLog(key.tenantID, newEvents)
+
+	return len(newEvents), nil
+}
+
+func (s *Store) ListAllEvents(
+	ctx context.Context, afterPosition int64, limit int,
+) (eventstore.Events, error) {
+	return s.listLog(ctx, afterPosition, limit,
+		func(*eventstore.Event) bool { return true }), nil
+}
+
+func (s *Store) ListEventsByType(
+	ctx context.Context, typeURLs []string, afterPosition int64, limit int,
+) (eventstore.Events, error) {
+	return s.listLog(ctx, afterPosition, limit,
+		func(event *eventstore.Event) bool {
+			return slices.Contains(typeURLs, event.Type)
+		}), nil
+}
+
+func (s *Store) ListEventsByMetadata(
+	ctx context.Context, filter map[string]any, afterPosition int64,
+	limit int,
+) (eventstore.Events, error) {
+	return s.listLog(ctx, afterPosition, limit,
+		func(event *eventstore.Event) bool {
+			return metadataContains(event.Metadata, filter)
+		}), nil
+}
+
+// listLog lists up to limit events of the global log in the tenant scope
+// of ctx with a position greater than afterPosition that match.
+func (s *Store) listLog(
+	ctx context.Context, afterPosition int64, limit int,
+	match func(*eventstore.Event) bool,
+) eventstore.Events {
+	scope := newTenantScope(ctx)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events eventstore.Events
+	for _, entry := range s.log[min(max(afterPosition, 0), int64(len(s.log))):] {
+		if limit > 0 && len(events) == limit {
+			break
+		}
+		if scope.contains(entry) && match(entry.event) {
+			events = append(events, entry.event)
+		}
+	}
+
+	return events
+}
+
+// metadataContains mimics JSONB containment for the top-level keys of the
+// filter, comparing values as they were set rather than as JSON.
+func metadataContains(m eventstore.Metadata, filter map[string]any) bool {
+	for key, want := range filter {
+		got, ok := m[key]
+		if !ok || !reflect.DeepEqual(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Store) GetEventByID(
+	ctx context.Context, eventID string,
+) (*eventstore.Event, error) {
+	tenantID := eventstore.MetadataFromContext(ctx).TenantID()
+
+	s.mu.RLock()
+	var aggregates []*aggregate
+	for key, agg := range s.aggregates {
+		if key.tenantID == tenantID {
+			aggregates = append(aggregates, agg)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, agg := range aggregates {
+		agg.RLock()
+		for _, event := range agg.events {
+			if event.ID == eventID {
+				agg.RUnlock()
+				return event, nil
+			}
+		}
+		agg.RUnlock()
+	}
+
+	return nil, fmt.Errorf("%w: %s", eventstore.ErrEventDoesNotExist, eventID)
+}
+
+func (s *Store) LastGlobalPosition(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.log)), nil
+}
+
+// SubscribeAll returns a channel delivering, in global order, every event
+// with a global position greater than fromGlobalPosition, first the events
+// already saved and then new ones as they are saved. New events are pushed
+// to a buffer of WithSubscriberBufferSize events without waiting for the
+// consumer, so that a slow consumer does not hold up writers: once the
+// buffer is full, the subscriber is dropped and the channel closed, with
+// eventstore.SubscriptionErr reporting eventstore.ErrSubscriberLagged. The
+// channel is also closed when ctx is done.
+func (s *Store) SubscribeAll(
+	ctx context.Context, fromGlobalPosition int64,
+) (<-chan *eventstore.Event, error) {
+	if fromGlobalPosition < 0 {
+		return nil, eventstore.ErrPositionOutOfRange
+	}
+
+	scope := newTenantScope(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var backlog eventstore.Events
+	if fromGlobalPosition < int64(len(s.log)) {
+		for _, entry := range s.log[fromGlobalPosition:] {
+			if scope.contains(entry) {
+				backlog = append(backlog, entry.event)
+			}
+		}
+	}
+
+	// The events already saved do not count against the buffer.
+	events := make(chan *eventstore.Event,
+		len(backlog)+s.config.subscriberBufferSize)
+	for _, event := range backlog {
+		events <- event
+	}
+	s.subscribers[events] = scope
+
+	context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if _, ok := s.subscribers[events]; ok {
+			delete(s.subscribers, events)
+			eventstore.CloseSubscription(events, nil)
+		}
+	})
+
+	return events, nil
+}
+
+// publish pushes the events of the entries to the subscribers whose tenant
+// scope they are in, dropping the subscribers lagging behind. It must be
+// called with s.mu locked.
+func (s *Store) publish(entries []logEntry) {
+	for subscriber, scope := range s.subscribers {
+		for _, entry := range entries {
+			if !scope.contains(entry) {
+				continue
+			}
+			select {
+			case subscriber <- entry.event:
+				continue
+			default:
+			}
+			delete(s.subscribers, subscriber)
+			eventstore.CloseSubscription(subscriber,
+				eventstore.ErrSubscriberLagged)
+			break
+		}
+	}
+}
+
+func (s *Store) AddTag(
+	ctx context.Context, aggregateID string, tag string,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tags[tag] == nil {
+		s.tags[tag] = make(map[streamKey]struct{})
+	}
+	s.tags[tag][newStreamKey(ctx, aggregateID)] = struct{}{}
+
+	return nil
+}
+
+func (s *Store) RemoveTag(
+	ctx context.Context, aggregateID string, tag string,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tags[tag], newStreamKey(ctx, aggregateID))
+	if len(s.tags[tag]) == 0 {
+		delete(s.tags, tag)
+	}
+
+	return nil
+}
+
+func (s *Store) ListAggregateIDs(
+	ctx context.Context, aggregateType string, afterID string, limit int,
+) ([]string, error) {
+	tenantID := eventstore.MetadataFromContext(ctx).TenantID()
+
+	// Aggregates are locked before the store by writers, so they are
+	// collected first and locked one by one after.
+	aggs := make(map[string]*aggregate)
+	s.mu.RLock()
+	for key, agg := range s.aggregates {
+		if key.tenantID == tenantID && key.aggregateID > afterID {
+			aggs[key.aggregateID] = agg
+		}
+	}
+	s.mu.RUnlock()
+
+	var aggregateIDs []string
+	for aggregateID, agg := range aggs {
+		agg.RLock()
+		ok := len(agg.events) > 0 &&
+			agg.events[0].AggregateType == aggregateType
+		agg.RUnlock()
+		if ok {
+			aggregateIDs = append(aggregateIDs, aggregateID)
+		}
+	}
+	slices.Sort(aggregateIDs)
+
+	if limit > 0 && len(aggregateIDs) > limit {
+		aggregateIDs = aggregateIDs[:limit]
+	}
+
+	return aggregateIDs, nil
+}
+
+func (s *Store) ListAggregatesByTag(
+	ctx context.Context, tag string,
+) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID := eventstore.MetadataFromContext(ctx).TenantID()
+
+	var aggregateIDs []string
+	for key := range s.tags[tag] {
+		if key.tenantID == tenantID {
+			aggregateIDs = append(aggregateIDs, key.aggregateID)
+		}
+	}
+	slices.Sort(aggregateIDs)
+
+	return aggregateIDs, nil
+}
+
+// SaveSnapshot keeps every snapshot saved for the aggregate, independently
+// of its events.
+func (s *Store) SaveSnapshot(
+	ctx context.Context, aggregateID string, aggregateVersion int,
+	data *anypb.Any,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := newStreamKey(ctx, aggregateID)
+	if s.snapshots[key] == nil {
+		s.snapshots[key] = make(map[int]*eventstore.Snapshot)
+	}
+	s.snapshots[key][aggregateVersion] = &eventstore.Snapshot{
+		AggregateID:      aggregateID,
+		AggregateVersion: aggregateVersion,
+		Timestamp:        time.Now(),
+		Data:             data,
+	}
+
+	return nil
+}
+
+// GetLatestSnapshot returns the snapshot with the highest version.
+func (s *Store) GetLatestSnapshot(
+	ctx context.Context, aggregateID string,
+) (*eventstore.Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshots := s.snapshots[newStreamKey(ctx, aggregateID)]
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+
+	return snapshots[slices.Max(slices.Collect(maps.Keys(snapshots)))], nil
+}
+
+func (s *Store) appendToLog(tenantID string, events eventstore.Events) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]logEntry, 0, len(events))
+	for _, event := range events {
+		event.GlobalPosition = int64(len(s.log) + 1)
+		entry := logEntry{tenantID: tenantID, event: event}
+		s.log = append(s.log, entry)
+		entries = append(entries, entry)
+	}
+
+	s.publish(entries)
+}
+
+func (s *Store) reserveEventIDs(events eventstore.Events) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := make(map[string]struct{}, len(events))
+	for _, event := range events {
+		_, saved := s.eventIDs[event.ID]
+		_, repeated := batch[event.ID]
+		if saved || repeated {
+			return fmt.Errorf("%w: %s", eventstore.ErrDuplicateEventID, event.ID)
+		}
+		batch[event.ID] = struct{}{}
+	}
+
+	for id := range batch {
+		s.eventIDs[id] = struct{}{}
+	}
+
+	return nil
+}
+
+// lockAggregate write-locks the aggregate, creating it if needed. An
+// aggregate deleted while waiting for the lock is replaced by a new one.
+func (s *Store) lockAggregate(key streamKey) *aggregate {
+	for {
+		agg := s.getOrCreateAggregate(key)
+		agg.Lock()
+		if !agg.deleted {
+			return agg
+		}
+		agg.Unlock()
+	}
+}
+
+// lockAggregates write-locks the aggregates in the order given, like
+// lockAggregate does for one.
+func (s *Store) lockAggregates(keys []streamKey) []*aggregate {
+	for {
+		aggs := make([]*aggregate, 0, len(keys))
+		deleted := false
+		for _, key := range keys {
+			agg := s.getOrCreateAggregate(key)
+			agg.Lock()
+			aggs = append(aggs, agg)
+			if agg.deleted {
+				deleted = true
+				break
+			}
+		}
+		if !deleted {
+			return aggs
+		}
+		for _, agg := range aggs {
+			agg.Unlock()
+		}
+	}
+}
+
+// rlockStream returns the aggregate read-locked, failing with
+// ErrStreamNotFound if it has no events.
+func (s *Store) rlockStream(
+	ctx context.Context, aggregateID string,
+) (*aggregate, error) {
+	agg := s.getAggregate(newStreamKey(ctx, aggregateID))
+	if agg != nil {
+		agg.RLock()
+		if !agg.deleted && len(agg.events) > 0 {
+			return agg, nil
+		}
+		agg.RUnlock()
+	}
+
+	return nil, fmt.Errorf("%w: %s", eventstore.ErrStreamNotFound, aggregateID)
+}
+
+func (s *Store) getAggregate(key streamKey) *aggregate {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return s.aggregates[aggregateID]
+	return s.aggregates[key]
 }
 
-func (s *Store) getOrCreateAggregate(aggregateID string) *aggregate {
-	if agg := s.getAggregate(aggregateID); agg != nil {
+func (s *Store) getOrCreateAggregate(key streamKey) *aggregate {
+	if agg := s.getAggregate(key); agg != nil {
 		return agg
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if agg := s.aggregates[aggregateID]; agg != nil {
+	if agg := s.aggregates[key]; agg != nil {
 		return agg
 	}
 
 	agg := new(aggregate)
-	s.aggregates[aggregateID] = agg
+	s.aggregates[key] = agg
 	return agg
 }
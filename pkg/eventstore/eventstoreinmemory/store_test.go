@@ -0,0 +1,397 @@
+package eventstoreinmemory_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoreinmemory"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoretest"
+)
+
+func newEvent(aggregateID string, version int) *eventstore.Event {
+	return &eventstore.Event{
+		ID:               fmt.Sprintf("%s-%d", aggregateID, version),
+		AggregateID:      aggregateID,
+		AggregateVersion: version,
+		Timestamp:        time.Now(),
+		Metadata:         eventstore.Metadata{},
+		Type:             "test.Event",
+	}
+}
+
+func saveEvent(
+	t testing.TB, store eventstore.Interface, aggregateID string, version int,
+) {
+	t.Helper()
+
+	if err := store.SaveEvents(
+		context.Background(), aggregateID, version-1,
+		eventstore.Events{newEvent(aggregateID, version)},
+	); err != nil {
+		t.Fatalf("save event %d of %s: %v", version, aggregateID, err)
+	}
+}
+
+func eventIDs(events eventstore.Events) []string {
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func TestSubscribeAllDeliversBacklogThenNewEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := eventstoreinmemory.New(
+		eventstoreinmemory.WithSubscriberBufferSize(2))
+	for version := 1; version <= 3; version++ {
+		saveEvent(t, store, "a", version)
+	}
+
+	ch, err := store.SubscribeAll(ctx, 1)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	saveEvent(t, store, "b", 1)
+
+	events, err := eventstoretest.DrainSubscription(ctx, ch, 3)
+	if err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	if got := fmt.Sprint(eventIDs(events)); got != "[a-2 a-3 b-1]" {
+		t.Fatalf("events: got %s, want [a-2 a-3 b-1]", got)
+	}
+
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Fatal("subscription not closed after cancel")
+	}
+	if err := eventstore.SubscriptionErr(ch); err != nil {
+		t.Fatalf("subscription error: got %v, want nil", err)
+	}
+}
+
+func TestSubscribeAllDropsLaggingSubscriber(t *testing.T) {
+	ctx := context.Background()
+	store := eventstoreinmemory.New(
+		eventstoreinmemory.WithSubscriberBufferSize(2))
+
+	ch, err := store.SubscribeAll(ctx, 0)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	// Writers are not held up by the subscriber not receiving.
+	for version := 1; version <= 3; version++ {
+		saveEvent(t, store, "a", version)
+	}
+
+	events, err := eventstoretest.DrainSubscription(ctx, ch, 3)
+	if err == nil {
+		t.Fatal("drain: got no error, want subscription closed")
+	}
+	if got := fmt.Sprint(eventIDs(events)); got != "[a-1 a-2]" {
+		t.Fatalf("events: got %s, want [a-1 a-2]", got)
+	}
+	if err := eventstore.SubscriptionErr(ch); !errors.Is(
+		err, eventstore.ErrSubscriberLagged,
+	) {
+		t.Fatalf("subscription error: got %v, want %v",
+			err, eventstore.ErrSubscriberLagged)
+	}
+}
+
+// TestConcurrentSavesAndReads is meant to be run with the race detector.
+func TestConcurrentSavesAndReads(t *testing.T) {
+	const (
+		workers = 8
+		saves   = 50
+	)
+
+	ctx := context.Background()
+	store := eventstoreinmemory.New()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- hammer(ctx, store, fmt.Sprintf("own-%d", w), saves)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	shared, err := store.ListEvents(ctx, "shared")
+	if err != nil {
+		t.Fatalf("list shared events: %v", err)
+	}
+	if len(shared) != workers*saves {
+		t.Fatalf("got %d shared events, want %d", len(shared), workers*saves)
+	}
+	for i, event := range shared {
+		if event.AggregateVersion != i+1 {
+			t.Fatalf("shared event %d has version %d", i, event.AggregateVersion)
+		}
+	}
+
+	all, err := store.ListAllEvents(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("list all events: %v", err)
+	}
+	if len(all) != 2*workers*saves {
+		t.Fatalf("got %d events, want %d", len(all), 2*workers*saves)
+	}
+}
+
+// hammer saves n events to the shared aggregate, retrying conflicts, and n
+// to its own aggregate, reading both and scribbling over the events listed
+// in between.
+func hammer(
+	ctx context.Context, store *eventstoreinmemory.Store, own string, n int,
+) error {
+	for i := range n {
+		for {
+			events, err := store.ListEvents(ctx, "shared")
+			if errors.Is(err, eventstore.ErrStreamNotFound) {
+				err = nil
+			}
+			if err != nil {
+				return fmt.Errorf("list shared events: %w", err)
+			}
+			version := len(events)
+			event := newEvent("shared", version+1)
+			event.ID = fmt.Sprintf("%s-shared-%d", own, i)
+			err = store.SaveEvents(
+				ctx, "shared", version, eventstore.Events{event})
+			if errors.Is(err, eventstore.ErrConcurrentUpdate) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("save shared event: %w", err)
+			}
+			break
+		}
+
+		if err := store.SaveEvents(
+			ctx, own, i, eventstore.Events{newEvent(own, i+1)},
+		); err != nil {
+			return fmt.Errorf("save event of %s: %w", own, err)
+		}
+
+		events, err := store.ListEvents(ctx, own)
+		if err != nil {
+			return fmt.Errorf("list events of %s: %w", own, err)
+		}
+		if len(events) != i+1 {
+			return fmt.Errorf("got %d events of %s, want %d",
+				len(events), own, i+1)
+		}
+		// Writing to the listed slice must not affect the store.
+		events[0] = nil
+		_ = append(events[:1], newEvent(own, 0))
+		events, err = store.ListEvents(ctx, own)
+		if err != nil {
+			return fmt.Errorf("list events of %s: %w", own, err)
+		}
+		if events[0] == nil || events[len(events)-1].AggregateVersion != i+1 {
+			return fmt.Errorf("events of %s changed by writing to a copy", own)
+		}
+
+		if _, err := store.ListAllEvents(ctx, 0, 10); err != nil {
+			return fmt.Errorf("list all events: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func TestGetLatestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	store := eventstoreinmemory.New()
+
+	snapshot, err := store.GetLatestSnapshot(ctx, "a")
+	if err != nil {
+		t.Fatalf("get latest snapshot: %v", err)
+	}
+	if snapshot != nil {
+		t.Fatalf("got snapshot at version %d, want none",
+			snapshot.AggregateVersion)
+	}
+
+	for _, version := range []int{2, 6, 4} {
+		data, err := anypb.New(wrapperspb.Int64(int64(version) * 10))
+		if err != nil {
+			t.Fatalf("marshal snapshot: %v", err)
+		}
+		if err := store.SaveSnapshot(ctx, "a", version, data); err != nil {
+			t.Fatalf("save snapshot at version %d: %v", version, err)
+		}
+	}
+	for version := 1; version <= 6; version++ {
+		saveEvent(t, store, "a", version)
+	}
+
+	snapshot, err = store.GetLatestSnapshot(ctx, "a")
+	if err != nil {
+		t.Fatalf("get latest snapshot: %v", err)
+	}
+	if snapshot == nil || snapshot.AggregateVersion != 6 {
+		t.Fatalf("got %v, want the snapshot at version 6", snapshot)
+	}
+	var value wrapperspb.Int64Value
+	if err := snapshot.Data.UnmarshalTo(&value); err != nil {
+		t.Fatalf("unmarshal snapshot: %v", err)
+	}
+	if value.Value != 60 {
+		t.Fatalf("snapshot data: got %d, want 60", value.Value)
+	}
+
+	// Snapshots are kept apart from the events.
+	events, err := store.ListEvents(ctx, "a")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) != 6 {
+		t.Fatalf("got %d events, want 6", len(events))
+	}
+}
+
+func TestListEventsPageEndingAtLimit(t *testing.T) {
+	ctx := context.Background()
+	store := eventstoreinmemory.New()
+	for version := 1; version <= 6; version++ {
+		saveEvent(t, store, "a", version)
+	}
+
+	for _, tc := range []struct {
+		afterVersion int
+		want         string
+		more         bool
+	}{
+		{0, "[a-1 a-2 a-3]", true},
+		{3, "[a-4 a-5 a-6]", false},
+		{6, "[]", false},
+	} {
+		events, more, err := store.ListEventsPage(ctx, "a", tc.afterVersion, 3)
+		if err != nil {
+			t.Fatalf("list page after %d: %v", tc.afterVersion, err)
+		}
+		if got := fmt.Sprint(eventIDs(events)); got != tc.want || more != tc.more {
+			t.Fatalf("page after %d: got %s and more %t, want %s and %t",
+				tc.afterVersion, got, more, tc.want, tc.more)
+		}
+	}
+
+	if _, _, err := store.ListEventsPage(ctx, "a", 0, 0); !errors.Is(
+		err, eventstore.ErrInvalidPageLimit,
+	) {
+		t.Fatalf("list page of 0: got %v, want %v",
+			err, eventstore.ErrInvalidPageLimit)
+	}
+}
+
+func TestReadsOfMissingStreamReturnNotFound(t *testing.T) {
+	ctx := context.Background()
+	store := eventstoreinmemory.New()
+	saveEvent(t, store, "a", 1)
+	saveEvent(t, store, "deleted", 1)
+	if err := store.DeleteStream(ctx, "deleted"); err != nil {
+		t.Fatalf("delete stream: %v", err)
+	}
+
+	for name, read := range map[string]func(id string) error{
+		"ListEvents": func(id string) error {
+			_, err := store.ListEvents(ctx, id)
+			return err
+		},
+		"ListEventsPage": func(id string) error {
+			_, _, err := store.ListEventsPage(ctx, id, 5, 10)
+			return err
+		},
+		"ListEventsRange": func(id string) error {
+			_, err := store.ListEventsRange(ctx, id, 5, 10)
+			return err
+		},
+		"StreamEvents": func(id string) error {
+			for _, err := range store.StreamEvents(ctx, id) {
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	} {
+		for _, id := range []string{"missing", "deleted"} {
+			if err := read(id); !errors.Is(err, eventstore.ErrStreamNotFound) {
+				t.Errorf("%s of %s stream: got %v, want %v",
+					name, id, err, eventstore.ErrStreamNotFound)
+			}
+		}
+		if err := read("a"); err != nil {
+			t.Errorf("%s past the end of existing stream: %v", name, err)
+		}
+	}
+}
+
+func TestListEventsByCorrelation(t *testing.T) {
+	eventstoretest.RunCorrelationTraceCase(t, eventstoreinmemory.New())
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	ctx := eventstore.WithTenantID(context.Background(), "t")
+	store := eventstoreinmemory.New()
+
+	event := newEvent("a", 1)
+	event.Timestamp = time.Date(2024, 1, 1, 0, 0, 0, 1, time.UTC)
+	event.Metadata = eventstore.Metadata{"k": "v"}
+	event.Data = []byte("data")
+	event.SchemaVersion = 3
+	if err := store.SaveEvents(ctx, "a", 0, eventstore.Events{event}); err != nil {
+		t.Fatalf("save event: %v", err)
+	}
+
+	var dump bytes.Buffer
+	if err := store.DumpTo(&dump); err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	restored := eventstoreinmemory.New()
+	if err := restored.LoadFrom(&dump); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	events, err := restored.ListEvents(ctx, "a")
+	if err != nil {
+		t.Fatalf("list restored events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d restored events, want 1", len(events))
+	}
+	got := events[0]
+	if got.ID != event.ID || !got.Timestamp.Equal(event.Timestamp) ||
+		got.Metadata["k"] != "v" || string(got.Data) != "data" ||
+		got.SchemaVersion != 3 || got.GlobalPosition != 1 {
+		t.Fatalf("restored event: got %+v, want %+v", got, event)
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	eventstoretest.RunTenantIsolationCase(t, eventstoreinmemory.New())
+}
@@ -0,0 +1,116 @@
+package eventstorepostgres
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// Compression compresses the data of events before it is saved and
+// decompresses it when it is read. Its name is saved along with every event
+// it compressed, so that the event can be read back with the same
+// compression, and must not change once events have been saved.
+type Compression interface {
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// GzipCompression compresses with gzip at the given level, where the zero
+// value stands for gzip.DefaultCompression.
+type GzipCompression struct {
+	Level int
+}
+
+func (GzipCompression) Name() string {
+	return "gzip"
+}
+
+func (c GzipCompression) Compress(data []byte) ([]byte, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (GzipCompression) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// compress returns the data of event as it is to be saved, along with the
+// name of the compression applied, empty if none was. Data that does not
+// shrink is saved uncompressed. The compressed data is checked to
+// decompress back to the original, so that a faulty compression fails the
+// save rather than corrupting the event.
+func (s *Store) compress(event *eventstore.Event) ([]byte, string, error) {
+	c := s.config.compression
+	if c == nil {
+		return event.Data, "", nil
+	}
+
+	compressed, err := c.Compress(event.Data)
+	if err != nil {
+		return nil, "", fmt.Errorf("compress data of event %s with %s: %w",
+			event.ID, c.Name(), err)
+	}
+	if len(compressed) >= len(event.Data) {
+		return event.Data, "", nil
+	}
+
+	decompressed, err := c.Decompress(compressed)
+	if err != nil {
+		return nil, "", fmt.Errorf("decompress data of event %s with %s: %w",
+			event.ID, c.Name(), err)
+	}
+	if !bytes.Equal(decompressed, event.Data) {
+		return nil, "", fmt.Errorf("%w: %s does not round-trip data of event %s",
+			ErrCompression, c.Name(), event.ID)
+	}
+
+	return compressed, c.Name(), nil
+}
+
+func (s *Store) decompress(
+	eventID string, compression string, data []byte,
+) ([]byte, error) {
+	if compression == "" {
+		return data, nil
+	}
+
+	c, ok := s.config.decompressors[compression]
+	if !ok {
+		return nil, fmt.Errorf("%w %s of event %s",
+			ErrUnknownCompression, compression, eventID)
+	}
+
+	decompressed, err := c.Decompress(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decompress data of event %s with %s: %w",
+			ErrCompression, eventID, compression, err)
+	}
+
+	return decompressed, nil
+}
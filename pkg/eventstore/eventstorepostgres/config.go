@@ -2,20 +2,49 @@ package eventstorepostgres
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
 )
 
 type config struct {
 	context       context.Context
 	logger        *slog.Logger
 	saveEventHook SaveEventHook
+	readPool      *pgxpool.Pool
+	metrics       eventstore.MetricsCollector
+	tracer        eventstore.Tracer
+	schema        string
+	tablePrefix   string
+	outboxRouter  OutboxRouter
+	retryAttempts int
+	retryBackoff  time.Duration
+	saveIsoLevel  pgx.TxIsoLevel
+	compression   Compression
+	decompressors map[string]Compression
+	fetchSize     int
 }
 
 func newConfig(opts ...option) config {
 	cfg := config{
-		context: context.Background(),
-		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		context:       context.Background(),
+		logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics:       eventstore.NopMetricsCollector{},
+		tracer:        eventstore.NopTracer{},
+		tablePrefix:   defaultTablePrefix,
+		retryAttempts: 3,
+		retryBackoff:  50 * time.Millisecond,
+		saveIsoLevel:  pgx.ReadCommitted,
+		fetchSize:     100,
+		decompressors: map[string]Compression{
+			GzipCompression{}.Name(): GzipCompression{},
+		},
 	}
 	for _, opt := range opts {
 		opt(&cfg)
@@ -42,3 +71,123 @@ func WithSaveEventHook(hook SaveEventHook) option {
 		cfg.saveEventHook = hook
 	}
 }
+
+// WithReadPool routes ListEvents to the given pool, typically connected to
+// a read replica, while writes keep going to the primary pool passed to
+// Start. Replicas lag behind the primary, so events that were just saved
+// may not be visible yet; use WithPrimaryReads on the context where
+// read-your-writes consistency is required.
+func WithReadPool(pool *pgxpool.Pool) option {
+	return func(cfg *config) {
+		cfg.readPool = pool
+	}
+}
+
+// WithMetricsCollector makes the store report ListEvents as loads,
+// SaveEvents as saves and their version conflicts to collector, with an
+// empty aggregate type.
+func WithMetricsCollector(collector eventstore.MetricsCollector) option {
+	return func(cfg *config) {
+		cfg.metrics = collector
+	}
+}
+
+// WithTracer makes the store create a span around each of its reads and
+// writes of events.
+func WithTracer(tracer eventstore.Tracer) option {
+	return func(cfg *config) {
+		cfg.tracer = tracer
+	}
+}
+
+// WithSchema makes the store qualify its tables with schema instead of
+// resolving them through the search path. It panics if schema is not a
+// lowercase SQL identifier. The tables have to be created in that schema,
+// e.g. by running the migrations with the search path set to it.
+func WithSchema(schema string) option {
+	validateIdentifier("schema", schema)
+	return func(cfg *config) {
+		cfg.schema = schema
+	}
+}
+
+// WithTablePrefix replaces the "es_" prefix of the table names, letting
+// several stores share a schema. It panics if prefix does not form
+// lowercase SQL identifiers. The migrations have to be adjusted to create
+// the tables under the same names.
+func WithTablePrefix(prefix string) option {
+	validateIdentifier("table prefix", prefix)
+	return func(cfg *config) {
+		cfg.tablePrefix = prefix
+	}
+}
+
+// WithOutbox makes the store insert every event it saves into the outbox
+// table, within the same transaction, under the topic and partition key
+// given by router. The table is created by the migrations in
+// migrations/outbox, which are only needed when this option is used.
+func WithOutbox(router OutboxRouter) option {
+	return func(cfg *config) {
+		cfg.outboxRouter = router
+	}
+}
+
+// WithConnectionRetry sets how many times reads of streams, SaveEvents and
+// SaveBatch are attempted when they fail to reach the server or with a
+// serialization failure, and the backoff before the first retry, which
+// doubles with each further one. Only connection errors that occurred
+// before anything was sent are retried, and serialization failures abort
+// the whole transaction, so retrying never applies a write twice. Version
+// conflicts, including unique violations on the aggregate version, are
+// returned right away as eventstore.ErrConcurrentUpdate. The default is 3
+// attempts starting at 50ms; 1 disables retries.
+func WithConnectionRetry(maxAttempts int, initialBackoff time.Duration) option {
+	return func(cfg *config) {
+		cfg.retryAttempts = maxAttempts
+		cfg.retryBackoff = initialBackoff
+	}
+}
+
+// WithSaveIsolationLevel sets the isolation level of the transactions of
+// SaveEvents, SaveBatch and IngestEvents, regardless of the default of the
+// server. The default, pgx.ReadCommitted, is enough for the version check:
+// it updates the version of the aggregate only if it is the expected one,
+// which locks the row, so that a concurrent save waits and then fails with
+// eventstore.ErrConcurrentUpdate, and the unique constraint on the
+// aggregate version backs it up. pgx.RepeatableRead and pgx.Serializable
+// give the same guarantee for the version, plus a consistent snapshot for
+// save hooks reading other tables, but concurrent saves may fail with
+// serialization failures instead, which SaveEvents and SaveBatch retry, see
+// WithConnectionRetry, and report as ErrSerialization if they persist.
+func WithSaveIsolationLevel(level pgx.TxIsoLevel) option {
+	return func(cfg *config) {
+		cfg.saveIsoLevel = level
+	}
+}
+
+// WithCompression makes the store compress the data of the events it saves,
+// unless the data does not shrink. Events are read back with the
+// compression recorded along with them, so events saved before compression
+// was enabled, or after it is disabled again, still load; gzip is always
+// readable, while a custom compression has to stay configured for as long
+// as there are events saved with it. Compression is disabled by default.
+func WithCompression(compression Compression) option {
+	return func(cfg *config) {
+		cfg.compression = compression
+		cfg.decompressors[compression.Name()] = compression
+	}
+}
+
+// WithFetchSize sets how many events ListEvents and StreamEvents fetch at
+// once from the cursor they read a stream through, 100 by default. Larger
+// batches take fewer round trips, while smaller ones lower the memory taken
+// by rows not yet decoded and let cancellation take effect sooner. It
+// panics if size is not positive.
+func WithFetchSize(size int) option {
+	if size < 1 {
+		panic(fmt.Sprintf("eventstorepostgres: invalid fetch size: %d", size))
+	}
+	return func(cfg *config) {
+		cfg.fetchSize = size
+	}
+}
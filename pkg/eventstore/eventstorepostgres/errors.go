@@ -0,0 +1,16 @@
+package eventstorepostgres
+
+import "errors"
+
+var (
+	ErrSchemaNotMigrated = errors.New("schema not migrated")
+	// ErrSerialization wraps serialization failures (SQLSTATE 40001) still
+	// occurring once retries are used up. They are safe to retry, unlike
+	// eventstore.ErrConcurrentUpdate.
+	ErrSerialization = errors.New("serialization failure")
+	// ErrCompression is returned when the data of an event fails to
+	// decompress, or the configured compression does not give back the
+	// data it compressed, in which case the event is not saved.
+	ErrCompression        = errors.New("compression failure")
+	ErrUnknownCompression = errors.New("unknown compression")
+)
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX es_events_correlation_id_idx;
+
+END;
@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE es_events
+    ALTER COLUMN data TYPE JSONB
+    USING convert_from(data, 'UTF8')::jsonb || jsonb_build_object('@type', event_type);
+
+ALTER TABLE es_events
+    DROP COLUMN event_type;
+
+END;
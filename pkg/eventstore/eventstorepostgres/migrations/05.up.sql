@@ -0,0 +1,18 @@
+BEGIN;
+
+ALTER TABLE es_events
+    ADD COLUMN event_type TEXT;
+
+UPDATE
+    es_events
+SET
+    event_type = data ->> '@type';
+
+ALTER TABLE es_events
+    ALTER COLUMN event_type SET NOT NULL;
+
+ALTER TABLE es_events
+    ALTER COLUMN data TYPE BYTEA
+    USING convert_to(data::text, 'UTF8');
+
+END;
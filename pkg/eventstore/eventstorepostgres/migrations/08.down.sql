@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX es_events_aggregate_type_idx;
+
+ALTER TABLE es_events
+    DROP COLUMN aggregate_type;
+
+END;
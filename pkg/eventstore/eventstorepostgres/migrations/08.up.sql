@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE es_events
+    ADD COLUMN aggregate_type TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX es_events_aggregate_type_idx ON es_events (tenant_id, aggregate_type, aggregate_id);
+
+END;
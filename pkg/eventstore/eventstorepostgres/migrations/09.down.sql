@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX es_events_metadata_idx;
+
+END;
@@ -0,0 +1,5 @@
+BEGIN;
+
+CREATE INDEX es_events_metadata_idx ON es_events USING GIN (metadata jsonb_path_ops);
+
+END;
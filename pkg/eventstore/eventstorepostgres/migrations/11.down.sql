@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE es_events
+    DROP COLUMN schema_version;
+
+END;
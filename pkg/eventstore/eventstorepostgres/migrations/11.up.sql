@@ -0,0 +1,13 @@
+BEGIN;
+
+ALTER TABLE es_events
+    ADD COLUMN schema_version INT NOT NULL DEFAULT 1;
+
+UPDATE
+    es_events
+SET
+    schema_version = (metadata ->> 'X-Schema-Version')::INT
+WHERE
+    metadata ? 'X-Schema-Version';
+
+END;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX es_events_tenant_id_sequence_number_idx;
+
+END;
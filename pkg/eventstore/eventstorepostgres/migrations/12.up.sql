@@ -0,0 +1,5 @@
+BEGIN;
+
+CREATE INDEX es_events_tenant_id_sequence_number_idx ON es_events (tenant_id, sequence_number);
+
+END;
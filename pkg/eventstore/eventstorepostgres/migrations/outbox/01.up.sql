@@ -0,0 +1,16 @@
+BEGIN;
+
+CREATE TABLE es_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id TEXT NOT NULL,
+    topic TEXT NOT NULL,
+    partition_key TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    published BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX ON es_outbox (id)
+WHERE
+    NOT published;
+
+END;
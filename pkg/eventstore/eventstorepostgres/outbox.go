@@ -0,0 +1,96 @@
+package eventstorepostgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// OutboxRouter tells which topic an event is to be published to and the key
+// partitioning it there.
+type OutboxRouter = func(*eventstore.Event) (topic, partitionKey string)
+
+// OutboxMessage is an event waiting in the outbox to be published. Payload
+// is the JSON encoding of the event.
+type OutboxMessage struct {
+	ID           int64
+	EventID      string
+	Topic        string
+	PartitionKey string
+	Payload      []byte
+}
+
+// OutboxReader is what a relay needs to publish the outbox. Messages are
+// listed in the order their events were saved, which is the version order
+// within an aggregate; a relay publishing them in that order, and marking
+// them published only afterwards, delivers every message at least once and
+// in order per aggregate. Published messages are kept in the table and are
+// left to be deleted separately.
+type OutboxReader interface {
+	ListUnpublishedOutboxMessages(ctx context.Context, limit int) ([]*OutboxMessage, error)
+	MarkOutboxMessagesPublished(ctx context.Context, ids []int64) error
+}
+
+var _ OutboxReader = (*Store)(nil)
+
+func (s *Store) ListUnpublishedOutboxMessages(
+	ctx context.Context, limit int,
+) ([]*OutboxMessage, error) {
+	rows, _ := s.pool.Query(ctx, s.queries.listUnpublishedOutboxMessages,
+		pgx.NamedArgs{
+			"limit": limit,
+		})
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*OutboxMessage, error) {
+		var m OutboxMessage
+		if err := row.Scan(
+			&m.ID, &m.EventID, &m.Topic, &m.PartitionKey, &m.Payload,
+		); err != nil {
+			return nil, err
+		}
+		return &m, nil
+	})
+}
+
+func (s *Store) MarkOutboxMessagesPublished(
+	ctx context.Context, ids []int64,
+) error {
+	_, err := s.pool.Exec(ctx, s.queries.markOutboxMessagesPublished,
+		pgx.NamedArgs{
+			"ids": ids,
+		})
+	return err
+}
+
+func (s *Store) saveOutboxMessages(
+	ctx context.Context, tx pgx.Tx, events eventstore.Events,
+) error {
+	var (
+		eventIDs      = make([]string, len(events))
+		topics        = make([]string, len(events))
+		partitionKeys = make([]string, len(events))
+		payloads      = make([][]byte, len(events))
+	)
+
+	for i, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("%d: marshal event: %w", i, err)
+		}
+		eventIDs[i] = event.ID
+		topics[i], partitionKeys[i] = s.config.outboxRouter(event)
+		payloads[i] = payload
+	}
+
+	_, err := tx.Exec(ctx, s.queries.saveOutboxMessages, pgx.NamedArgs{
+		"event_ids":      eventIDs,
+		"topics":         topics,
+		"partition_keys": partitionKeys,
+		"payloads":       payloads,
+	})
+	return err
+}
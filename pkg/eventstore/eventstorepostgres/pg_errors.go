@@ -0,0 +1,34 @@
+package eventstorepostgres
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	uniqueViolationCode      = "23505"
+	serializationFailureCode = "40001"
+)
+
+func isUniqueViolation(err error, constraintName string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode &&
+		pgErr.ConstraintName == constraintName
+}
+
+// isSerializationFailure reports whether err is a transaction aborted by
+// the server because it could not be serialized with concurrent ones,
+// which is safe to retry as a whole.
+func isSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == serializationFailureCode
+}
+
+// isConnectionError reports whether err is a failure to reach the server
+// that happened before the statement could have had any effect, so that
+// retrying cannot apply a write twice.
+func isConnectionError(err error) bool {
+	var connectErr *pgconn.ConnectError
+	return errors.As(err, &connectErr) || pgconn.SafeToRetry(err)
+}
@@ -0,0 +1,32 @@
+package eventstorepostgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// Ping checks that the database is reachable and that the migrations the
+// store depends on have been applied, failing with ErrSchemaNotMigrated
+// otherwise. The outbox table is only checked when WithOutbox is used.
+func (s *Store) Ping(ctx context.Context) error {
+	if err := s.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+
+	checks := []string{s.queries.checkSchema}
+	if s.config.outboxRouter != nil {
+		checks = append(checks, s.queries.checkOutboxSchema)
+	}
+
+	for _, check := range checks {
+		var migrated bool
+		if err := s.pool.QueryRow(ctx, check).Scan(&migrated); err != nil {
+			return fmt.Errorf("check schema: %w", err)
+		}
+		if !migrated {
+			return ErrSchemaNotMigrated
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package eventstorepostgres
+
+import "context"
+
+type primaryReadsContextKey struct{}
+
+// WithPrimaryReads forces reads made with the returned context to go to the
+// primary pool even if a read pool is configured.
+func WithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsContextKey{}, true)
+}
+
+func primaryReads(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryReadsContextKey{}).(bool)
+	return v
+}
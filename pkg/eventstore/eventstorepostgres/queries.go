@@ -1,20 +1,92 @@
 package eventstorepostgres
 
-import _ "embed"
+import (
+	_ "embed"
+	"fmt"
+)
 
 var (
 	//go:embed queries/list_events.sql
 	listEventsQuery string
 
+	//go:embed queries/list_events_many.sql
+	listEventsManyQuery string
+
+	//go:embed queries/count_events.sql
+	countEventsQuery string
+
+	//go:embed queries/stream_exists.sql
+	streamExistsQuery string
+
+	//go:embed queries/list_events_page.sql
+	listEventsPageQuery string
+
+	//go:embed queries/close_events_cursor.sql
+	closeEventsCursorQuery string
+
+	//go:embed queries/declare_events_cursor.sql
+	declareEventsCursorQuery string
+
+	//go:embed queries/fetch_events_cursor.sql
+	fetchEventsCursorQuery string
+
+	//go:embed queries/list_events_by_type.sql
+	listEventsByTypeQuery string
+
+	//go:embed queries/list_events_by_metadata.sql
+	listEventsByMetadataQuery string
+
+	//go:embed queries/list_events_range.sql
+	listEventsRangeQuery string
+
+	//go:embed queries/list_events_until.sql
+	listEventsUntilQuery string
+
+	//go:embed queries/list_events_after_position.sql
+	listEventsAfterPositionQuery string
+
+	//go:embed queries/list_events_after_version.sql
+	listEventsAfterVersionQuery string
+
+	//go:embed queries/list_events_by_correlation.sql
+	listEventsByCorrelationQuery string
+
+	//go:embed queries/get_event_by_id.sql
+	getEventByIDQuery string
+
+	//go:embed queries/list_aggregate_versions.sql
+	listAggregateVersionsQuery string
+
 	//go:embed queries/create_aggregate.sql
 	createAggregateQuery string
 
+	//go:embed queries/lock_aggregate.sql
+	lockAggregateQuery string
+
+	//go:embed queries/list_saved_event_ids.sql
+	listSavedEventIDsQuery string
+
 	//go:embed queries/update_aggregate_version.sql
 	updateAggregateVersionQuery string
 
+	//go:embed queries/add_aggregate_tag.sql
+	addAggregateTagQuery string
+
+	//go:embed queries/remove_aggregate_tag.sql
+	removeAggregateTagQuery string
+
+	//go:embed queries/list_aggregate_ids.sql
+	listAggregateIDsQuery string
+
+	//go:embed queries/list_aggregates_by_tag.sql
+	listAggregatesByTagQuery string
+
 	//go:embed queries/save_event.sql
 	saveEventQuery string
 
+	//go:embed queries/save_events.sql
+	saveEventsQuery string
+
 	//go:embed queries/sequence_events.sql
 	sequenceEventsQuery string
 
@@ -38,4 +110,165 @@ var (
 
 	//go:embed queries/complete_subscription_event_processing.sql
 	completeSubscriptionEventProcessingQuery string
+
+	//go:embed queries/lock_subscription.sql
+	lockSubscriptionQuery string
+
+	//go:embed queries/select_last_sequence_number.sql
+	selectLastSequenceNumberQuery string
+
+	//go:embed queries/clear_subscription_backlog.sql
+	clearSubscriptionBacklogQuery string
+
+	//go:embed queries/update_subscription_position.sql
+	updateSubscriptionPositionQuery string
+
+	//go:embed queries/delete_aggregate_subscription_backlogs.sql
+	deleteAggregateSubscriptionBacklogsQuery string
+
+	//go:embed queries/delete_aggregate_events.sql
+	deleteAggregateEventsQuery string
+
+	//go:embed queries/delete_aggregate_tags.sql
+	deleteAggregateTagsQuery string
+
+	//go:embed queries/delete_aggregate_snapshots.sql
+	deleteAggregateSnapshotsQuery string
+
+	//go:embed queries/delete_aggregate.sql
+	deleteAggregateQuery string
+
+	//go:embed queries/save_snapshot.sql
+	saveSnapshotQuery string
+
+	//go:embed queries/get_latest_snapshot.sql
+	getLatestSnapshotQuery string
+
+	//go:embed queries/save_outbox_messages.sql
+	saveOutboxMessagesQuery string
+
+	//go:embed queries/list_unpublished_outbox_messages.sql
+	listUnpublishedOutboxMessagesQuery string
+
+	//go:embed queries/mark_outbox_messages_published.sql
+	markOutboxMessagesPublishedQuery string
+
+	//go:embed queries/check_schema.sql
+	checkSchemaQuery string
+
+	//go:embed queries/check_outbox_schema.sql
+	checkOutboxSchemaQuery string
 )
+
+type queries struct {
+	listEvents                           string
+	listEventsMany                       string
+	countEvents                          string
+	streamExists                         string
+	listEventsPage                       string
+	declareEventsCursor                  string
+	closeEventsCursor                    string
+	fetchEventsCursor                    string
+	listEventsByType                     string
+	listEventsByMetadata                 string
+	listEventsRange                      string
+	listEventsUntil                      string
+	listEventsAfterPosition              string
+	listEventsAfterVersion               string
+	listEventsByCorrelation              string
+	getEventByID                         string
+	listAggregateVersions                string
+	createAggregate                      string
+	lockAggregate                        string
+	listSavedEventIDs                    string
+	updateAggregateVersion               string
+	addAggregateTag                      string
+	removeAggregateTag                   string
+	listAggregateIDs                     string
+	listAggregatesByTag                  string
+	saveEvent                            string
+	saveEvents                           string
+	sequenceEvents                       string
+	notifyEventsInserted                 string
+	acquireEventsAdvisoryLock            string
+	notifyEventsSequenced                string
+	createSubscription                   string
+	populateSubscriptionBacklog          string
+	selectSubscriptionEventForProcessing string
+	completeSubscriptionEventProcessing  string
+	lockSubscription                     string
+	selectLastSequenceNumber             string
+	clearSubscriptionBacklog             string
+	updateSubscriptionPosition           string
+	deleteAggregateSubscriptionBacklogs  string
+	deleteAggregateEvents                string
+	deleteAggregateTags                  string
+	deleteAggregateSnapshots             string
+	deleteAggregate                      string
+	saveSnapshot                         string
+	getLatestSnapshot                    string
+	saveOutboxMessages                   string
+	listUnpublishedOutboxMessages        string
+	markOutboxMessagesPublished          string
+	checkSchema                          string
+	checkOutboxSchema                    string
+}
+
+// newQueries rewrites the queries for the tables, and sets the number of
+// events fetched at once from the events cursor to fetchSize, as FETCH does
+// not take parameters.
+func newQueries(t tables, fetchSize int) queries {
+	return queries{
+		listEvents:                           t.rewrite(listEventsQuery),
+		listEventsMany:                       t.rewrite(listEventsManyQuery),
+		countEvents:                          t.rewrite(countEventsQuery),
+		streamExists:                         t.rewrite(streamExistsQuery),
+		listEventsPage:                       t.rewrite(listEventsPageQuery),
+		declareEventsCursor:                  t.rewrite(declareEventsCursorQuery),
+		closeEventsCursor:                    closeEventsCursorQuery,
+		fetchEventsCursor:                    fmt.Sprintf(t.rewrite(fetchEventsCursorQuery), fetchSize),
+		listEventsByType:                     t.rewrite(listEventsByTypeQuery),
+		listEventsByMetadata:                 t.rewrite(listEventsByMetadataQuery),
+		listEventsRange:                      t.rewrite(listEventsRangeQuery),
+		listEventsUntil:                      t.rewrite(listEventsUntilQuery),
+		listEventsAfterPosition:              t.rewrite(listEventsAfterPositionQuery),
+		listEventsAfterVersion:               t.rewrite(listEventsAfterVersionQuery),
+		listEventsByCorrelation:              t.rewrite(listEventsByCorrelationQuery),
+		getEventByID:                         t.rewrite(getEventByIDQuery),
+		listAggregateVersions:                t.rewrite(listAggregateVersionsQuery),
+		createAggregate:                      t.rewrite(createAggregateQuery),
+		lockAggregate:                        t.rewrite(lockAggregateQuery),
+		listSavedEventIDs:                    t.rewrite(listSavedEventIDsQuery),
+		updateAggregateVersion:               t.rewrite(updateAggregateVersionQuery),
+		addAggregateTag:                      t.rewrite(addAggregateTagQuery),
+		removeAggregateTag:                   t.rewrite(removeAggregateTagQuery),
+		listAggregateIDs:                     t.rewrite(listAggregateIDsQuery),
+		listAggregatesByTag:                  t.rewrite(listAggregatesByTagQuery),
+		saveEvent:                            t.rewrite(saveEventQuery),
+		saveEvents:                           t.rewrite(saveEventsQuery),
+		sequenceEvents:                       t.rewrite(sequenceEventsQuery),
+		notifyEventsInserted:                 t.rewrite(notifyEventsInsertedQuery),
+		acquireEventsAdvisoryLock:            t.rewrite(acquireEventsAdvisoryLockQuery),
+		notifyEventsSequenced:                t.rewrite(notifyEventsSequencedQuery),
+		createSubscription:                   t.rewrite(createSubscriptionQuery),
+		populateSubscriptionBacklog:          t.rewrite(populateSubscriptionBacklogQuery),
+		selectSubscriptionEventForProcessing: t.rewrite(selectSubscriptionEventForProcessingQuery),
+		completeSubscriptionEventProcessing:  t.rewrite(completeSubscriptionEventProcessingQuery),
+		lockSubscription:                     t.rewrite(lockSubscriptionQuery),
+		selectLastSequenceNumber:             t.rewrite(selectLastSequenceNumberQuery),
+		clearSubscriptionBacklog:             t.rewrite(clearSubscriptionBacklogQuery),
+		updateSubscriptionPosition:           t.rewrite(updateSubscriptionPositionQuery),
+		deleteAggregateSubscriptionBacklogs:  t.rewrite(deleteAggregateSubscriptionBacklogsQuery),
+		deleteAggregateEvents:                t.rewrite(deleteAggregateEventsQuery),
+		deleteAggregateTags:                  t.rewrite(deleteAggregateTagsQuery),
+		deleteAggregateSnapshots:             t.rewrite(deleteAggregateSnapshotsQuery),
+		deleteAggregate:                      t.rewrite(deleteAggregateQuery),
+		saveSnapshot:                         t.rewrite(saveSnapshotQuery),
+		getLatestSnapshot:                    t.rewrite(getLatestSnapshotQuery),
+		saveOutboxMessages:                   t.rewrite(saveOutboxMessagesQuery),
+		listUnpublishedOutboxMessages:        t.rewrite(listUnpublishedOutboxMessagesQuery),
+		markOutboxMessagesPublished:          t.rewrite(markOutboxMessagesPublishedQuery),
+		checkSchema:                          t.rewrite(checkSchemaQuery),
+		checkOutboxSchema:                    t.rewrite(checkOutboxSchemaQuery),
+	}
+}
@@ -0,0 +1,4 @@
+INSERT INTO es_aggregate_tags (tenant_id, tag, aggregate_id)
+    VALUES (@tenant_id, @tag, @aggregate_id)
+ON CONFLICT
+    DO NOTHING;
@@ -0,0 +1,2 @@
+SELECT
+    to_regclass('es_outbox') IS NOT NULL;
@@ -0,0 +1,39 @@
+SELECT
+    to_regclass('es_aggregates') IS NOT NULL
+    AND to_regclass('es_events') IS NOT NULL
+    AND to_regclass('es_subscriptions') IS NOT NULL
+    AND to_regclass('es_subscription_backlogs') IS NOT NULL
+    AND to_regclass('es_aggregate_tags') IS NOT NULL
+    AND to_regclass('es_snapshots') IS NOT NULL
+    AND EXISTS (
+        SELECT
+        FROM
+            pg_attribute
+        WHERE
+            attrelid = to_regclass('es_events')
+            AND attname = 'tenant_id'
+            AND NOT attisdropped)
+    AND EXISTS (
+        SELECT
+        FROM
+            pg_attribute
+        WHERE
+            attrelid = to_regclass('es_events')
+            AND attname = 'aggregate_type'
+            AND NOT attisdropped)
+    AND EXISTS (
+        SELECT
+        FROM
+            pg_attribute
+        WHERE
+            attrelid = to_regclass('es_events')
+            AND attname = 'data_compression'
+            AND NOT attisdropped)
+    AND EXISTS (
+        SELECT
+        FROM
+            pg_attribute
+        WHERE
+            attrelid = to_regclass('es_events')
+            AND attname = 'schema_version'
+            AND NOT attisdropped);
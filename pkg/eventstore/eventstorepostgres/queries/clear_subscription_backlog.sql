@@ -0,0 +1,2 @@
+DELETE FROM es_subscription_backlogs
+WHERE subscription_id = @subscription_id;
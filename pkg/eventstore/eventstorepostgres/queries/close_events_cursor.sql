@@ -0,0 +1 @@
+CLOSE events_cursor;
@@ -0,0 +1,7 @@
+SELECT
+    COUNT(*)
+FROM
+    es_events
+WHERE
+    tenant_id = @tenant_id
+    AND aggregate_id = @aggregate_id;
@@ -1,4 +1,4 @@
-INSERT INTO es_aggregates (id, version)
-    VALUES (@aggregate_id, 0)
+INSERT INTO es_aggregates (tenant_id, id, version)
+    VALUES (@tenant_id, @aggregate_id, 0)
 ON CONFLICT
     DO NOTHING;
@@ -0,0 +1,3 @@
+DELETE FROM es_aggregates
+WHERE tenant_id = @tenant_id
+    AND id = @aggregate_id;
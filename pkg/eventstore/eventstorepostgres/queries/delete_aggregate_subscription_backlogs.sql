@@ -0,0 +1,4 @@
+DELETE FROM es_subscription_backlogs b USING es_events e
+WHERE b.event_id = e.id
+    AND e.tenant_id = @tenant_id
+    AND e.aggregate_id = @aggregate_id;
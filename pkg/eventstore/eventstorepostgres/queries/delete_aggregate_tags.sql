@@ -0,0 +1,3 @@
+DELETE FROM es_aggregate_tags
+WHERE tenant_id = @tenant_id
+    AND aggregate_id = @aggregate_id;
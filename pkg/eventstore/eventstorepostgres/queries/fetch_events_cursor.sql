@@ -0,0 +1 @@
+FETCH FORWARD %d FROM events_cursor;
@@ -0,0 +1,13 @@
+SELECT
+    aggregate_id,
+    aggregate_version,
+    timestamp,
+    data
+FROM
+    es_snapshots
+WHERE
+    tenant_id = @tenant_id
+    AND aggregate_id = @aggregate_id
+ORDER BY
+    aggregate_version DESC
+LIMIT 1;
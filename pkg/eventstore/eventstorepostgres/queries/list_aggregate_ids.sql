@@ -0,0 +1,11 @@
+SELECT DISTINCT
+    aggregate_id
+FROM
+    es_events
+WHERE
+    tenant_id = @tenant_id
+    AND aggregate_type = @aggregate_type
+    AND aggregate_id > @after_id
+ORDER BY
+    aggregate_id
+LIMIT @limit;
@@ -0,0 +1,8 @@
+SELECT
+    id,
+    version
+FROM
+    es_aggregates
+WHERE
+    tenant_id = @tenant_id
+    AND id = ANY (@aggregate_ids);
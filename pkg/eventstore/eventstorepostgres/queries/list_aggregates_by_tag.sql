@@ -0,0 +1,9 @@
+SELECT
+    aggregate_id
+FROM
+    es_aggregate_tags
+WHERE
+    tenant_id = @tenant_id
+    AND tag = @tag
+ORDER BY
+    aggregate_id;
@@ -1,13 +1,19 @@
 SELECT
     id,
     aggregate_id,
+    aggregate_type,
     aggregate_version,
     timestamp,
     metadata,
-    data
+    event_type,
+    data,
+    sequence_number,
+    data_compression,
+    schema_version
 FROM
     es_events
 WHERE
-    aggregate_id = @aggregate_id
+    tenant_id = @tenant_id
+    AND aggregate_id = @aggregate_id
 ORDER BY
     aggregate_version;
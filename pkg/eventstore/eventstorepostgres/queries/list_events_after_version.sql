@@ -0,0 +1,20 @@
+SELECT
+    id,
+    aggregate_id,
+    aggregate_type,
+    aggregate_version,
+    timestamp,
+    metadata,
+    event_type,
+    data,
+    sequence_number,
+    data_compression,
+    schema_version
+FROM
+    es_events
+WHERE
+    tenant_id = @tenant_id
+    AND aggregate_id = @aggregate_id
+    AND aggregate_version > @after_version
+ORDER BY
+    aggregate_version;
@@ -0,0 +1,61 @@
+WITH RECURSIVE correlated_events AS (
+    SELECT
+        id,
+        aggregate_id,
+        aggregate_type,
+        aggregate_version,
+        timestamp,
+        metadata,
+        event_type,
+        data,
+        sequence_number,
+        data_compression,
+        schema_version,
+        metadata ->> 'X-Causation-ID' AS causation_id
+    FROM
+        es_events
+    WHERE
+        tenant_id = @tenant_id
+        AND (metadata ->> 'X-Correlation-ID' = @correlation_id
+            OR id = @correlation_id)
+),
+causal_events AS (
+    SELECT
+        e.*,
+        0 AS depth
+    FROM
+        correlated_events e
+    WHERE
+        NOT EXISTS (
+            SELECT
+            FROM
+                correlated_events c
+            WHERE
+                c.id = e.causation_id)
+        UNION ALL
+        SELECT
+            e.*,
+            c.depth + 1
+        FROM
+            correlated_events e
+            JOIN causal_events c ON e.causation_id = c.id
+)
+SELECT
+    id,
+    aggregate_id,
+    aggregate_type,
+    aggregate_version,
+    timestamp,
+    metadata,
+    event_type,
+    data,
+    sequence_number,
+    data_compression,
+    schema_version
+FROM
+    causal_events
+ORDER BY
+    depth,
+    sequence_number NULLS LAST,
+    aggregate_id,
+    aggregate_version;
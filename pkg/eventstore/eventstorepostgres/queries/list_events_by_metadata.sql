@@ -0,0 +1,22 @@
+SELECT
+    id,
+    aggregate_id,
+    aggregate_type,
+    aggregate_version,
+    timestamp,
+    metadata,
+    event_type,
+    data,
+    sequence_number,
+    data_compression,
+    schema_version
+FROM
+    es_events
+WHERE
+    metadata @> @filter::jsonb
+    AND (@all_tenants::BOOLEAN
+        OR tenant_id = @tenant_id)
+    AND sequence_number > @after_position
+ORDER BY
+    sequence_number
+LIMIT @limit;
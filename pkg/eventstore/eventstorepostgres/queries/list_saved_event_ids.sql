@@ -0,0 +1,8 @@
+SELECT
+    id,
+    tenant_id,
+    aggregate_id
+FROM
+    es_events
+WHERE
+    id = ANY (@event_ids);
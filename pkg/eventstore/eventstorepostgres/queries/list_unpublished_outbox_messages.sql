@@ -0,0 +1,13 @@
+SELECT
+    id,
+    event_id,
+    topic,
+    partition_key,
+    payload
+FROM
+    es_outbox
+WHERE
+    NOT published
+ORDER BY
+    id
+LIMIT @limit;
@@ -0,0 +1,7 @@
+SELECT
+    position
+FROM
+    es_subscriptions
+WHERE
+    id = @subscription_id
+FOR UPDATE;
@@ -0,0 +1,6 @@
+UPDATE
+    es_outbox
+SET
+    published = TRUE
+WHERE
+    id = ANY (@ids::BIGINT[]);
@@ -1 +1,2 @@
-NOTIFY "es_events.inserted";
+SELECT
+    pg_notify(@channel, '');
@@ -1 +1,2 @@
-NOTIFY "es_events.sequenced";
+SELECT
+    pg_notify(@channel, '');
@@ -0,0 +1,4 @@
+DELETE FROM es_aggregate_tags
+WHERE tenant_id = @tenant_id
+    AND tag = @tag
+    AND aggregate_id = @aggregate_id;
@@ -1,2 +1,2 @@
-INSERT INTO es_events (id, aggregate_id, aggregate_version, timestamp, metadata, data)
-    VALUES (@id, @aggregate_id, @aggregate_version, @timestamp, @metadata, @data);
+INSERT INTO es_events (id, tenant_id, aggregate_id, aggregate_type, aggregate_version, timestamp, metadata, event_type, data, data_compression, schema_version)
+    VALUES (@id, @tenant_id, @aggregate_id, @aggregate_type, @aggregate_version, @timestamp, @metadata, @event_type, @data, @data_compression, @schema_version);
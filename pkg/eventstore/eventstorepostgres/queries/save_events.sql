@@ -0,0 +1,16 @@
+INSERT INTO es_events (id, tenant_id, aggregate_id, aggregate_type, aggregate_version, timestamp, metadata, event_type, data, data_compression, schema_version)
+SELECT
+    id,
+    @tenant_id,
+    aggregate_id,
+    aggregate_type,
+    aggregate_version,
+    timestamp,
+    metadata,
+    event_type,
+    data,
+    data_compression,
+    schema_version
+FROM
+    unnest(@ids::TEXT[], @aggregate_ids::TEXT[], @aggregate_types::TEXT[], @aggregate_versions::INT[], @timestamps::TIMESTAMPTZ[], @metadata::JSONB[], @event_types::TEXT[], @data::BYTEA[], @data_compressions::TEXT[], @schema_versions::INT[])
+    AS e (id, aggregate_id, aggregate_type, aggregate_version, timestamp, metadata, event_type, data, data_compression, schema_version);
@@ -0,0 +1,11 @@
+INSERT INTO es_outbox (event_id, topic, partition_key, payload)
+SELECT
+    event_id,
+    topic,
+    partition_key,
+    payload
+FROM
+    unnest(@event_ids::TEXT[], @topics::TEXT[], @partition_keys::TEXT[], @payloads::BYTEA[])
+    WITH ORDINALITY AS m (event_id, topic, partition_key, payload, n)
+ORDER BY
+    n;
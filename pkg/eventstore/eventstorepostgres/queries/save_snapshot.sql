@@ -0,0 +1,5 @@
+INSERT INTO es_snapshots (tenant_id, aggregate_id, aggregate_version, timestamp, data)
+    VALUES (@tenant_id, @aggregate_id, @aggregate_version, @timestamp, @data)
+ON CONFLICT (tenant_id, aggregate_id, aggregate_version)
+    DO UPDATE SET
+        timestamp = excluded.timestamp, data = excluded.data;
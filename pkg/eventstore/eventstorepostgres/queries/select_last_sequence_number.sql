@@ -0,0 +1,4 @@
+SELECT
+    coalesce(max(sequence_number), 0)
+FROM
+    es_events;
@@ -1,17 +1,23 @@
 WITH processible_events AS (
-    SELECT DISTINCT ON (e.aggregate_id)
+    SELECT DISTINCT ON (e.tenant_id, e.aggregate_id)
         e.id,
         e.aggregate_id,
+        e.aggregate_type,
         e.aggregate_version,
         e.timestamp,
         e.metadata,
-        e.data
+        e.event_type,
+        e.data,
+        e.sequence_number,
+        e.data_compression,
+        e.schema_version
     FROM
         es_subscription_backlogs b
         JOIN es_events e ON b.event_id = e.id
     WHERE
         b.subscription_id = @subscription_id
     ORDER BY
+        e.tenant_id,
         e.aggregate_id,
         e.aggregate_version
 )
@@ -0,0 +1,8 @@
+SELECT
+    EXISTS (
+        SELECT
+        FROM
+            es_aggregates
+        WHERE
+            tenant_id = @tenant_id
+            AND id = @aggregate_id);
@@ -3,5 +3,6 @@ UPDATE
 SET
     version = @new_aggregate_version
 WHERE
-    id = @aggregate_id
+    tenant_id = @tenant_id
+    AND id = @aggregate_id
     AND version = @expected_aggregate_version;
@@ -0,0 +1,6 @@
+UPDATE
+    es_subscriptions
+SET
+    position = @position
+WHERE
+    id = @subscription_id;
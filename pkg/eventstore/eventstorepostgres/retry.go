@@ -0,0 +1,53 @@
+package eventstorepostgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// retry calls f until it succeeds, fails with an error other than a
+// connection error or a serialization failure, or the configured number of
+// attempts is used up, doubling the backoff between attempts. Cancelling ctx
+// interrupts the backoff. f must run its statements in a transaction of its
+// own, so that a serialization failure can be retried by calling it again.
+// A serialization failure returned in the end is wrapped in
+// ErrSerialization. Saves in the caller's transaction, see WithTx, are not
+// retried.
+func (s *Store) retry(ctx context.Context, f func() error) error {
+	backoff := s.config.retryBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+
+		serialization := isSerializationFailure(err)
+		if serialization {
+			err = fmt.Errorf("%w: %w", ErrSerialization, err)
+		}
+
+		if _, ok := txFromContext(ctx); ok ||
+			attempt >= s.config.retryAttempts ||
+			!serialization && !isConnectionError(err) {
+			return err
+		}
+
+		s.config.logger.WarnContext(ctx,
+			"retrying after retryable error",
+			slog.String("error", err.Error()),
+			slog.Int("attempt", attempt))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+	}
+}
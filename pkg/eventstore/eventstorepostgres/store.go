@@ -1,14 +1,19 @@
 package eventstorepostgres
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"iter"
 	"log/slog"
+	"math"
+	"slices"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rnovatorov/go-routine"
 	"github.com/rnovatorov/pgxlisten"
@@ -18,8 +23,15 @@ import (
 	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
 )
 
-var _ eventstore.Interface = (*Store)(nil)
+var (
+	_ eventstore.Interface     = (*Store)(nil)
+	_ eventstore.SnapshotStore = (*Store)(nil)
+)
 
+// Store saves events at the READ COMMITTED isolation level by default, under
+// which concurrent saves to an aggregate are told apart by its version, see
+// WithSaveIsolationLevel for the alternatives. Saves run in a transaction of
+// their own unless the caller provides one with WithTx.
 type Store struct {
 	routines                   *routine.Group
 	pool                       *pgxpool.Pool
@@ -28,10 +40,13 @@ type Store struct {
 	listenerReady              chan struct{}
 	eventsSequencedFanout      *pgxlisten.Fanout
 	eventsSequencedFanoutReady chan struct{}
+	tables                     tables
+	queries                    queries
 }
 
 func Start(pool *pgxpool.Pool, opts ...option) *Store {
 	cfg := newConfig(opts...)
+	tables := tables{schema: cfg.schema, prefix: cfg.tablePrefix}
 
 	s := &Store{
 		routines:                   routine.NewGroup(cfg.context),
@@ -39,6 +54,8 @@ func Start(pool *pgxpool.Pool, opts ...option) *Store {
 		config:                     cfg,
 		listenerReady:              make(chan struct{}),
 		eventsSequencedFanoutReady: make(chan struct{}),
+		tables:                     tables,
+		queries:                    newQueries(tables, cfg.fetchSize),
 	}
 
 	s.routines.Go(s.runListen)
@@ -48,6 +65,9 @@ func Start(pool *pgxpool.Pool, opts ...option) *Store {
 	return s
 }
 
+// Stop cancels all background routines, including subscriptions, and waits
+// for them to return. An event whose handler is interrupted by Stop is not
+// marked as processed and is redelivered once the subscription is resumed.
 func (s *Store) Stop() {
 	s.routines.Stop()
 }
@@ -70,7 +90,7 @@ func (s *Store) runEventsSequencedFanout(ctx context.Context) error {
 	case <-s.listenerReady:
 	}
 
-	eventsSequenced := s.listener.Listen("es_events.sequenced")
+	eventsSequenced := s.listener.Listen(s.tables.channel("events.sequenced"))
 	defer eventsSequenced.Unlisten()
 
 	s.eventsSequencedFanout = pgxlisten.StartFanout(eventsSequenced)
@@ -86,7 +106,7 @@ func (s *Store) runEventsSequencedFanout(ctx context.Context) error {
 func (s *Store) Subscribe(
 	ctx context.Context, subscriptionID string, handler eventstore.EventHandler,
 ) error {
-	if _, err := s.pool.Exec(ctx, createSubscriptionQuery, pgx.NamedArgs{
+	if _, err := s.pool.Exec(ctx, s.queries.createSubscription, pgx.NamedArgs{
 		"subscription_id": subscriptionID,
 	}); err != nil {
 		return fmt.Errorf("create subscription: %w", err)
@@ -100,6 +120,210 @@ func (s *Store) Subscribe(
 	return nil
 }
 
+// SubscribeAggregate calls handler for every event of the aggregate with a
+// version greater than afterVersion, first for the events already saved and
+// then for new ones as they are saved. It blocks until ctx is done, in which
+// case it returns nil, or until handler fails. Events are delivered in
+// version order, and only once the handler returns is the next one passed.
+func (s *Store) SubscribeAggregate(
+	ctx context.Context, aggregateID string, afterVersion int,
+	handler eventstore.EventHandler,
+) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-s.eventsSequencedFanoutReady:
+	}
+
+	eventsSequenced := s.eventsSequencedFanout.Listen()
+	defer eventsSequenced.Unlisten()
+
+	// FIXME: Hard-code.
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		rows, _ := s.reader(ctx).Query(ctx, s.queries.listEventsAfterVersion,
+			pgx.NamedArgs{
+				"tenant_id":     tenantID(ctx),
+				"aggregate_id":  aggregateID,
+				"after_version": afterVersion,
+			})
+		events, err := pgx.CollectRows(rows, s.collectEvent)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			s.config.logger.ErrorContext(ctx,
+				"failed to list aggregate events",
+				slog.String("error", err.Error()),
+				slog.String("aggregate_id", aggregateID))
+		}
+
+		for _, event := range events {
+			if err := handler(ctx, event); err != nil {
+				return fmt.Errorf("event handler: %w", err)
+			}
+			afterVersion = event.AggregateVersion
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-eventsSequenced.Notifications():
+		}
+	}
+}
+
+// SubscribeAll returns a channel delivering, in global order, every event
+// with a global position greater than fromGlobalPosition, first the events
+// already sequenced and then new ones as they are sequenced. Events are read
+// from the database at the pace of the consumer, so a slow consumer falls
+// behind without holding up writers. The channel is closed when ctx is done
+// or the store is stopped.
+func (s *Store) SubscribeAll(
+	ctx context.Context, fromGlobalPosition int64,
+) (<-chan *eventstore.Event, error) {
+	if fromGlobalPosition < 0 {
+		return nil, eventstore.ErrPositionOutOfRange
+	}
+
+	events := make(chan *eventstore.Event, subscribeAllBatchSize)
+
+	s.routines.Go(func(storeCtx context.Context) error {
+		defer close(events)
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stop := context.AfterFunc(storeCtx, cancel)
+		defer stop()
+
+		s.runSubscribeAll(ctx, fromGlobalPosition, events)
+
+		return nil
+	})
+
+	return events, nil
+}
+
+// FIXME: Hard-code.
+const subscribeAllBatchSize = 100
+
+func (s *Store) runSubscribeAll(
+	ctx context.Context, position int64, events chan<- *eventstore.Event,
+) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-s.eventsSequencedFanoutReady:
+	}
+
+	eventsSequenced := s.eventsSequencedFanout.Listen()
+	defer eventsSequenced.Unlisten()
+
+	// FIXME: Hard-code.
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		rows, _ := s.reader(ctx).Query(ctx, s.queries.listEventsAfterPosition,
+			pgx.NamedArgs{
+				"tenant_id":      tenantID(ctx),
+				"all_tenants":    eventstore.AllTenants(ctx),
+				"after_position": position,
+				"limit":          subscribeAllBatchSize,
+			})
+		batch, err := pgx.CollectRows(rows, s.collectEvent)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.config.logger.ErrorContext(ctx,
+				"failed to list events",
+				slog.String("error", err.Error()),
+				slog.Int64("position", position))
+		}
+
+		for _, event := range batch {
+			select {
+			case <-ctx.Done():
+				return
+			case events <- event:
+			}
+			position = event.GlobalPosition
+		}
+
+		if len(batch) == subscribeAllBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-eventsSequenced.Notifications():
+		}
+	}
+}
+
+// ResetSubscription rewinds (or fast-forwards) the subscription so that its
+// handler next receives the events with a sequence number greater than
+// position. Events pending in its backlog are discarded and, if position is
+// lower than before, events are delivered again, so handlers must process
+// them idempotently. Resetting beyond the last sequenced event fails with
+// eventstore.ErrPositionOutOfRange.
+func (s *Store) ResetSubscription(
+	ctx context.Context, subscriptionID string, position int64,
+) error {
+	if position < 0 {
+		return eventstore.ErrPositionOutOfRange
+	}
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		var currentPosition int64
+		if err := tx.QueryRow(ctx, s.queries.lockSubscription, pgx.NamedArgs{
+			"subscription_id": subscriptionID,
+		}).Scan(&currentPosition); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return eventstore.ErrSubscriptionDoesNotExist
+			}
+			return fmt.Errorf("lock subscription: %w", err)
+		}
+
+		var lastSequenceNumber int64
+		if err := tx.QueryRow(
+			ctx, s.queries.selectLastSequenceNumber,
+		).Scan(&lastSequenceNumber); err != nil {
+			return fmt.Errorf("select last sequence number: %w", err)
+		}
+		if position > lastSequenceNumber {
+			return eventstore.ErrPositionOutOfRange
+		}
+
+		if _, err := tx.Exec(ctx, s.queries.clearSubscriptionBacklog, pgx.NamedArgs{
+			"subscription_id": subscriptionID,
+		}); err != nil {
+			return fmt.Errorf("clear backlog: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, s.queries.updateSubscriptionPosition, pgx.NamedArgs{
+			"subscription_id": subscriptionID,
+			"position":        position,
+		}); err != nil {
+			return fmt.Errorf("update position: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, s.queries.notifyEventsSequenced, pgx.NamedArgs{
+			"channel": s.tables.channel("events.sequenced"),
+		}); err != nil {
+			return fmt.Errorf("notify events sequenced: %w", err)
+		}
+
+		return nil
+	})
+}
+
 func (s *Store) runSequenceEvents(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
@@ -107,7 +331,7 @@ func (s *Store) runSequenceEvents(ctx context.Context) error {
 	case <-s.listenerReady:
 	}
 
-	eventsInserted := s.listener.Listen("es_events.inserted")
+	eventsInserted := s.listener.Listen(s.tables.channel("events.inserted"))
 	defer eventsInserted.Unlisten()
 
 	// FIXME: Hard-code.
@@ -132,16 +356,18 @@ func (s *Store) runSequenceEvents(ctx context.Context) error {
 func (s *Store) sequenceEvents(ctx context.Context) error {
 	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
 		if _, err := tx.Exec(
-			ctx, acquireEventsAdvisoryLockQuery,
+			ctx, s.queries.acquireEventsAdvisoryLock,
 		); err != nil {
 			return fmt.Errorf("acquire events advisory lock: %w", err)
 		}
 
-		if ct, err := tx.Exec(ctx, sequenceEventsQuery); err != nil {
+		if ct, err := tx.Exec(ctx, s.queries.sequenceEvents); err != nil {
 			return err
 		} else if ct.RowsAffected() > 0 {
 			if _, err := tx.Exec(
-				ctx, notifyEventsSequencedQuery,
+				ctx, s.queries.notifyEventsSequenced, pgx.NamedArgs{
+					"channel": s.tables.channel("events.sequenced"),
+				},
 			); err != nil {
 				return fmt.Errorf("notify events sequenced: %w", err)
 			}
@@ -188,7 +414,7 @@ func (s *Store) runSubscription(
 func (s *Store) processSubscriptionEvents(
 	ctx context.Context, subscriptionID string, handler eventstore.EventHandler,
 ) error {
-	if _, err := s.pool.Exec(ctx, populateSubscriptionBacklogQuery, pgx.NamedArgs{
+	if _, err := s.pool.Exec(ctx, s.queries.populateSubscriptionBacklog, pgx.NamedArgs{
 		"subscription_id": subscriptionID,
 	}); err != nil {
 		return fmt.Errorf("populate backlog: %w", err)
@@ -210,7 +436,7 @@ func (s *Store) handleSubscriptionEvent(
 	ctx context.Context, subscriptionID string, handler eventstore.EventHandler,
 ) error {
 	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
-		rows, _ := tx.Query(ctx, selectSubscriptionEventForProcessingQuery,
+		rows, _ := tx.Query(ctx, s.queries.selectSubscriptionEventForProcessing,
 			pgx.NamedArgs{
 				"subscription_id": subscriptionID,
 			})
@@ -219,11 +445,11 @@ func (s *Store) handleSubscriptionEvent(
 			return fmt.Errorf("select event for processing: %w", err)
 		}
 
-		if err := handler(ctx, event); err != nil {
+		if err := s.callEventHandler(ctx, handler, event); err != nil {
 			return fmt.Errorf("event handler: %w", err)
 		}
 
-		if _, err := tx.Exec(ctx, completeSubscriptionEventProcessingQuery,
+		if _, err := tx.Exec(ctx, s.queries.completeSubscriptionEventProcessing,
 			pgx.NamedArgs{
 				"subscription_id": subscriptionID,
 				"event_id":        event.ID,
@@ -236,114 +462,1071 @@ func (s *Store) handleSubscriptionEvent(
 	})
 }
 
+func (s *Store) callEventHandler(
+	ctx context.Context, handler eventstore.EventHandler, event *eventstore.Event,
+) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", eventstore.ErrEventHandlerPanicked, r)
+		}
+	}()
+
+	return handler(ctx, event)
+}
+
+// ListEvents reads the events through a cursor, as StreamEvents does, so
+// that the server sends a long stream a batch at a time and cancelling ctx
+// stops the read between batches. The events
+// returned still take memory in proportion to the length of the stream;
+// use StreamEvents to process very long streams in constant memory.
 func (s *Store) ListEvents(
 	ctx context.Context, aggregateID string,
 ) (eventstore.Events, error) {
-	rows, _ := s.pool.Query(ctx, listEventsQuery, pgx.NamedArgs{
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.ListEvents",
+		aggregateID)
+	defer span.End()
+
+	defer func(start time.Time) {
+		s.config.metrics.ObserveLoad("", time.Since(start))
+	}(time.Now())
+
+	var events eventstore.Events
+
+	err := s.retry(ctx, func() error {
+		events = nil
+		return s.fetchEvents(ctx, aggregateID,
+			func(batch eventstore.Events) bool {
+				events = append(events, batch...)
+				return true
+			})
+	})
+
+	return events, err
+}
+
+// StreamEvents reads the events through a cursor, a batch at a time, within
+// a read-only transaction held open until iteration ends.
+func (s *Store) StreamEvents(
+	ctx context.Context, aggregateID string,
+) iter.Seq2[*eventstore.Event, error] {
+	return func(yield func(*eventstore.Event, error) bool) {
+		ctx, span := s.startSpan(ctx, "eventstorepostgres.StreamEvents",
+			aggregateID)
+		defer span.End()
+
+		stopped := false
+
+		err := s.fetchEvents(ctx, aggregateID,
+			func(batch eventstore.Events) bool {
+				for _, event := range batch {
+					if !yield(event, nil) {
+						stopped = true
+						return false
+					}
+				}
+				return true
+			})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// fetchEvents passes the events of the aggregate to f in batches of the
+// fetch size, see WithFetchSize, until they run out or f returns false. It
+// fails with ErrStreamNotFound if the aggregate has no events.
+// The batches are fetched through a cursor within a read-only transaction,
+// or within a savepoint of the caller's transaction, see WithTx.
+func (s *Store) fetchEvents(
+	ctx context.Context, aggregateID string,
+	f func(eventstore.Events) bool,
+) error {
+	return s.beginReadTxFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, s.queries.declareEventsCursor,
+			pgx.NamedArgs{
+				"tenant_id":    tenantID(ctx),
+				"aggregate_id": aggregateID,
+			}); err != nil {
+			return fmt.Errorf("declare cursor: %w", err)
+		}
+
+		for first := true; ; first = false {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			rows, _ := tx.Query(ctx, s.queries.fetchEventsCursor)
+			events, err := pgx.CollectRows(rows, s.collectEvent)
+			if err != nil {
+				return fmt.Errorf("fetch: %w", err)
+			}
+
+			// Streams are never left without events, as deleting one
+			// deletes its aggregate as well.
+			if first && len(events) == 0 {
+				return fmt.Errorf("%w: %s",
+					eventstore.ErrStreamNotFound, aggregateID)
+			}
+
+			if !f(events) || len(events) < s.config.fetchSize {
+				break
+			}
+		}
+
+		// Releasing a savepoint keeps the cursor open until the caller's
+		// transaction ends, so it is closed for the next read to declare.
+		if _, err := tx.Exec(ctx, s.queries.closeEventsCursor); err != nil {
+			return fmt.Errorf("close cursor: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (s *Store) ListEventsMany(
+	ctx context.Context, aggregateIDs []string,
+) (map[string]eventstore.Events, error) {
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.ListEventsMany", "")
+	defer span.End()
+
+	defer func(start time.Time) {
+		s.config.metrics.ObserveLoad("", time.Since(start))
+	}(time.Now())
+
+	var events eventstore.Events
+
+	err := s.retry(ctx, func() error {
+		rows, _ := s.reader(ctx).Query(ctx, s.queries.listEventsMany,
+			pgx.NamedArgs{
+				"tenant_id":     tenantID(ctx),
+				"aggregate_ids": aggregateIDs,
+			})
+
+		var err error
+		events, err = pgx.CollectRows(rows, s.collectEvent)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	eventsByAggregate := make(map[string]eventstore.Events)
+	for _, event := range events {
+		eventsByAggregate[event.AggregateID] = append(
+			eventsByAggregate[event.AggregateID], event)
+	}
+
+	return eventsByAggregate, nil
+}
+
+func (s *Store) CountEvents(
+	ctx context.Context, aggregateID string,
+) (int, error) {
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.CountEvents",
+		aggregateID)
+	defer span.End()
+
+	var count int
+
+	err := s.retry(ctx, func() error {
+		return s.reader(ctx).QueryRow(ctx, s.queries.countEvents,
+			pgx.NamedArgs{
+				"tenant_id":    tenantID(ctx),
+				"aggregate_id": aggregateID,
+			}).Scan(&count)
+	})
+
+	return count, err
+}
+
+func (s *Store) ListEventsPage(
+	ctx context.Context, aggregateID string, afterVersion int, limit int,
+) (eventstore.Events, bool, error) {
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.ListEventsPage",
+		aggregateID)
+	defer span.End()
+
+	if limit < 1 {
+		return nil, false, fmt.Errorf("%w: %d",
+			eventstore.ErrInvalidPageLimit, limit)
+	}
+
+	var events eventstore.Events
+
+	err := s.retry(ctx, func() error {
+		// One more event than asked for tells whether there are more.
+		rows, _ := s.reader(ctx).Query(ctx, s.queries.listEventsPage,
+			pgx.NamedArgs{
+				"tenant_id":     tenantID(ctx),
+				"aggregate_id":  aggregateID,
+				"after_version": afterVersion,
+				"limit":         limit + 1,
+			})
+
+		var err error
+		events, err = pgx.CollectRows(rows, s.collectEvent)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(events) == 0 {
+		if err := s.checkStreamExists(ctx, aggregateID); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if len(events) > limit {
+		return events[:limit], true, nil
+	}
+
+	return events, false, nil
+}
+
+func (s *Store) ListEventsRange(
+	ctx context.Context, aggregateID string, fromVersion int, toVersion int,
+) (eventstore.Events, error) {
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.ListEventsRange",
+		aggregateID)
+	defer span.End()
+
+	if toVersion == 0 {
+		toVersion = math.MaxInt32
+	}
+	if fromVersion > toVersion {
+		return nil, eventstore.ErrInvalidVersionRange
+	}
+
+	var events eventstore.Events
+
+	err := s.retry(ctx, func() error {
+		rows, _ := s.reader(ctx).Query(ctx, s.queries.listEventsRange,
+			pgx.NamedArgs{
+				"tenant_id":    tenantID(ctx),
+				"aggregate_id": aggregateID,
+				"from_version": fromVersion,
+				"to_version":   toVersion,
+			})
+
+		var err error
+		events, err = pgx.CollectRows(rows, s.collectEvent)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		if err := s.checkStreamExists(ctx, aggregateID); err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
+
+// checkStreamExists fails with ErrStreamNotFound if the aggregate has no
+// events, for reads that cannot tell that from finding none in the range
+// they were asked for.
+func (s *Store) checkStreamExists(
+	ctx context.Context, aggregateID string,
+) error {
+	var exists bool
+	if err := s.retry(ctx, func() error {
+		return s.reader(ctx).QueryRow(ctx, s.queries.streamExists,
+			pgx.NamedArgs{
+				"tenant_id":    tenantID(ctx),
+				"aggregate_id": aggregateID,
+			}).Scan(&exists)
+	}); err != nil {
+		return fmt.Errorf("check stream exists: %w", err)
+	}
+
+	if !exists {
+		return fmt.Errorf("%w: %s", eventstore.ErrStreamNotFound, aggregateID)
+	}
+
+	return nil
+}
+
+// ListAllEvents only returns events that have already been sequenced, which
+// happens shortly after they are saved.
+func (s *Store) ListAllEvents(
+	ctx context.Context, afterPosition int64, limit int,
+) (eventstore.Events, error) {
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.ListAllEvents", "")
+	defer span.End()
+
+	var limitArg *int
+	if limit > 0 {
+		limitArg = &limit
+	}
+
+	rows, _ := s.reader(ctx).Query(ctx, s.queries.listEventsAfterPosition,
+		pgx.NamedArgs{
+			"tenant_id":      tenantID(ctx),
+			"all_tenants":    eventstore.AllTenants(ctx),
+			"after_position": afterPosition,
+			"limit":          limitArg,
+		})
+
+	return pgx.CollectRows(rows, s.collectEvent)
+}
+
+// ListEventsByType only returns events that have already been sequenced,
+// like ListAllEvents.
+func (s *Store) ListEventsByType(
+	ctx context.Context, typeURLs []string, afterPosition int64, limit int,
+) (eventstore.Events, error) {
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.ListEventsByType", "")
+	defer span.End()
+
+	var limitArg *int
+	if limit > 0 {
+		limitArg = &limit
+	}
+
+	rows, _ := s.reader(ctx).Query(ctx, s.queries.listEventsByType,
+		pgx.NamedArgs{
+			"event_types":    typeURLs,
+			"tenant_id":      tenantID(ctx),
+			"all_tenants":    eventstore.AllTenants(ctx),
+			"after_position": afterPosition,
+			"limit":          limitArg,
+		})
+
+	return pgx.CollectRows(rows, s.collectEvent)
+}
+
+// ListEventsByMetadata only returns events that have already been sequenced,
+// like ListAllEvents. The filter is matched with JSONB containment, which
+// is served by the GIN index on the metadata column (es_events_metadata_idx).
+// The index narrows down the candidate rows, which are then sorted by
+// position, so selective filters are cheap while filters matching a large
+// share of the log cost about as much as a scan of it.
+func (s *Store) ListEventsByMetadata(
+	ctx context.Context, filter map[string]any, afterPosition int64, limit int,
+) (eventstore.Events, error) {
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.ListEventsByMetadata",
+		"")
+	defer span.End()
+
+	filterBytes, err := json.Marshal(filter)
+	if err != nil {
+		return nil, fmt.Errorf("marshal filter: %w", err)
+	}
+
+	var limitArg *int
+	if limit > 0 {
+		limitArg = &limit
+	}
+
+	rows, _ := s.reader(ctx).Query(ctx, s.queries.listEventsByMetadata,
+		pgx.NamedArgs{
+			"filter":         string(filterBytes),
+			"tenant_id":      tenantID(ctx),
+			"all_tenants":    eventstore.AllTenants(ctx),
+			"after_position": afterPosition,
+			"limit":          limitArg,
+		})
+
+	return pgx.CollectRows(rows, s.collectEvent)
+}
+
+func (s *Store) LastGlobalPosition(ctx context.Context) (int64, error) {
+	var position int64
+	if err := s.reader(ctx).QueryRow(
+		ctx, s.queries.selectLastSequenceNumber,
+	).Scan(&position); err != nil {
+		return 0, err
+	}
+
+	return position, nil
+}
+
+func (s *Store) ListEventsUntil(
+	ctx context.Context, aggregateID string, until time.Time,
+) (eventstore.Events, error) {
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.ListEventsUntil",
+		aggregateID)
+	defer span.End()
+
+	rows, _ := s.reader(ctx).Query(ctx, s.queries.listEventsUntil, pgx.NamedArgs{
+		"tenant_id":    tenantID(ctx),
 		"aggregate_id": aggregateID,
+		"until":        until,
 	})
 
 	return pgx.CollectRows(rows, s.collectEvent)
 }
 
+// ListEventsByCorrelation returns all events sharing the correlation ID,
+// including the event whose ID seeded it, in causal order. Events are
+// ordered by causal depth first: events whose causation ID does not refer to
+// another event of the correlation have depth 0, and every other event is
+// one level deeper than the event that caused it. Events at the same depth
+// are ordered by global position, with events not sequenced yet coming last,
+// then by aggregate ID and aggregate version.
+func (s *Store) ListEventsByCorrelation(
+	ctx context.Context, correlationID string,
+) (eventstore.Events, error) {
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.ListEventsByCorrelation",
+		"")
+	span.SetAttribute("eventsource.correlation_id", correlationID)
+	defer span.End()
+
+	rows, _ := s.reader(ctx).Query(ctx, s.queries.listEventsByCorrelation,
+		pgx.NamedArgs{
+			"tenant_id":      tenantID(ctx),
+			"correlation_id": correlationID,
+		})
+
+	return pgx.CollectRows(rows, s.collectEvent)
+}
+
+// GetEventByID looks the event up by the primary key of the events table.
+func (s *Store) GetEventByID(
+	ctx context.Context, eventID string,
+) (*eventstore.Event, error) {
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.GetEventByID", "")
+	span.SetAttribute("eventsource.event_id", eventID)
+	defer span.End()
+
+	rows, _ := s.reader(ctx).Query(ctx, s.queries.getEventByID,
+		pgx.NamedArgs{
+			"tenant_id": tenantID(ctx),
+			"id":        eventID,
+		})
+
+	event, err := pgx.CollectExactlyOneRow(rows, s.collectEvent)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s",
+				eventstore.ErrEventDoesNotExist, eventID)
+		}
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (s *Store) CurrentVersions(
+	ctx context.Context, aggregateIDs []string,
+) (map[string]int, error) {
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.CurrentVersions", "")
+	defer span.End()
+
+	versions := make(map[string]int, len(aggregateIDs))
+	for _, id := range aggregateIDs {
+		versions[id] = 0
+	}
+
+	rows, _ := s.reader(ctx).Query(ctx, s.queries.listAggregateVersions,
+		pgx.NamedArgs{
+			"tenant_id":     tenantID(ctx),
+			"aggregate_ids": aggregateIDs,
+		})
+
+	var id string
+	var version int
+	if _, err := pgx.ForEachRow(rows, []any{&id, &version}, func() error {
+		versions[id] = version
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (s *Store) AddTag(
+	ctx context.Context, aggregateID string, tag string,
+) error {
+	_, err := s.pool.Exec(ctx, s.queries.addAggregateTag, pgx.NamedArgs{
+		"tenant_id":    tenantID(ctx),
+		"aggregate_id": aggregateID,
+		"tag":          tag,
+	})
+	return err
+}
+
+func (s *Store) RemoveTag(
+	ctx context.Context, aggregateID string, tag string,
+) error {
+	_, err := s.pool.Exec(ctx, s.queries.removeAggregateTag, pgx.NamedArgs{
+		"tenant_id":    tenantID(ctx),
+		"aggregate_id": aggregateID,
+		"tag":          tag,
+	})
+	return err
+}
+
+func (s *Store) ListAggregateIDs(
+	ctx context.Context, aggregateType string, afterID string, limit int,
+) ([]string, error) {
+	var limitArg *int
+	if limit > 0 {
+		limitArg = &limit
+	}
+
+	rows, _ := s.reader(ctx).Query(ctx, s.queries.listAggregateIDs,
+		pgx.NamedArgs{
+			"tenant_id":      tenantID(ctx),
+			"aggregate_type": aggregateType,
+			"after_id":       afterID,
+			"limit":          limitArg,
+		})
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func (s *Store) ListAggregatesByTag(
+	ctx context.Context, tag string,
+) ([]string, error) {
+	rows, _ := s.reader(ctx).Query(ctx, s.queries.listAggregatesByTag,
+		pgx.NamedArgs{
+			"tenant_id": tenantID(ctx),
+			"tag":       tag,
+		})
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func (s *Store) SaveSnapshot(
+	ctx context.Context, aggregateID string, aggregateVersion int,
+	data *anypb.Any,
+) error {
+	dataBytes, err := protojson.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal data: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, s.queries.saveSnapshot, pgx.NamedArgs{
+		"tenant_id":         tenantID(ctx),
+		"aggregate_id":      aggregateID,
+		"aggregate_version": aggregateVersion,
+		"timestamp":         time.Now(),
+		"data":              dataBytes,
+	})
+	return err
+}
+
+func (s *Store) GetLatestSnapshot(
+	ctx context.Context, aggregateID string,
+) (*eventstore.Snapshot, error) {
+	var snapshot eventstore.Snapshot
+	var dataBytes []byte
+
+	if err := s.reader(ctx).QueryRow(ctx, s.queries.getLatestSnapshot,
+		pgx.NamedArgs{
+			"tenant_id":    tenantID(ctx),
+			"aggregate_id": aggregateID,
+		}).Scan(
+		&snapshot.AggregateID, &snapshot.AggregateVersion, &snapshot.Timestamp,
+		&dataBytes,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var data anypb.Any
+	if err := protojson.Unmarshal(dataBytes, &data); err != nil {
+		return nil, fmt.Errorf("unmarshal data: %w", err)
+	}
+	snapshot.Data = &data
+
+	return &snapshot, nil
+}
+
+func (s *Store) startSpan(
+	ctx context.Context, name string, aggregateID string,
+) (context.Context, eventstore.Span) {
+	ctx, span := s.config.tracer.Start(ctx, name)
+	if aggregateID != "" {
+		span.SetAttribute("eventsource.aggregate_id", aggregateID)
+	}
+	return ctx, span
+}
+
+// querier is implemented both by pools and by transactions.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// reader returns the caller's transaction if ctx carries one, see WithTx,
+// so that reads see the writes made in it, and the pool reads go to
+// otherwise.
+func (s *Store) reader(ctx context.Context) querier {
+	if tx, ok := txFromContext(ctx); ok {
+		return tx
+	}
+	return s.readPool(ctx)
+}
+
+func (s *Store) readPool(ctx context.Context) *pgxpool.Pool {
+	if s.config.readPool == nil || primaryReads(ctx) {
+		return s.pool
+	}
+	return s.config.readPool
+}
+
 func (s *Store) collectEvent(row pgx.CollectableRow) (*eventstore.Event, error) {
 	var id string
 	var aggregateID string
+	var aggregateType string
 	var aggregateVersion int
 	var timestamp time.Time
 	var metadataBytes []byte
-	var dataBytes []byte
+	var eventType string
+	var data []byte
+	var sequenceNumber *int64
+	var dataCompression string
+	var schemaVersion int
 
 	if err := row.Scan(
-		&id, &aggregateID, &aggregateVersion, &timestamp, &metadataBytes,
-		&dataBytes,
+		&id, &aggregateID, &aggregateType, &aggregateVersion, &timestamp,
+		&metadataBytes, &eventType, &data, &sequenceNumber, &dataCompression,
+		&schemaVersion,
 	); err != nil {
 		return nil, fmt.Errorf("scan row: %w", err)
 	}
 
+	data, err := s.decompress(id, dataCompression, data)
+	if err != nil {
+		return nil, err
+	}
+
 	var metadata eventstore.Metadata
 	if err := json.Unmarshal(metadataBytes, &metadata); err != nil {
 		return nil, fmt.Errorf("unmarshal metadata: %w", err)
 	}
 
-	var data anypb.Any
-	if err := protojson.Unmarshal(dataBytes, &data); err != nil {
-		return nil, fmt.Errorf("unmarshal data: %w", err)
-	}
-
 	return &eventstore.Event{
 		ID:               id,
 		AggregateID:      aggregateID,
 		AggregateVersion: aggregateVersion,
 		Timestamp:        timestamp,
 		Metadata:         metadata,
-		Data:             &data,
+		Type:             eventType,
+		Data:             data,
+		SchemaVersion:    schemaVersion,
+		GlobalPosition:   valueOrZero(sequenceNumber),
+		AggregateType:    aggregateType,
 	}, nil
 }
 
+// tenantID returns the tenant that the aggregates accessed with ctx belong
+// to, taken from the X-Tenant-ID metadata. Aggregates saved without a tenant
+// belong to the empty one.
+func tenantID(ctx context.Context) string {
+	return eventstore.MetadataFromContext(ctx).TenantID()
+}
+
+func valueOrZero[T any](p *T) T {
+	var v T
+	if p != nil {
+		v = *p
+	}
+	return v
+}
+
 func (s *Store) SaveEvents(
 	ctx context.Context, aggregateID string, expectedAggregateVersion int,
 	events eventstore.Events,
 ) error {
-	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
-		if expectedAggregateVersion == 0 {
-			if _, err := tx.Exec(ctx, createAggregateQuery, pgx.NamedArgs{
-				"aggregate_id": aggregateID,
-			}); err != nil {
-				return fmt.Errorf("create aggregate: %w", err)
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.SaveEvents",
+		aggregateID)
+	defer span.End()
+
+	start := time.Now()
+
+	if err := s.retry(ctx, func() error {
+		return s.beginSaveTxFunc(ctx, func(tx pgx.Tx) error {
+			if err := s.saveAggregateEvents(
+				ctx, tx, aggregateID, expectedAggregateVersion, events,
+			); err != nil {
+				return err
+			}
+
+			if _, err := tx.Exec(ctx, s.queries.notifyEventsInserted,
+				pgx.NamedArgs{
+					"channel": s.tables.channel("events.inserted"),
+				}); err != nil {
+				return fmt.Errorf("notify events inserted: %w", err)
 			}
+
+			return nil
+		})
+	}); err != nil {
+		if errors.Is(err, eventstore.ErrConcurrentUpdate) {
+			s.config.metrics.IncConcurrentUpdates("")
 		}
+		return err
+	}
 
-		newVersion := expectedAggregateVersion + len(events)
+	s.config.metrics.ObserveSave("", time.Since(start), len(events))
 
-		if ct, err := tx.Exec(ctx, updateAggregateVersionQuery, pgx.NamedArgs{
-			"aggregate_id":               aggregateID,
-			"expected_aggregate_version": expectedAggregateVersion,
-			"new_aggregate_version":      newVersion,
+	return nil
+}
+
+// SaveBatch saves the aggregates in the order of their IDs, so that
+// concurrent batches lock them in the same order.
+func (s *Store) SaveBatch(
+	ctx context.Context, batch []eventstore.AggregateEvents,
+) error {
+	ctx, span := s.startSpan(ctx, "eventstorepostgres.SaveBatch", "")
+	defer span.End()
+
+	batch = slices.Clone(batch)
+	slices.SortFunc(batch, func(a, b eventstore.AggregateEvents) int {
+		return cmp.Compare(a.AggregateID, b.AggregateID)
+	})
+
+	start := time.Now()
+
+	if err := s.retry(ctx, func() error {
+		return s.beginSaveTxFunc(ctx, func(tx pgx.Tx) error {
+			for _, ae := range batch {
+				if err := s.saveAggregateEvents(
+					ctx, tx, ae.AggregateID, ae.ExpectedAggregateVersion,
+					ae.Events,
+				); err != nil {
+					return fmt.Errorf("aggregate %s: %w", ae.AggregateID, err)
+				}
+			}
+
+			if _, err := tx.Exec(ctx, s.queries.notifyEventsInserted,
+				pgx.NamedArgs{
+					"channel": s.tables.channel("events.inserted"),
+				}); err != nil {
+				return fmt.Errorf("notify events inserted: %w", err)
+			}
+
+			return nil
+		})
+	}); err != nil {
+		if errors.Is(err, eventstore.ErrConcurrentUpdate) {
+			s.config.metrics.IncConcurrentUpdates("")
+		}
+		return err
+	}
+
+	events := 0
+	for _, ae := range batch {
+		events += len(ae.Events)
+	}
+	s.config.metrics.ObserveSave("", time.Since(start), events)
+
+	return nil
+}
+
+func (s *Store) saveAggregateEvents(
+	ctx context.Context, tx pgx.Tx, aggregateID string,
+	expectedAggregateVersion int, events eventstore.Events,
+) error {
+	if expectedAggregateVersion == 0 {
+		if _, err := tx.Exec(ctx, s.queries.createAggregate, pgx.NamedArgs{
+			"tenant_id":    tenantID(ctx),
+			"aggregate_id": aggregateID,
 		}); err != nil {
-			return fmt.Errorf("update aggregate version: %w", err)
-		} else if ct.RowsAffected() == 0 {
-			return eventstore.ErrConcurrentUpdate
+			return fmt.Errorf("create aggregate: %w", err)
+		}
+	}
+
+	newVersion := expectedAggregateVersion + len(events)
+
+	if ct, err := tx.Exec(ctx, s.queries.updateAggregateVersion, pgx.NamedArgs{
+		"tenant_id":                  tenantID(ctx),
+		"aggregate_id":               aggregateID,
+		"expected_aggregate_version": expectedAggregateVersion,
+		"new_aggregate_version":      newVersion,
+	}); err != nil {
+		return fmt.Errorf("update aggregate version: %w", err)
+	} else if ct.RowsAffected() == 0 {
+		return eventstore.ErrConcurrentUpdate
+	}
+
+	if err := s.saveEvents(ctx, tx, events); err != nil {
+		return fmt.Errorf("save events: %w", err)
+	}
+
+	return nil
+}
+
+// IngestEvents appends events that already carry IDs assigned upstream.
+// Events whose ID has already been saved for the aggregate are skipped, so
+// re-submitting them is a no-op; an ID saved for another aggregate fails
+// with eventstore.ErrDuplicateEventID. Versions are assigned by the store:
+// the events that are not skipped get consecutive versions following the
+// current version of the aggregate, in the order given, and their
+// AggregateVersion fields are overwritten accordingly. It returns the
+// number of events actually saved.
+func (s *Store) IngestEvents(
+	ctx context.Context, aggregateID string, events eventstore.Events,
+) (int, error) {
+	var ingested int
+
+	if err := s.beginSaveTxFunc(ctx, func(tx pgx.Tx) error {
+		ingested = 0
+
+		if _, err := tx.Exec(ctx, s.queries.createAggregate, pgx.NamedArgs{
+			"tenant_id":    tenantID(ctx),
+			"aggregate_id": aggregateID,
+		}); err != nil {
+			return fmt.Errorf("create aggregate: %w", err)
+		}
+
+		var version int
+		if err := tx.QueryRow(ctx, s.queries.lockAggregate, pgx.NamedArgs{
+			"tenant_id":    tenantID(ctx),
+			"aggregate_id": aggregateID,
+		}).Scan(&version); err != nil {
+			return fmt.Errorf("lock aggregate: %w", err)
+		}
+
+		eventIDs := make([]string, 0, len(events))
+		for _, event := range events {
+			eventIDs = append(eventIDs, event.ID)
+		}
+
+		type stream struct{ tenantID, aggregateID string }
+		savedEventIDs := make(map[string]stream, len(events))
+		rows, _ := tx.Query(ctx, s.queries.listSavedEventIDs, pgx.NamedArgs{
+			"event_ids": eventIDs,
+		})
+		var eventID string
+		var eventStream stream
+		if _, err := pgx.ForEachRow(rows, []any{
+			&eventID, &eventStream.tenantID, &eventStream.aggregateID,
+		}, func() error {
+			savedEventIDs[eventID] = eventStream
+			return nil
+		}); err != nil {
+			return fmt.Errorf("list saved event IDs: %w", err)
 		}
 
+		newVersion := version
 		for i, event := range events {
+			if savedStream, ok := savedEventIDs[event.ID]; ok {
+				if savedStream != (stream{tenantID(ctx), aggregateID}) {
+					return fmt.Errorf("%d: %w: %s", i,
+						eventstore.ErrDuplicateEventID, event.ID)
+				}
+				continue
+			}
+			newVersion++
+			event.AggregateID = aggregateID
+			event.AggregateVersion = newVersion
 			if err := s.saveEvent(ctx, tx, event); err != nil {
 				return fmt.Errorf("%d: %w", i, err)
 			}
+			savedEventIDs[event.ID] = stream{tenantID(ctx), aggregateID}
+			ingested++
 		}
 
-		if _, err := tx.Exec(ctx, notifyEventsInsertedQuery); err != nil {
+		if ingested == 0 {
+			return nil
+		}
+
+		if _, err := tx.Exec(ctx, s.queries.updateAggregateVersion, pgx.NamedArgs{
+			"tenant_id":                  tenantID(ctx),
+			"aggregate_id":               aggregateID,
+			"expected_aggregate_version": version,
+			"new_aggregate_version":      newVersion,
+		}); err != nil {
+			return fmt.Errorf("update aggregate version: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, s.queries.notifyEventsInserted, pgx.NamedArgs{
+			"channel": s.tables.channel("events.inserted"),
+		}); err != nil {
 			return fmt.Errorf("notify events inserted: %w", err)
 		}
 
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return ingested, nil
+}
+
+// DeleteStream deletes the events of the aggregate along with its tags and
+// snapshots. Subscriptions that have not processed the events yet never
+// will, and the sequence numbers of the events are left as gaps.
+func (s *Store) DeleteStream(ctx context.Context, aggregateID string) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		args := pgx.NamedArgs{
+			"tenant_id":    tenantID(ctx),
+			"aggregate_id": aggregateID,
+		}
+
+		var version int
+		if err := tx.QueryRow(ctx, s.queries.lockAggregate, args).Scan(
+			&version,
+		); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return eventstore.ErrStreamNotFound
+			}
+			return fmt.Errorf("lock aggregate: %w", err)
+		}
+		if version == 0 {
+			return eventstore.ErrStreamNotFound
+		}
+
+		if _, err := tx.Exec(
+			ctx, s.queries.deleteAggregateSubscriptionBacklogs, args,
+		); err != nil {
+			return fmt.Errorf("delete subscription backlogs: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, s.queries.deleteAggregateEvents, args); err != nil {
+			return fmt.Errorf("delete events: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, s.queries.deleteAggregateTags, args); err != nil {
+			return fmt.Errorf("delete tags: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, s.queries.deleteAggregateSnapshots, args); err != nil {
+			return fmt.Errorf("delete snapshots: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, s.queries.deleteAggregate, args); err != nil {
+			return fmt.Errorf("delete aggregate: %w", err)
+		}
+
 		return nil
 	})
 }
 
+// saveEvents inserts events with a single multi-row INSERT instead of one
+// statement per event, so saving a batch costs one round trip to the
+// database regardless of its size. The aggregate version check is done by
+// the caller within the same transaction.
+func (s *Store) saveEvents(
+	ctx context.Context, tx pgx.Tx, events eventstore.Events,
+) error {
+	var (
+		ids               = make([]string, len(events))
+		aggregateIDs      = make([]string, len(events))
+		aggregateTypes    = make([]string, len(events))
+		aggregateVersions = make([]int, len(events))
+		timestamps        = make([]time.Time, len(events))
+		metadata          = make([]string, len(events))
+		eventTypes        = make([]string, len(events))
+		data              = make([][]byte, len(events))
+		dataCompressions  = make([]string, len(events))
+		schemaVersions    = make([]int, len(events))
+	)
+
+	for i, event := range events {
+		metadataBytes, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return fmt.Errorf("%d: marshal metadata: %w", i, err)
+		}
+		ids[i] = event.ID
+		aggregateIDs[i] = event.AggregateID
+		aggregateTypes[i] = event.AggregateType
+		aggregateVersions[i] = event.AggregateVersion
+		timestamps[i] = event.Timestamp
+		metadata[i] = string(metadataBytes)
+		eventTypes[i] = event.Type
+		schemaVersions[i] = event.EffectiveSchemaVersion()
+		data[i], dataCompressions[i], err = s.compress(event)
+		if err != nil {
+			return fmt.Errorf("%d: %w", i, err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, s.queries.saveEvents, pgx.NamedArgs{
+		"tenant_id":          tenantID(ctx),
+		"ids":                ids,
+		"aggregate_ids":      aggregateIDs,
+		"aggregate_types":    aggregateTypes,
+		"aggregate_versions": aggregateVersions,
+		"timestamps":         timestamps,
+		"metadata":           metadata,
+		"event_types":        eventTypes,
+		"data":               data,
+		"data_compressions":  dataCompressions,
+		"schema_versions":    schemaVersions,
+	}); err != nil {
+		var pgErr *pgconn.PgError
+		if isUniqueViolation(err, s.tables.constraint("events_pkey")) && errors.As(err, &pgErr) {
+			return fmt.Errorf("%w: %s", eventstore.ErrDuplicateEventID, pgErr.Detail)
+		}
+		if s.isVersionViolation(err) {
+			return eventstore.ErrConcurrentUpdate
+		}
+		return err
+	}
+
+	if s.config.outboxRouter != nil {
+		if err := s.saveOutboxMessages(ctx, tx, events); err != nil {
+			return fmt.Errorf("save outbox messages: %w", err)
+		}
+	}
+
+	if hook := s.config.saveEventHook; hook != nil {
+		for i, event := range events {
+			if err := hook(ctx, tx, event); err != nil {
+				return fmt.Errorf("%d: save event hook: %w", i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (s *Store) saveEvent(
 	ctx context.Context, tx pgx.Tx, event *eventstore.Event,
 ) error {
-	dataBytes, err := protojson.Marshal(event.Data)
+	metadataBytes, err := json.Marshal(event.Metadata)
 	if err != nil {
-		return fmt.Errorf("marshal data: %w", err)
+		return fmt.Errorf("marshal metadata: %w", err)
 	}
 
-	metadataBytes, err := json.Marshal(event.Metadata)
+	data, dataCompression, err := s.compress(event)
 	if err != nil {
-		return fmt.Errorf("marshal metadata: %w", err)
+		return err
 	}
 
-	if _, err := tx.Exec(ctx, saveEventQuery, pgx.NamedArgs{
+	if _, err := tx.Exec(ctx, s.queries.saveEvent, pgx.NamedArgs{
+		"tenant_id":         tenantID(ctx),
 		"id":                event.ID,
 		"aggregate_id":      event.AggregateID,
+		"aggregate_type":    event.AggregateType,
 		"aggregate_version": event.AggregateVersion,
 		"timestamp":         event.Timestamp,
 		"metadata":          string(metadataBytes),
-		"data":              string(dataBytes),
+		"event_type":        event.Type,
+		"data":              data,
+		"data_compression":  dataCompression,
+		"schema_version":    event.EffectiveSchemaVersion(),
 	}); err != nil {
+		if isUniqueViolation(err, s.tables.constraint("events_pkey")) {
+			return fmt.Errorf("%w: %s", eventstore.ErrDuplicateEventID, event.ID)
+		}
+		if s.isVersionViolation(err) {
+			return eventstore.ErrConcurrentUpdate
+		}
 		return err
 	}
 
+	if s.config.outboxRouter != nil {
+		if err := s.saveOutboxMessages(
+			ctx, tx, eventstore.Events{event},
+		); err != nil {
+			return fmt.Errorf("save outbox message: %w", err)
+		}
+	}
+
 	if hook := s.config.saveEventHook; hook != nil {
 		if err := hook(ctx, tx, event); err != nil {
 			return fmt.Errorf("save event hook: %w", err)
@@ -352,3 +1535,11 @@ func (s *Store) saveEvent(
 
 	return nil
 }
+
+// isVersionViolation reports whether err is an attempt to save an event at
+// a version another one has taken, which is a genuine version conflict
+// rather than a retryable failure.
+func (s *Store) isVersionViolation(err error) bool {
+	return isUniqueViolation(err, s.tables.constraint(
+		"events_tenant_id_aggregate_id_aggregate_version_key"))
+}
@@ -0,0 +1,912 @@
+package eventstorepostgres
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoretest"
+)
+
+// testDatabaseURLEnv names the variable holding the URL of the database the
+// tests run against. The tests are skipped if it is not set. Every test
+// migrates a schema of its own and drops it when done.
+const testDatabaseURLEnv = "EVENTSTOREPOSTGRES_TEST_DATABASE_URL"
+
+var testSchemaSeq atomic.Int64
+
+type testDatabase struct {
+	pool   *pgxpool.Pool
+	schema string
+}
+
+func newTestDatabase(t testing.TB) testDatabase {
+	t.Helper()
+
+	url := os.Getenv(testDatabaseURLEnv)
+	if url == "" {
+		t.Skipf("%s not set", testDatabaseURLEnv)
+	}
+
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	schema := fmt.Sprintf("es_test_%d_%d",
+		time.Now().UnixNano(), testSchemaSeq.Add(1))
+	if _, err := pool.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := pool.Exec(
+			context.Background(), "DROP SCHEMA "+schema+" CASCADE",
+		); err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+	})
+
+	migrate(t, pool, schema)
+
+	return testDatabase{pool: pool, schema: schema}
+}
+
+// migrate applies the up migrations in order with the search path set to
+// schema, as WithSchema expects.
+func migrate(t testing.TB, pool *pgxpool.Pool, schema string) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	files, err := filepath.Glob("migrations/*.up.sql")
+	if err != nil {
+		t.Fatalf("list migrations: %v", err)
+	}
+	slices.Sort(files)
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SET search_path TO "+schema); err != nil {
+		t.Fatalf("set search path: %v", err)
+	}
+	defer conn.Exec(ctx, "RESET search_path")
+
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read migration: %v", err)
+		}
+		if _, err := conn.Exec(ctx, string(migration)); err != nil {
+			t.Fatalf("migration %s: %v", file, err)
+		}
+	}
+}
+
+func (db testDatabase) start(t testing.TB, opts ...option) *Store {
+	t.Helper()
+
+	s := Start(db.pool, append([]option{WithSchema(db.schema)}, opts...)...)
+	t.Cleanup(s.Stop)
+
+	return s
+}
+
+func newTestEvent(
+	aggregateID string, version int, metadata eventstore.Metadata,
+	data []byte,
+) *eventstore.Event {
+	return &eventstore.Event{
+		ID:               fmt.Sprintf("%s-%d", aggregateID, version),
+		AggregateID:      aggregateID,
+		AggregateVersion: version,
+		Timestamp:        time.Now().UTC().Truncate(time.Microsecond),
+		Metadata:         metadata,
+		Type:             "test.Event",
+		Data:             data,
+	}
+}
+
+func TestListEventsByCorrelationCompressed(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+	plain := db.start(t)
+	compressing := db.start(t, WithCompression(GzipCompression{}))
+
+	rootData := []byte("root")
+	root := newTestEvent("a", 1, eventstore.Metadata{}, rootData)
+	if err := plain.SaveEvents(ctx, "a", 0, eventstore.Events{root}); err != nil {
+		t.Fatalf("save root: %v", err)
+	}
+
+	effectData := []byte(strings.Repeat("effect ", 100))
+	effect := newTestEvent("b", 1, eventstore.Metadata{
+		eventstore.CorrelationID: root.ID,
+		eventstore.CausationID:   root.ID,
+	}, effectData)
+	if err := compressing.SaveEvents(
+		ctx, "b", 0, eventstore.Events{effect},
+	); err != nil {
+		t.Fatalf("save effect: %v", err)
+	}
+
+	for id, want := range map[string]string{root.ID: "", effect.ID: "gzip"} {
+		var got string
+		if err := db.pool.QueryRow(ctx,
+			"SELECT data_compression FROM "+db.schema+".es_events WHERE id = $1",
+			id,
+		).Scan(&got); err != nil {
+			t.Fatalf("select compression of %s: %v", id, err)
+		}
+		if got != want {
+			t.Fatalf("compression of %s: got %q, want %q", id, got, want)
+		}
+	}
+
+	for _, s := range []*Store{plain, compressing} {
+		events, err := s.ListEventsByCorrelation(ctx, root.ID)
+		if err != nil {
+			t.Fatalf("list events by correlation: %v", err)
+		}
+		if len(events) != 2 {
+			t.Fatalf("got %d events, want 2", len(events))
+		}
+		if events[0].ID != root.ID || string(events[0].Data) != string(rootData) {
+			t.Fatalf("first event: got %s %q", events[0].ID, events[0].Data)
+		}
+		if events[1].ID != effect.ID || string(events[1].Data) != string(effectData) {
+			t.Fatalf("second event: got %s %q", events[1].ID, events[1].Data)
+		}
+	}
+}
+
+func TestListEventsByCorrelation(t *testing.T) {
+	eventstoretest.RunCorrelationTraceCase(t, newTestDatabase(t).start(t))
+}
+
+func TestEventTypeColumn(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+	s := db.start(t)
+
+	const (
+		events = 2000
+		every  = 50
+		sparse = "type.googleapis.com/test.Sparse"
+	)
+	for i := 0; i < events; i += every {
+		aggregateID := fmt.Sprintf("a-%d", i)
+		batch := newTestEvents(aggregateID, every, []byte("{}"))
+		batch[every-1].Type = sparse
+		if err := s.SaveEvents(ctx, aggregateID, 0, batch); err != nil {
+			t.Fatalf("save events: %v", err)
+		}
+	}
+	waitSequenced(t, s, events)
+
+	var count int
+	if err := db.pool.QueryRow(ctx,
+		"SELECT count(*) FROM "+db.schema+".es_events WHERE event_type = $1",
+		sparse,
+	).Scan(&count); err != nil {
+		t.Fatalf("count events by type column: %v", err)
+	}
+	if count != events/every {
+		t.Fatalf("events with type column %s: got %d, want %d",
+			sparse, count, events/every)
+	}
+
+	var indexDef string
+	if err := db.pool.QueryRow(ctx,
+		"SELECT indexdef FROM pg_indexes "+
+			"WHERE schemaname = $1 AND indexname = 'es_events_event_type_idx'",
+		db.schema,
+	).Scan(&indexDef); err != nil {
+		t.Fatalf("select index: %v", err)
+	}
+	if !strings.Contains(indexDef, "(event_type, sequence_number)") {
+		t.Fatalf("index: got %s", indexDef)
+	}
+
+	if _, err := db.pool.Exec(ctx, "ANALYZE "+db.schema+".es_events"); err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	rows, _ := db.pool.Query(ctx, "EXPLAIN "+s.queries.listEventsByType,
+		pgx.NamedArgs{
+			"event_types":    []string{sparse},
+			"tenant_id":      "",
+			"all_tenants":    false,
+			"after_position": 0,
+			"limit":          nil,
+		})
+	plan, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+	if !strings.Contains(strings.Join(plan, "\n"), "es_events_event_type_idx") {
+		t.Fatalf("plan does not use es_events_event_type_idx:\n%s",
+			strings.Join(plan, "\n"))
+	}
+
+	listed, err := s.ListEventsByType(ctx, []string{sparse}, 0, 0)
+	if err != nil {
+		t.Fatalf("list events by type: %v", err)
+	}
+	if len(listed) != events/every {
+		t.Fatalf("listed %d events, want %d", len(listed), events/every)
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	eventstoretest.RunTenantIsolationCase(t, newTestDatabase(t).start(t))
+}
+
+func TestSchemaVersionIsStored(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+	s := db.start(t)
+
+	versioned := newTestEvent("a", 1, eventstore.Metadata{}, nil)
+	versioned.SchemaVersion = 3
+	legacy := newTestEvent("a", 2, eventstore.Metadata{
+		eventstore.SchemaVersion: 2,
+	}, nil)
+	unversioned := newTestEvent("a", 3, eventstore.Metadata{}, nil)
+	if err := s.SaveEvents(ctx, "a", 0, eventstore.Events{
+		versioned, legacy, unversioned,
+	}); err != nil {
+		t.Fatalf("save events: %v", err)
+	}
+
+	events, err := s.ListEvents(ctx, "a")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	var got []int
+	for _, event := range events {
+		got = append(got, event.SchemaVersion)
+	}
+	if want := []int{3, 2, 1}; !slices.Equal(got, want) {
+		t.Fatalf("schema versions: got %v, want %v", got, want)
+	}
+
+	rows, _ := db.pool.Query(ctx, "SELECT schema_version FROM "+db.schema+
+		".es_events ORDER BY aggregate_version")
+	stored, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		t.Fatalf("select schema versions: %v", err)
+	}
+	if !slices.Equal(stored, got) {
+		t.Fatalf("stored schema versions: got %v, want %v", stored, got)
+	}
+}
+
+func TestListEventsPageEndingAtLimit(t *testing.T) {
+	ctx := context.Background()
+	s := newTestDatabase(t).start(t)
+	for version := 1; version <= 6; version++ {
+		if err := s.SaveEvents(ctx, "a", version-1, eventstore.Events{
+			newTestEvent("a", version, eventstore.Metadata{}, nil),
+		}); err != nil {
+			t.Fatalf("save event %d: %v", version, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		afterVersion int
+		want         []int
+		more         bool
+	}{
+		{0, []int{1, 2, 3}, true},
+		{3, []int{4, 5, 6}, false},
+		{6, nil, false},
+	} {
+		events, more, err := s.ListEventsPage(ctx, "a", tc.afterVersion, 3)
+		if err != nil {
+			t.Fatalf("list page after %d: %v", tc.afterVersion, err)
+		}
+		var got []int
+		for _, event := range events {
+			got = append(got, event.AggregateVersion)
+		}
+		if !slices.Equal(got, tc.want) || more != tc.more {
+			t.Fatalf("page after %d: got %v and more %t, want %v and %t",
+				tc.afterVersion, got, more, tc.want, tc.more)
+		}
+	}
+}
+
+// saveConcurrently makes n saves of an event to the same aggregate at
+// version 0 at once, and returns the errors of those that failed.
+func saveConcurrently(t testing.TB, s *Store, aggregateID string, n int) []error {
+	t.Helper()
+
+	ctx := context.Background()
+	start := make(chan struct{})
+	errs := make(chan error, n)
+	for i := range n {
+		go func() {
+			event := newTestEvent(aggregateID, 1, eventstore.Metadata{}, nil)
+			event.ID = fmt.Sprintf("%s-writer-%d", aggregateID, i)
+			<-start
+			errs <- s.SaveEvents(ctx, aggregateID, 0, eventstore.Events{event})
+		}()
+	}
+	close(start)
+
+	var failed []error
+	for range n {
+		if err := <-errs; err != nil {
+			failed = append(failed, err)
+		}
+	}
+
+	events, err := s.ListEvents(ctx, aggregateID)
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) != n-len(failed) {
+		t.Fatalf("got %d events for %d successful saves",
+			len(events), n-len(failed))
+	}
+
+	return failed
+}
+
+func TestConcurrentSavesAtSameVersion(t *testing.T) {
+	const writers = 10
+
+	s := newTestDatabase(t).start(t)
+
+	failed := saveConcurrently(t, s, "a", writers)
+	if len(failed) != writers-1 {
+		t.Fatalf("%d saves failed, want %d", len(failed), writers-1)
+	}
+	for _, err := range failed {
+		if !errors.Is(err, eventstore.ErrConcurrentUpdate) {
+			t.Fatalf("save: got %v, want %v", err, eventstore.ErrConcurrentUpdate)
+		}
+	}
+}
+
+func TestConcurrentSavesUnderEachIsolationLevel(t *testing.T) {
+	const writers = 10
+
+	db := newTestDatabase(t)
+
+	for _, level := range []pgx.TxIsoLevel{
+		pgx.ReadCommitted, pgx.RepeatableRead, pgx.Serializable,
+	} {
+		t.Run(string(level), func(t *testing.T) {
+			s := db.start(t, WithSaveIsolationLevel(level))
+			aggregateID := strings.ReplaceAll(string(level), " ", "_")
+
+			failed := saveConcurrently(t, s, aggregateID, writers)
+			if len(failed) != writers-1 {
+				t.Fatalf("%d saves failed, want %d", len(failed), writers-1)
+			}
+			for _, err := range failed {
+				// Serialization failures only surface if they persist
+				// through the retries.
+				if !errors.Is(err, eventstore.ErrConcurrentUpdate) &&
+					!(level != pgx.ReadCommitted &&
+						errors.Is(err, ErrSerialization)) {
+					t.Fatalf("save: got %v, want %v",
+						err, eventstore.ErrConcurrentUpdate)
+				}
+			}
+		})
+	}
+}
+
+func TestReadsAreTraced(t *testing.T) {
+	ctx := context.Background()
+	tracer := &eventstoretest.RecordingTracer{}
+	s := newTestDatabase(t).start(t, WithTracer(tracer))
+
+	event := newTestEvent("a", 1, eventstore.Metadata{}, nil)
+	if err := s.SaveEvents(ctx, "a", 0, eventstore.Events{event}); err != nil {
+		t.Fatalf("save events: %v", err)
+	}
+
+	reads := map[string]func() error{
+		"ListAllEvents": func() error {
+			_, err := s.ListAllEvents(ctx, 0, 0)
+			return err
+		},
+		"ListEventsByType": func() error {
+			_, err := s.ListEventsByType(ctx, []string{"test.Event"}, 0, 0)
+			return err
+		},
+		"ListEventsByMetadata": func() error {
+			_, err := s.ListEventsByMetadata(ctx, map[string]any{}, 0, 0)
+			return err
+		},
+		"ListEventsUntil": func() error {
+			_, err := s.ListEventsUntil(ctx, "a", time.Now())
+			return err
+		},
+		"ListEventsByCorrelation": func() error {
+			_, err := s.ListEventsByCorrelation(ctx, event.ID)
+			return err
+		},
+		"GetEventByID": func() error {
+			_, err := s.GetEventByID(ctx, event.ID)
+			return err
+		},
+		"CurrentVersions": func() error {
+			_, err := s.CurrentVersions(ctx, []string{"a"})
+			return err
+		},
+	}
+	for name, read := range reads {
+		if err := read(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		span, ok := tracer.Span("eventstorepostgres." + name)
+		if !ok || !span.Ended {
+			t.Fatalf("%s: got span %+v, ended span expected", name, span)
+		}
+	}
+}
+
+// recordingMetrics counts the saves, saved events and concurrent updates
+// it observes.
+type recordingMetrics struct {
+	eventstore.NopMetricsCollector
+	mu                sync.Mutex
+	saves             int
+	events            int
+	concurrentUpdates int
+}
+
+func (m *recordingMetrics) ObserveSave(_ string, _ time.Duration, events int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saves++
+	m.events += events
+}
+
+func (m *recordingMetrics) IncConcurrentUpdates(string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.concurrentUpdates++
+}
+
+func TestSaveBatchRecordsMetrics(t *testing.T) {
+	ctx := context.Background()
+	metrics := &recordingMetrics{}
+	s := newTestDatabase(t).start(t, WithMetricsCollector(metrics))
+
+	batch := []eventstore.AggregateEvents{
+		{AggregateID: "a", Events: newTestEvents("a", 2, nil)},
+		{AggregateID: "b", Events: newTestEvents("b", 1, nil)},
+	}
+	if err := s.SaveBatch(ctx, batch); err != nil {
+		t.Fatalf("save batch: %v", err)
+	}
+	if err := s.SaveBatch(ctx, batch); !errors.Is(
+		err, eventstore.ErrConcurrentUpdate,
+	) {
+		t.Fatalf("save batch again: got %v, want %v",
+			err, eventstore.ErrConcurrentUpdate)
+	}
+
+	if metrics.saves != 1 || metrics.events != 3 ||
+		metrics.concurrentUpdates != 1 {
+		t.Fatalf("got %d saves of %d events and %d concurrent updates, "+
+			"want 1 save of 3 events and 1 concurrent update",
+			metrics.saves, metrics.events, metrics.concurrentUpdates)
+	}
+}
+
+func TestReadsInCallerTxSeeItsWrites(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+	s := db.start(t)
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer tx.Rollback(ctx)
+	txCtx := WithTx(ctx, tx)
+
+	if err := s.SaveEvents(txCtx, "a", 0, newTestEvents("a", 2, nil)); err != nil {
+		t.Fatalf("save events: %v", err)
+	}
+
+	// Reading twice checks that the cursor of the first read is closed.
+	for range 2 {
+		events, err := s.ListEvents(txCtx, "a")
+		if err != nil {
+			t.Fatalf("list events in tx: %v", err)
+		}
+		if len(events) != 2 {
+			t.Fatalf("listed %d events in tx, want 2", len(events))
+		}
+	}
+	versions, err := s.CurrentVersions(txCtx, []string{"a"})
+	if err != nil {
+		t.Fatalf("current versions in tx: %v", err)
+	}
+	if versions["a"] != 2 {
+		t.Fatalf("version in tx: got %d, want 2", versions["a"])
+	}
+
+	if _, err := s.ListEvents(ctx, "a"); !errors.Is(
+		err, eventstore.ErrStreamNotFound,
+	) {
+		t.Fatalf("list uncommitted events outside tx: got %v, want %v",
+			err, eventstore.ErrStreamNotFound)
+	}
+}
+
+func TestReadsOfMissingStreamReturnNotFound(t *testing.T) {
+	ctx := context.Background()
+	s := newTestDatabase(t).start(t)
+
+	if err := s.SaveEvents(ctx, "a", 0, newTestEvents("a", 2, nil)); err != nil {
+		t.Fatalf("save events: %v", err)
+	}
+
+	for name, read := range map[string]func(id string) error{
+		"ListEvents": func(id string) error {
+			_, err := s.ListEvents(ctx, id)
+			return err
+		},
+		"ListEventsPage": func(id string) error {
+			_, _, err := s.ListEventsPage(ctx, id, 5, 10)
+			return err
+		},
+		"ListEventsRange": func(id string) error {
+			_, err := s.ListEventsRange(ctx, id, 5, 10)
+			return err
+		},
+		"StreamEvents": func(id string) error {
+			for _, err := range s.StreamEvents(ctx, id) {
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	} {
+		if err := read("missing"); !errors.Is(err, eventstore.ErrStreamNotFound) {
+			t.Errorf("%s of missing stream: got %v, want %v",
+				name, err, eventstore.ErrStreamNotFound)
+		}
+		if err := read("a"); err != nil {
+			t.Errorf("%s past the end of existing stream: %v", name, err)
+		}
+	}
+}
+
+var benchAggregateSeq atomic.Int64
+
+// newBenchAggregateID returns an aggregate ID not used by any benchmark run
+// so far, as benchmark functions are run several times on the same schema.
+func newBenchAggregateID() string {
+	return fmt.Sprintf("bench-%d", benchAggregateSeq.Add(1))
+}
+
+func newTestEvents(aggregateID string, n int, data []byte) eventstore.Events {
+	events := make(eventstore.Events, 0, n)
+	for version := 1; version <= n; version++ {
+		events = append(events,
+			newTestEvent(aggregateID, version, eventstore.Metadata{}, data))
+	}
+	return events
+}
+
+// BenchmarkSaveEvents compares saving the events of an aggregate in one
+// call, which inserts them with a single statement, with saving them one
+// call at a time, which is what every save did before.
+func BenchmarkSaveEvents(b *testing.B) {
+	ctx := context.Background()
+	s := newTestDatabase(b).start(b)
+	data := []byte(`{"value": "benchmark"}`)
+
+	for _, n := range []int{1, 10, 50} {
+		b.Run(fmt.Sprintf("events=%d/batch", n), func(b *testing.B) {
+			for range b.N {
+				aggregateID := newBenchAggregateID()
+				if err := s.SaveEvents(
+					ctx, aggregateID, 0, newTestEvents(aggregateID, n, data),
+				); err != nil {
+					b.Fatalf("save events: %v", err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("events=%d/one_by_one", n), func(b *testing.B) {
+			for range b.N {
+				aggregateID := newBenchAggregateID()
+				for i, event := range newTestEvents(aggregateID, n, data) {
+					if err := s.SaveEvents(
+						ctx, aggregateID, i, eventstore.Events{event},
+					); err != nil {
+						b.Fatalf("save event: %v", err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkListEventsMany compares listing the events of several
+// aggregates in one query with listing them one aggregate at a time.
+func BenchmarkListEventsMany(b *testing.B) {
+	ctx := context.Background()
+	s := newTestDatabase(b).start(b)
+
+	for _, n := range []int{1, 5, 20} {
+		ids := make([]string, 0, n)
+		for range n {
+			aggregateID := newBenchAggregateID()
+			if err := s.SaveEvents(ctx, aggregateID, 0,
+				newTestEvents(aggregateID, 10, []byte("{}")),
+			); err != nil {
+				b.Fatalf("save events: %v", err)
+			}
+			ids = append(ids, aggregateID)
+		}
+
+		b.Run(fmt.Sprintf("aggregates=%d/many", n), func(b *testing.B) {
+			for range b.N {
+				if _, err := s.ListEventsMany(ctx, ids); err != nil {
+					b.Fatalf("list events many: %v", err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("aggregates=%d/one_by_one", n), func(b *testing.B) {
+			for range b.N {
+				for _, id := range ids {
+					if _, err := s.ListEvents(ctx, id); err != nil {
+						b.Fatalf("list events: %v", err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// waitSequenced waits for the store to sequence the first n events saved.
+func waitSequenced(t testing.TB, s *Store, n int64) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		position, err := s.LastGlobalPosition(context.Background())
+		if err != nil {
+			t.Fatalf("last global position: %v", err)
+		}
+		if position >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("sequenced %d of %d events", position, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// BenchmarkListEventsByType compares filtering a stream in which one event
+// in 50 is of the wanted type in the database with filtering it after
+// listing all events, reporting the rows transferred.
+func BenchmarkListEventsByType(b *testing.B) {
+	const (
+		events = 5000
+		every  = 50
+	)
+
+	ctx := context.Background()
+	s := newTestDatabase(b).start(b)
+
+	for i := 0; i < events; i += every {
+		aggregateID := newBenchAggregateID()
+		batch := newTestEvents(aggregateID, every, []byte("{}"))
+		batch[every-1].Type = "test.Sparse"
+		if err := s.SaveEvents(ctx, aggregateID, 0, batch); err != nil {
+			b.Fatalf("save events: %v", err)
+		}
+	}
+	waitSequenced(b, s, events)
+
+	b.Run("by_type", func(b *testing.B) {
+		var rows int
+		for range b.N {
+			listed, err := s.ListEventsByType(ctx, []string{"test.Sparse"}, 0, 0)
+			if err != nil {
+				b.Fatalf("list events by type: %v", err)
+			}
+			rows = len(listed)
+		}
+		b.ReportMetric(float64(rows), "rows/op")
+	})
+	b.Run("all", func(b *testing.B) {
+		var rows int
+		for range b.N {
+			listed, err := s.ListAllEvents(ctx, 0, 0)
+			if err != nil {
+				b.Fatalf("list all events: %v", err)
+			}
+			var sparse eventstore.Events
+			for _, event := range listed {
+				if event.Type == "test.Sparse" {
+					sparse = append(sparse, event)
+				}
+			}
+			rows = len(listed)
+		}
+		b.ReportMetric(float64(rows), "rows/op")
+	})
+}
+
+// benchData is the JSON encoding of a large event with repetitive fields,
+// the kind of data compression is meant for.
+func benchData() []byte {
+	var b strings.Builder
+	b.WriteString(`{"lines": [`)
+	for i := range 20 {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, `{"timestamp": "2024-01-%02dT10:00:00Z", `+
+			`"accountDebited": "expenses:office:%d", `+
+			`"accountCredited": "assets:bank:checking", `+
+			`"amount": "%d", "description": "Office supplies, invoice %d"}`,
+			i%28+1, i%3, 1000+i*17, 4000+i)
+	}
+	b.WriteString(`]}`)
+	return []byte(b.String())
+}
+
+// BenchmarkGzipCompression reports the CPU cost and the ratio of
+// compressing typical event data at several levels.
+func BenchmarkGzipCompression(b *testing.B) {
+	data := benchData()
+
+	for _, level := range []int{
+		gzip.BestSpeed, gzip.DefaultCompression, gzip.BestCompression,
+	} {
+		c := GzipCompression{Level: level}
+		compressed, err := c.Compress(data)
+		if err != nil {
+			b.Fatalf("compress: %v", err)
+		}
+		ratio := float64(len(compressed)) / float64(len(data))
+
+		b.Run(fmt.Sprintf("level=%d/compress", level), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for range b.N {
+				if _, err := c.Compress(data); err != nil {
+					b.Fatalf("compress: %v", err)
+				}
+			}
+			b.ReportMetric(ratio, "ratio")
+		})
+		b.Run(fmt.Sprintf("level=%d/decompress", level), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for range b.N {
+				if _, err := c.Decompress(compressed); err != nil {
+					b.Fatalf("decompress: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCompressionRoundTrip saves and lists events with and without
+// compression, reporting the bytes stored per event.
+func BenchmarkCompressionRoundTrip(b *testing.B) {
+	const events = 10
+
+	ctx := context.Background()
+	db := newTestDatabase(b)
+	data := benchData()
+
+	for name, opts := range map[string][]option{
+		"none": nil,
+		"gzip": {WithCompression(GzipCompression{})},
+	} {
+		s := db.start(b, opts...)
+
+		b.Run(name, func(b *testing.B) {
+			var ids []string
+			for range b.N {
+				aggregateID := newBenchAggregateID()
+				if err := s.SaveEvents(ctx, aggregateID, 0,
+					newTestEvents(aggregateID, events, data),
+				); err != nil {
+					b.Fatalf("save events: %v", err)
+				}
+				listed, err := s.ListEvents(ctx, aggregateID)
+				if err != nil {
+					b.Fatalf("list events: %v", err)
+				}
+				if len(listed) != events {
+					b.Fatalf("listed %d events, want %d", len(listed), events)
+				}
+				ids = append(ids, aggregateID)
+			}
+
+			var stored float64
+			if err := db.pool.QueryRow(ctx,
+				"SELECT avg(octet_length(data)) FROM "+db.schema+
+					".es_events WHERE aggregate_id = ANY ($1)",
+				ids,
+			).Scan(&stored); err != nil {
+				b.Fatalf("select stored size: %v", err)
+			}
+			b.ReportMetric(stored, "stored_bytes/event")
+		})
+	}
+}
+
+// BenchmarkFetchSize reads a long stream through the cursor at several
+// fetch sizes, trading round trips against memory held per batch.
+func BenchmarkFetchSize(b *testing.B) {
+	const events = 10000
+
+	ctx := context.Background()
+	db := newTestDatabase(b)
+	data := benchData()
+
+	aggregateID := newBenchAggregateID()
+	if err := db.start(b).SaveEvents(ctx, aggregateID, 0,
+		newTestEvents(aggregateID, events, data),
+	); err != nil {
+		b.Fatalf("save events: %v", err)
+	}
+
+	for _, size := range []int{100, 1000, 10000} {
+		s := db.start(b, WithFetchSize(size))
+
+		b.Run(fmt.Sprintf("size=%d/list", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				listed, err := s.ListEvents(ctx, aggregateID)
+				if err != nil {
+					b.Fatalf("list events: %v", err)
+				}
+				if len(listed) != events {
+					b.Fatalf("listed %d events, want %d", len(listed), events)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("size=%d/stream", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				n := 0
+				for _, err := range s.StreamEvents(ctx, aggregateID) {
+					if err != nil {
+						b.Fatalf("stream events: %v", err)
+					}
+					n++
+				}
+				if n != events {
+					b.Fatalf("streamed %d events, want %d", n, events)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package eventstorepostgres
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// identifierPattern is the allowlist for schema names and table prefixes.
+// Identifiers are quoted when substituted into queries anyway, the pattern
+// only keeps them to names that are also valid unquoted, so that migrations
+// can refer to the tables without quoting.
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// tableNamePattern matches references to the tables of the store in the
+// embedded queries, which are written against the default names.
+var tableNamePattern = regexp.MustCompile(
+	`\bes_(aggregate_tags|aggregates|events|outbox|snapshots|subscription_backlogs|subscriptions)\b`,
+)
+
+const defaultTablePrefix = "es_"
+
+type tables struct {
+	schema string
+	prefix string
+}
+
+func validateIdentifier(kind, name string) {
+	if !identifierPattern.MatchString(name) {
+		panic(fmt.Sprintf("eventstorepostgres: invalid %s: %q", kind, name))
+	}
+}
+
+func (t tables) name(table string) string {
+	if t.schema == "" {
+		return pgx.Identifier{t.prefix + table}.Sanitize()
+	}
+	return pgx.Identifier{t.schema, t.prefix + table}.Sanitize()
+}
+
+func (t tables) rewrite(query string) string {
+	return tableNamePattern.ReplaceAllStringFunc(query, func(match string) string {
+		return t.name(match[len(defaultTablePrefix):])
+	})
+}
+
+func (t tables) constraint(name string) string {
+	return t.prefix + name
+}
+
+func (t tables) channel(name string) string {
+	if t.schema == "" {
+		return t.prefix + name
+	}
+	return t.schema + "." + t.prefix + name
+}
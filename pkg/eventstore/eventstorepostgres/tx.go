@@ -0,0 +1,57 @@
+package eventstorepostgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type txContextKey struct{}
+
+// WithTx makes saves made with the returned context run in tx, which is
+// owned by the caller, so that events can be saved atomically with other
+// writes, e.g. to a read model table. The events are saved in a savepoint
+// of tx and become visible, and subscriptions are notified, only once the
+// caller commits it. Reads made with the context run in tx as well, so
+// that they see the events saved in it, and bypass the read pool.
+//
+// The saves then run at the isolation level of tx rather than the one set
+// with WithSaveIsolationLevel, and are not retried: a serialization failure
+// or a dropped connection aborts tx, so it is up to the caller to retry the
+// whole transaction. Without WithTx every save runs in a transaction of its
+// own taken from the pool.
+func WithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+func txFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// beginSaveTxFunc calls f in a savepoint of the caller's transaction if ctx
+// carries one, and in a new transaction at the save isolation level
+// otherwise.
+func (s *Store) beginSaveTxFunc(
+	ctx context.Context, f func(tx pgx.Tx) error,
+) error {
+	if tx, ok := txFromContext(ctx); ok {
+		return pgx.BeginFunc(ctx, tx, f)
+	}
+	return pgx.BeginTxFunc(ctx, s.pool, pgx.TxOptions{
+		IsoLevel: s.config.saveIsoLevel,
+	}, f)
+}
+
+// beginReadTxFunc calls f in a savepoint of the caller's transaction if ctx
+// carries one, and in a new read-only transaction otherwise.
+func (s *Store) beginReadTxFunc(
+	ctx context.Context, f func(tx pgx.Tx) error,
+) error {
+	if tx, ok := txFromContext(ctx); ok {
+		return pgx.BeginFunc(ctx, tx, f)
+	}
+	return pgx.BeginTxFunc(ctx, s.readPool(ctx), pgx.TxOptions{
+		AccessMode: pgx.ReadOnly,
+	}, f)
+}
@@ -0,0 +1,63 @@
+package eventstoretest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// AssertStreamVersion checks that the latest event of the aggregate has the
+// given version, 0 meaning that the aggregate has no events.
+func AssertStreamVersion(
+	t testing.TB, store eventstore.Interface, aggregateID string, version int,
+) {
+	t.Helper()
+
+	events, err := store.ListEvents(context.Background(), aggregateID)
+	if errors.Is(err, eventstore.ErrStreamNotFound) {
+		err = nil
+	}
+	if err != nil {
+		t.Fatalf("stream %s: list events: %v", aggregateID, err)
+	}
+
+	got := 0
+	if len(events) > 0 {
+		got = events[len(events)-1].AggregateVersion
+	}
+
+	if got != version {
+		t.Fatalf("stream %s: version: got %d, want %d",
+			aggregateID, got, version)
+	}
+}
+
+// AssertEventTypes checks that the events of the aggregate have exactly the
+// given types, in order.
+func AssertEventTypes(
+	t testing.TB, store eventstore.Interface, aggregateID string,
+	typeURLs ...string,
+) {
+	t.Helper()
+
+	events, err := store.ListEvents(context.Background(), aggregateID)
+	if errors.Is(err, eventstore.ErrStreamNotFound) {
+		err = nil
+	}
+	if err != nil {
+		t.Fatalf("stream %s: list events: %v", aggregateID, err)
+	}
+
+	got := make([]string, len(events))
+	for i, event := range events {
+		got[i] = event.Type
+	}
+
+	if !slices.Equal(got, typeURLs) {
+		t.Fatalf("stream %s: event types:\ngot:  %q\nwant: %q",
+			aggregateID, got, typeURLs)
+	}
+}
@@ -0,0 +1,102 @@
+package eventstoretest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// CorrelationStore is implemented by the stores able to trace the events of
+// a correlation.
+type CorrelationStore interface {
+	eventstore.Interface
+	ListEventsByCorrelation(
+		ctx context.Context, correlationID string,
+	) (eventstore.Events, error)
+}
+
+// RunCorrelationTraceCase saves the events of a saga into an empty store
+// and checks that ListEventsByCorrelation returns them by causal depth, then
+// by global position, whatever their timestamps and aggregate IDs.
+func RunCorrelationTraceCase(t testing.TB, store CorrelationStore) {
+	t.Helper()
+
+	ctx := context.Background()
+	epoch := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	save := func(
+		aggregateID string, version int, after time.Duration,
+		correlationID string, causationID string,
+	) {
+		t.Helper()
+
+		metadata := eventstore.Metadata{}
+		if correlationID != "" {
+			metadata[eventstore.CorrelationID] = correlationID
+			metadata[eventstore.CausationID] = causationID
+		}
+		if err := store.SaveEvents(ctx, aggregateID, version-1,
+			eventstore.Events{{
+				ID:               fmt.Sprintf("%s-%d", aggregateID, version),
+				AggregateID:      aggregateID,
+				AggregateVersion: version,
+				Timestamp:        epoch.Add(after),
+				Metadata:         metadata,
+				Type:             "test.Event",
+				Data:             []byte("{}"),
+			}},
+		); err != nil {
+			t.Fatalf("save event %d of %s: %v", version, aggregateID, err)
+		}
+	}
+
+	// The order starts the saga, which ships before it is paid for, the
+	// clock of the payment service running behind, and completes the order
+	// once paid. Another order is placed meanwhile.
+	save("order", 1, 0, "", "")
+	save("z-shipping", 1, 2*time.Second, "order-1", "order-1")
+	save("other-order", 1, 0, "", "")
+	save("a-payment", 1, time.Second, "order-1", "order-1")
+	save("order", 2, 3*time.Second, "order-1", "a-payment-1")
+
+	waitSequenced(t, store, 5)
+
+	events, err := store.ListEventsByCorrelation(ctx, "order-1")
+	if err != nil {
+		t.Fatalf("list events by correlation: %v", err)
+	}
+
+	got := make([]string, 0, len(events))
+	for _, event := range events {
+		got = append(got, event.ID)
+	}
+	want := []string{"order-1", "z-shipping-1", "a-payment-1", "order-2"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("events of correlation:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+// waitSequenced waits for the store to sequence the first n events saved,
+// which some stores do in the background.
+func waitSequenced(t testing.TB, store eventstore.Interface, n int64) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		position, err := store.LastGlobalPosition(context.Background())
+		if err != nil {
+			t.Fatalf("last global position: %v", err)
+		}
+		if position >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("sequenced %d of %d events", position, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,45 @@
+package eventstoretest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// DefaultDrainTimeout bounds DrainSubscription when ctx has no deadline.
+const DefaultDrainTimeout = 5 * time.Second
+
+// DrainSubscription receives n events from ch, e.g. as returned by
+// SubscribeAll. It fails if ch is closed or ctx is done before then, or
+// after DefaultDrainTimeout if ctx has no deadline, returning the events
+// received so far along with the error.
+func DrainSubscription(
+	ctx context.Context, ch <-chan *eventstore.Event, n int,
+) (eventstore.Events, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultDrainTimeout)
+		defer cancel()
+	}
+
+	events := make(eventstore.Events, 0, n)
+
+	for len(events) < n {
+		select {
+		case <-ctx.Done():
+			return events, fmt.Errorf("received %d of %d events: %w",
+				len(events), n, ctx.Err())
+		case event, ok := <-ch:
+			if !ok {
+				return events, fmt.Errorf(
+					"received %d of %d events: subscription closed",
+					len(events), n)
+			}
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
@@ -0,0 +1,156 @@
+package eventstoretest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// RunTenantIsolationCase saves events of two tenants into an empty store
+// and checks that the methods reading the global log only return the
+// events of the tenant of the context, unless it is from
+// eventstore.WithAllTenants.
+func RunTenantIsolationCase(t *testing.T, store eventstore.Interface) {
+	ctx := context.Background()
+	t1 := eventstore.WithTenantID(ctx, "t1")
+	t2 := eventstore.WithTenantID(ctx, "t2")
+	all := eventstore.WithAllTenants(ctx)
+
+	save := func(ctx context.Context, aggregateID string, version int,
+		eventType string,
+	) {
+		t.Helper()
+
+		tenantID := eventstore.MetadataFromContext(ctx).TenantID()
+		if err := store.SaveEvents(ctx, aggregateID, version-1,
+			eventstore.Events{{
+				ID:               eventID(tenantID, aggregateID, version),
+				AggregateID:      aggregateID,
+				AggregateVersion: version,
+				Timestamp:        time.Now().UTC().Truncate(time.Microsecond),
+				Metadata:         eventstore.Metadata{"k": "v"},
+				Type:             eventType,
+				Data:             []byte("{}"),
+			}},
+		); err != nil {
+			t.Fatalf("save event %d of %s of %s: %v",
+				version, aggregateID, tenantID, err)
+		}
+	}
+
+	// Both tenants have an aggregate a.
+	save(t1, "a", 1, "test.Created")
+	save(t1, "a", 2, "test.Updated")
+	save(t2, "a", 1, "test.Created")
+	waitSequenced(t, store, 3)
+
+	check := func(
+		t *testing.T, list func(context.Context) (eventstore.Events, error),
+		wantT1 []string, wantT2 []string, wantAll []string,
+	) {
+		t.Helper()
+
+		for _, tc := range []struct {
+			name string
+			ctx  context.Context
+			want []string
+		}{
+			{"t1", t1, wantT1},
+			{"t2", t2, wantT2},
+			{"no tenant", ctx, nil},
+			{"all tenants", all, wantAll},
+		} {
+			events, err := list(tc.ctx)
+			if err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			if got := eventIDs(events); !slices.Equal(got, tc.want) {
+				t.Fatalf("%s:\ngot:  %q\nwant: %q", tc.name, got, tc.want)
+			}
+		}
+	}
+
+	t.Run("ListAllEvents", func(t *testing.T) {
+		check(t, func(ctx context.Context) (eventstore.Events, error) {
+			return store.ListAllEvents(ctx, 0, 0)
+		},
+			[]string{"t1/a-1", "t1/a-2"},
+			[]string{"t2/a-1"},
+			[]string{"t1/a-1", "t1/a-2", "t2/a-1"})
+	})
+
+	t.Run("ListEventsByType", func(t *testing.T) {
+		check(t, func(ctx context.Context) (eventstore.Events, error) {
+			return store.ListEventsByType(ctx, []string{"test.Created"}, 0, 0)
+		},
+			[]string{"t1/a-1"},
+			[]string{"t2/a-1"},
+			[]string{"t1/a-1", "t2/a-1"})
+	})
+
+	t.Run("ListEventsByMetadata", func(t *testing.T) {
+		check(t, func(ctx context.Context) (eventstore.Events, error) {
+			return store.ListEventsByMetadata(
+				ctx, map[string]any{"k": "v"}, 0, 0)
+		},
+			[]string{"t1/a-1", "t1/a-2"},
+			[]string{"t2/a-1"},
+			[]string{"t1/a-1", "t1/a-2", "t2/a-1"})
+	})
+
+	t.Run("SubscribeAll", func(t *testing.T) {
+		subCtx, cancel := context.WithCancel(t2)
+		defer cancel()
+		t2Events, err := store.SubscribeAll(subCtx, 0)
+		if err != nil {
+			t.Fatalf("subscribe t2: %v", err)
+		}
+		allCtx, cancelAll := context.WithCancel(all)
+		defer cancelAll()
+		allEvents, err := store.SubscribeAll(allCtx, 0)
+		if err != nil {
+			t.Fatalf("subscribe all tenants: %v", err)
+		}
+
+		// The event of t1 comes first, so that it would be received in
+		// place of the one of t2 if it leaked.
+		save(t1, "b", 1, "test.Created")
+		save(t2, "a", 2, "test.Updated")
+
+		events, err := DrainSubscription(ctx, t2Events, 2)
+		if err != nil {
+			t.Fatalf("drain t2: %v", err)
+		}
+		if got, want := eventIDs(events), []string{
+			"t2/a-1", "t2/a-2",
+		}; !slices.Equal(got, want) {
+			t.Fatalf("t2:\ngot:  %q\nwant: %q", got, want)
+		}
+
+		events, err = DrainSubscription(ctx, allEvents, 5)
+		if err != nil {
+			t.Fatalf("drain all tenants: %v", err)
+		}
+		if got, want := eventIDs(events), []string{
+			"t1/a-1", "t1/a-2", "t2/a-1", "t1/b-1", "t2/a-2",
+		}; !slices.Equal(got, want) {
+			t.Fatalf("all tenants:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
+
+func eventID(tenantID string, aggregateID string, version int) string {
+	return fmt.Sprintf("%s/%s-%d", tenantID, aggregateID, version)
+}
+
+func eventIDs(events eventstore.Events) []string {
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
@@ -0,0 +1,89 @@
+package eventstoretest
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+var _ eventstore.Tracer = (*RecordingTracer)(nil)
+
+// RecordingTracer records the spans it starts, so that tests can check
+// which operations are traced.
+type RecordingTracer struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+type RecordedSpan struct {
+	Name       string
+	Attributes map[string]any
+	Err        error
+	Ended      bool
+}
+
+func (t *RecordingTracer) Start(
+	ctx context.Context, name string,
+) (context.Context, eventstore.Span) {
+	span := &recordingSpan{
+		tracer: t,
+		span:   &RecordedSpan{Name: name, Attributes: make(map[string]any)},
+	}
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span.span)
+	t.mu.Unlock()
+
+	return ctx, span
+}
+
+func (*RecordingTracer) Inject(context.Context, eventstore.Metadata) {}
+
+// Spans returns a copy of the spans started so far, in order.
+func (t *RecordingTracer) Spans() []RecordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spans := make([]RecordedSpan, 0, len(t.spans))
+	for _, span := range t.spans {
+		copied := *span
+		copied.Attributes = maps.Clone(span.Attributes)
+		spans = append(spans, copied)
+	}
+	return spans
+}
+
+// Span returns the first span started with the given name.
+func (t *RecordingTracer) Span(name string) (RecordedSpan, bool) {
+	for _, span := range t.Spans() {
+		if span.Name == name {
+			return span, true
+		}
+	}
+	return RecordedSpan{}, false
+}
+
+type recordingSpan struct {
+	tracer *RecordingTracer
+	span   *RecordedSpan
+}
+
+func (s *recordingSpan) SetAttribute(key string, value any) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.span.Attributes[key] = value
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.span.Err = err
+}
+
+func (s *recordingSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.span.Ended = true
+}
@@ -2,14 +2,120 @@ package eventstore
 
 import (
 	"context"
+	"iter"
 )
 
+// Interface is implemented by event stores. Aggregates are partitioned by
+// the tenant ID in the context metadata, see WithTenantID, and every method
+// only sees the aggregates of that tenant. The methods reading the global
+// log, i.e. ListAllEvents, ListEventsByType, ListEventsByMetadata and
+// SubscribeAll, span all tenants only with a context from WithAllTenants.
+// Global positions are shared by all tenants, so the positions of the
+// events of a tenant have gaps.
 type Interface interface {
+	// ListEvents lists the events of the aggregate. It fails with
+	// ErrStreamNotFound if the aggregate has none, i.e. it was never
+	// created or its stream was deleted with DeleteStream. A soft-deleted
+	// aggregate still has its events, ending with a tombstone.
 	ListEvents(
 		ctx context.Context, aggregateID string,
 	) (Events, error)
+	// StreamEvents yields the events of the aggregate one by one, without
+	// holding them all in memory. Iteration stops after the first error.
+	// Like ListEvents, it yields ErrStreamNotFound if there are no events.
+	StreamEvents(
+		ctx context.Context, aggregateID string,
+	) iter.Seq2[*Event, error]
+	// ListEventsMany lists the events of each of the aggregates in one go.
+	// Aggregates without events are missing from the result.
+	ListEventsMany(
+		ctx context.Context, aggregateIDs []string,
+	) (map[string]Events, error)
+	// CountEvents returns the number of events of the aggregate, or 0 if
+	// there are none.
+	CountEvents(
+		ctx context.Context, aggregateID string,
+	) (int, error)
+	// ListEventsPage lists up to limit events of the aggregate with a
+	// version greater than afterVersion, and tells whether there are more.
+	// It fails with ErrInvalidPageLimit if limit is not positive, and with
+	// ErrStreamNotFound if the aggregate has no events at all.
+	ListEventsPage(
+		ctx context.Context, aggregateID string, afterVersion int, limit int,
+	) (Events, bool, error)
+	// ListEventsRange lists the events of the aggregate with versions
+	// from fromVersion to toVersion inclusive. A toVersion of 0 means up
+	// to the latest version. It fails with ErrStreamNotFound if the
+	// aggregate has no events at all, and returns none if it has some but
+	// not in the range.
+	ListEventsRange(
+		ctx context.Context, aggregateID string, fromVersion int, toVersion int,
+	) (Events, error)
+	// ListAllEvents lists up to limit events of all aggregates of the
+	// tenant, or of all tenants with WithAllTenants, with a global position
+	// greater than afterPosition, ordered by global position. A limit of 0
+	// means no limit.
+	ListAllEvents(
+		ctx context.Context, afterPosition int64, limit int,
+	) (Events, error)
+	// ListEventsByType is like ListAllEvents but only lists the events of
+	// the given types.
+	ListEventsByType(
+		ctx context.Context, typeURLs []string, afterPosition int64, limit int,
+	) (Events, error)
+	// ListEventsByMetadata is like ListAllEvents but only lists the events
+	// whose metadata has all the keys of the filter set to the given values.
+	ListEventsByMetadata(
+		ctx context.Context, filter map[string]any, afterPosition int64,
+		limit int,
+	) (Events, error)
+	// GetEventByID fails with ErrEventDoesNotExist if there is no event
+	// with the ID.
+	GetEventByID(
+		ctx context.Context, eventID string,
+	) (*Event, error)
+	// LastGlobalPosition returns the highest global position assigned so
+	// far to the events of any tenant, or 0 if there are no events.
+	LastGlobalPosition(
+		ctx context.Context,
+	) (int64, error)
 	SaveEvents(
 		ctx context.Context, aggregateID string, expectedAggregateVersion int,
 		events Events,
 	) error
+	// SaveBatch saves the events of several aggregates atomically. If the
+	// version of any of them differs from the expected one, nothing is saved
+	// and an error wrapping ErrConcurrentUpdate names that aggregate.
+	SaveBatch(
+		ctx context.Context, batch []AggregateEvents,
+	) error
+	// DeleteStream irreversibly deletes all events of the aggregate. It
+	// fails with ErrStreamNotFound if there are none.
+	DeleteStream(
+		ctx context.Context, aggregateID string,
+	) error
+	CurrentVersions(
+		ctx context.Context, aggregateIDs []string,
+	) (map[string]int, error)
+	AddTag(
+		ctx context.Context, aggregateID string, tag string,
+	) error
+	RemoveTag(
+		ctx context.Context, aggregateID string, tag string,
+	) error
+	// ListAggregateIDs lists, in order, up to limit IDs greater than afterID
+	// of the aggregates of the type. A limit of 0 means no limit.
+	ListAggregateIDs(
+		ctx context.Context, aggregateType string, afterID string, limit int,
+	) ([]string, error)
+	ListAggregatesByTag(
+		ctx context.Context, tag string,
+	) ([]string, error)
+	// SubscribeAll delivers, in global order, the events of the tenant, or
+	// of all tenants with WithAllTenants, with a global position greater
+	// than fromGlobalPosition, including those saved later. The channel is closed when ctx is done, or when the subscriber
+	// falls too far behind, see SubscriptionErr.
+	SubscribeAll(
+		ctx context.Context, fromGlobalPosition int64,
+	) (<-chan *Event, error)
 }
@@ -1,29 +1,208 @@
 package eventstore
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"maps"
+)
+
+type (
+	metadataContextKey         struct{}
+	reservedMetadataContextKey struct{}
+	allTenantsContextKey       struct{}
+)
 
-type metadataContextKey struct{}
+// reservedMetadataKeys are the keys the framework relies on, which are only
+// to be set with the dedicated helpers, see ValidateReservedMetadata. The
+// schema version has no helper, being recorded in Event.SchemaVersion.
+var reservedMetadataKeys = []string{CausationID, CorrelationID, SchemaVersion}
 
 type Metadata map[string]interface{}
 
 func (m Metadata) CausationID() string {
-	v, ok := m[CausationID]
+	return m.getString(CausationID)
+}
+
+func (m Metadata) CorrelationID() string {
+	return m.getString(CorrelationID)
+}
+
+func (m Metadata) UserID() string {
+	return m.getString(UserID)
+}
+
+func (m Metadata) TenantID() string {
+	return m.getString(TenantID)
+}
+
+// SchemaVersion returns the schema version stamped in the metadata of events
+// saved before Event.SchemaVersion was recorded, see
+// Event.EffectiveSchemaVersion. Events without one are reported as version 1.
+func (m Metadata) SchemaVersion() int {
+	switch v := m[SchemaVersion].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	default:
+		return 1
+	}
+}
+
+// Validate checks that the required keys are present and that the values of
+// the well-known keys have the right type, failing with
+// ErrMetadataKeyMissing or ErrMetadataValueInvalid.
+func (m Metadata) Validate(required ...string) error {
+	for _, key := range required {
+		if v, ok := m[key]; !ok || v == "" {
+			return fmt.Errorf("%w: %s", ErrMetadataKeyMissing, key)
+		}
+	}
+
+	for key, v := range m {
+		switch key {
+		case CausationID, CorrelationID, UserID, TenantID:
+			if _, ok := v.(string); !ok {
+				return fmt.Errorf("%w: %s: %T", ErrMetadataValueInvalid, key, v)
+			}
+		case SchemaVersion:
+			switch v.(type) {
+			case int, float64:
+			default:
+				return fmt.Errorf("%w: %s: %T", ErrMetadataValueInvalid, key, v)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m Metadata) getString(key string) string {
+	v, ok := m[key]
 	if !ok {
 		return ""
 	}
-	causationID, _ := v.(string)
-	return causationID
+	s, _ := v.(string)
+	return s
 }
 
+// WithMetadata returns a context whose metadata is the metadata already in
+// ctx merged with md, the values in md winning. Layers of middleware can
+// thus each contribute their own keys.
 func WithMetadata(ctx context.Context, md Metadata) context.Context {
-	return context.WithValue(ctx, metadataContextKey{}, md)
+	merged := make(Metadata)
+	maps.Copy(merged, MetadataFromContext(ctx))
+	maps.Copy(merged, md)
+
+	return context.WithValue(ctx, metadataContextKey{}, merged)
 }
 
+// MetadataFromContext returns the metadata merged by WithMetadata. It must
+// not be modified, see WithMetadata for adding keys.
 func MetadataFromContext(ctx context.Context) Metadata {
 	md, _ := ctx.Value(metadataContextKey{}).(Metadata)
 	return md
 }
 
+// WithCorrelationID returns a context whose metadata has the correlation ID
+// set to id, keeping the other metadata already in ctx.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return withReservedMetadata(ctx, map[string]string{CorrelationID: id})
+}
+
+// WithCausationID returns a context whose metadata has the causation ID set
+// to id, keeping the other metadata already in ctx.
+func WithCausationID(ctx context.Context, id string) context.Context {
+	return withReservedMetadata(ctx, map[string]string{CausationID: id})
+}
+
+// ValidateReservedMetadata fails with ErrReservedMetadataKey if the
+// metadata in ctx has a reserved key, i.e. the causation or correlation ID
+// or the schema version, that was not set by WithCausationID,
+// WithCorrelationID, PropagateContext or MetadataBuilder, e.g. one
+// overridden with WithMetadata.
+func ValidateReservedMetadata(ctx context.Context) error {
+	md := MetadataFromContext(ctx)
+	reserved, _ := ctx.Value(reservedMetadataContextKey{}).(map[string]string)
+
+	for _, key := range reservedMetadataKeys {
+		v, ok := md[key]
+		if !ok {
+			continue
+		}
+		if s, ok := v.(string); !ok || s != reserved[key] {
+			return fmt.Errorf("%w: %s", ErrReservedMetadataKey, key)
+		}
+	}
+
+	return nil
+}
+
+func withReservedMetadata(
+	ctx context.Context, values map[string]string,
+) context.Context {
+	reserved, _ := ctx.Value(reservedMetadataContextKey{}).(map[string]string)
+	merged := make(map[string]string)
+	maps.Copy(merged, reserved)
+	maps.Copy(merged, values)
+	ctx = context.WithValue(ctx, reservedMetadataContextKey{}, merged)
+
+	md := make(Metadata, len(values))
+	for key, v := range values {
+		md[key] = v
+	}
+
+	return WithMetadata(ctx, md)
+}
+
+// WithTenantID returns a context whose metadata has the tenant ID set to
+// id, keeping the other metadata already in ctx. Event stores scope the
+// aggregates they access with the context to that tenant.
+func WithTenantID(ctx context.Context, id string) context.Context {
+	return WithMetadata(ctx, Metadata{TenantID: id})
+}
+
+// WithAllTenants returns a context with which ListAllEvents,
+// ListEventsByType, ListEventsByMetadata and SubscribeAll see the events of
+// all tenants rather than only those of the tenant in ctx. It is meant for
+// trusted consumers of the whole log, such as projections and process
+// managers, and must not be derived from anything a tenant controls.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allTenantsContextKey{}, true)
+}
+
+// AllTenants reports whether ctx was returned by WithAllTenants.
+func AllTenants(ctx context.Context) bool {
+	all, _ := ctx.Value(allTenantsContextKey{}).(bool)
+	return all
+}
+
+// CorrelationIDFromEvent returns the correlation ID of the event, or "" if
+// it has none.
+func CorrelationIDFromEvent(event *Event) string {
+	return event.Metadata.CorrelationID()
+}
+
+// PropagateContext returns a context for issuing commands in reaction to
+// event. The correlation ID is inherited from the event, or seeded with the
+// event ID if the event has none, and the causation ID is set to the event
+// ID. Other metadata already in ctx is kept.
+func PropagateContext(ctx context.Context, event *Event) context.Context {
+	correlationID := event.Metadata.CorrelationID()
+	if correlationID == "" {
+		correlationID = event.ID
+	}
+
+	return withReservedMetadata(ctx, map[string]string{
+		CorrelationID: correlationID,
+		CausationID:   event.ID,
+	})
+}
+
 const (
-	CausationID = "X-Causation-ID"
+	CausationID   = "X-Causation-ID"
+	CorrelationID = "X-Correlation-ID"
+	SchemaVersion = "X-Schema-Version"
+	UserID        = "X-User-ID"
+	TenantID      = "X-Tenant-ID"
 )
@@ -0,0 +1,67 @@
+package eventstore
+
+import (
+	"context"
+	"maps"
+)
+
+// MetadataBuilder sets the well-known metadata keys with values of the
+// right type, starting from a copy of the metadata given to
+// NewMetadataBuilder.
+type MetadataBuilder struct {
+	md Metadata
+}
+
+func NewMetadataBuilder(md Metadata) *MetadataBuilder {
+	b := &MetadataBuilder{md: make(Metadata, len(md))}
+	maps.Copy(b.md, md)
+	return b
+}
+
+func (b *MetadataBuilder) WithUserID(id string) *MetadataBuilder {
+	b.md[UserID] = id
+	return b
+}
+
+func (b *MetadataBuilder) WithTenantID(id string) *MetadataBuilder {
+	b.md[TenantID] = id
+	return b
+}
+
+func (b *MetadataBuilder) WithCausationID(id string) *MetadataBuilder {
+	b.md[CausationID] = id
+	return b
+}
+
+func (b *MetadataBuilder) WithCorrelationID(id string) *MetadataBuilder {
+	b.md[CorrelationID] = id
+	return b
+}
+
+// Validate validates the metadata built so far, see Metadata.Validate.
+func (b *MetadataBuilder) Validate(required ...string) error {
+	return b.md.Validate(required...)
+}
+
+func (b *MetadataBuilder) Build() Metadata {
+	md := make(Metadata, len(b.md))
+	maps.Copy(md, b.md)
+	return md
+}
+
+// Context returns a context carrying the built metadata. The causation and
+// correlation IDs count as set by a dedicated helper, see
+// ValidateReservedMetadata.
+func (b *MetadataBuilder) Context(ctx context.Context) context.Context {
+	md := b.Build()
+
+	reserved := make(map[string]string)
+	for _, key := range reservedMetadataKeys {
+		if _, ok := md[key]; ok {
+			reserved[key] = md.getString(key)
+			delete(md, key)
+		}
+	}
+
+	return withReservedMetadata(WithMetadata(ctx, md), reserved)
+}
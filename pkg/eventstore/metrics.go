@@ -0,0 +1,20 @@
+package eventstore
+
+import "time"
+
+// MetricsCollector records the latency and outcome of loads and saves, e.g.
+// by adapting a prometheus.Registerer. Event stores do not know the types of
+// aggregates and report them as empty, so a collector shared between a
+// repository and its event store should tell the two apart by that.
+type MetricsCollector interface {
+	ObserveLoad(aggregateType string, duration time.Duration)
+	ObserveSave(aggregateType string, duration time.Duration, events int)
+	IncConcurrentUpdates(aggregateType string)
+}
+
+// NopMetricsCollector discards all metrics.
+type NopMetricsCollector struct{}
+
+func (NopMetricsCollector) ObserveLoad(string, time.Duration)      {}
+func (NopMetricsCollector) ObserveSave(string, time.Duration, int) {}
+func (NopMetricsCollector) IncConcurrentUpdates(string)            {}
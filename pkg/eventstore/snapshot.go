@@ -0,0 +1,27 @@
+package eventstore
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+type Snapshot struct {
+	AggregateID      string
+	AggregateVersion int
+	Timestamp        time.Time
+	Data             *anypb.Any
+}
+
+// SnapshotStore keeps snapshots of aggregate roots. GetLatestSnapshot
+// returns nil if the aggregate has no snapshot.
+type SnapshotStore interface {
+	SaveSnapshot(
+		ctx context.Context, aggregateID string, aggregateVersion int,
+		data *anypb.Any,
+	) error
+	GetLatestSnapshot(
+		ctx context.Context, aggregateID string,
+	) (*Snapshot, error)
+}
@@ -0,0 +1,28 @@
+package eventstore
+
+import "sync"
+
+// subscriptionErrs holds the errors subscriptions were closed with until
+// SubscriptionErr reports them, keyed by receive-only channel.
+var subscriptionErrs sync.Map
+
+// CloseSubscription closes ch, as returned by SubscribeAll, so that
+// SubscriptionErr reports err for it. It is meant for implementations of
+// SubscribeAll.
+func CloseSubscription(ch chan *Event, err error) {
+	if err != nil {
+		subscriptionErrs.Store((<-chan *Event)(ch), err)
+	}
+	close(ch)
+}
+
+// SubscriptionErr tells why ch, as returned by SubscribeAll, was closed:
+// ErrSubscriberLagged if the subscriber fell too far behind and was dropped,
+// or nil if ctx was done or the store stopped. The error is reported once.
+func SubscriptionErr(ch <-chan *Event) error {
+	err, ok := subscriptionErrs.LoadAndDelete(ch)
+	if !ok {
+		return nil
+	}
+	return err.(error)
+}
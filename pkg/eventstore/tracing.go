@@ -0,0 +1,33 @@
+package eventstore
+
+import "context"
+
+// Tracer creates spans, e.g. by adapting an OpenTelemetry tracer and
+// propagator, which keeps the dependency out of this module.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+	// Inject adds the trace context of ctx to metadata, so that consumers
+	// of the events can continue the trace.
+	Inject(ctx context.Context, metadata Metadata)
+}
+
+type Span interface {
+	SetAttribute(key string, value any)
+	RecordError(err error)
+	End()
+}
+
+// NopTracer creates spans that record nothing.
+type NopTracer struct{}
+
+func (NopTracer) Start(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+func (NopTracer) Inject(context.Context, Metadata) {}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(string, any) {}
+func (nopSpan) RecordError(error)        {}
+func (nopSpan) End()                     {}
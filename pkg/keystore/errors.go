@@ -0,0 +1,8 @@
+package keystore
+
+import "errors"
+
+var (
+	ErrKeyDoesNotExist = errors.New("key does not exist")
+	ErrKeyShredded     = errors.New("key shredded")
+)
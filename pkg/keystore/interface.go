@@ -0,0 +1,21 @@
+package keystore
+
+import (
+	"context"
+)
+
+// Interface keeps encryption keys per aggregate. Shredding a key makes the
+// data encrypted with it unreadable for good.
+type Interface interface {
+	GetOrCreateKey(
+		ctx context.Context, aggregateID string,
+	) ([]byte, error)
+	// GetKey fails with ErrKeyShredded if the key has been shredded and
+	// with ErrKeyDoesNotExist if it was never created.
+	GetKey(
+		ctx context.Context, aggregateID string,
+	) ([]byte, error)
+	ShredKey(
+		ctx context.Context, aggregateID string,
+	) error
+}
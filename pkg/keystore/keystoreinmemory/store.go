@@ -0,0 +1,78 @@
+package keystoreinmemory
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+
+	"github.com/rnovatorov/go-eventsource/pkg/keystore"
+)
+
+var _ keystore.Interface = (*Store)(nil)
+
+const keySize = 32
+
+type Store struct {
+	mu   sync.Mutex
+	keys map[string][]byte
+}
+
+func New() *Store {
+	return &Store{
+		keys: make(map[string][]byte),
+	}
+}
+
+func (s *Store) GetOrCreateKey(
+	ctx context.Context, aggregateID string,
+) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[aggregateID]
+	if ok {
+		if key == nil {
+			return nil, keystore.ErrKeyShredded
+		}
+		return key, nil
+	}
+
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+	s.keys[aggregateID] = key
+
+	return key, nil
+}
+
+func (s *Store) GetKey(
+	ctx context.Context, aggregateID string,
+) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[aggregateID]
+	if !ok {
+		return nil, keystore.ErrKeyDoesNotExist
+	}
+	if key == nil {
+		return nil, keystore.ErrKeyShredded
+	}
+
+	return key, nil
+}
+
+// ShredKey forgets the key but remembers that it existed, so that the key
+// is not silently recreated for the aggregate.
+func (s *Store) ShredKey(
+	ctx context.Context, aggregateID string,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[aggregateID] = nil
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package processmanager
+
+import (
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+)
+
+type config struct {
+	timeoutInterval time.Duration
+	clock           eventsource.Clock
+}
+
+func newConfig(opts ...option) config {
+	cfg := config{
+		timeoutInterval: time.Second,
+		clock:           eventsource.SystemClock{},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+type option func(*config)
+
+// WithTimeoutInterval sets how often to look for due timeouts.
+func WithTimeoutInterval(interval time.Duration) option {
+	return func(cfg *config) {
+		cfg.timeoutInterval = interval
+	}
+}
+
+// WithClock makes the coordinator tell whether timeouts are due, and
+// processes schedule them with ScheduleAfter, by the time told by clock.
+func WithClock(clock eventsource.Clock) option {
+	return func(cfg *config) {
+		cfg.clock = clock
+	}
+}
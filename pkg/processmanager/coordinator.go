@@ -0,0 +1,195 @@
+package processmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/projection"
+)
+
+// Coordinator feeds process managers the events of all aggregates and their
+// due timeouts, and persists their processes.
+type Coordinator struct {
+	eventStore  eventstore.Interface
+	commandBus  *eventsource.CommandBus
+	processes   Store
+	checkpoints projection.CheckpointStore
+	config      config
+}
+
+// NewCoordinator returns a coordinator dispatching the commands of process
+// managers to commandBus. Checkpoints are saved under the names of the
+// managers, so they must not clash with those of projections sharing
+// checkpoints.
+func NewCoordinator(
+	eventStore eventstore.Interface, commandBus *eventsource.CommandBus,
+	processes Store, checkpoints projection.CheckpointStore,
+	opts ...option,
+) *Coordinator {
+	return &Coordinator{
+		eventStore:  eventStore,
+		commandBus:  commandBus,
+		processes:   processes,
+		checkpoints: checkpoints,
+		config:      newConfig(opts...),
+	}
+}
+
+// Run feeds the manager the events following its checkpoint, including
+// those saved later, and the timeouts of its processes as they fall due,
+// until ctx is done, in which case it returns nil. If the manager fails to
+// handle an event or a timeout, Run returns the error and the next run
+// handles it again. Only one Run per manager may be active at a time.
+func (c *Coordinator) Run(ctx context.Context, manager ProcessManager) error {
+	name := manager.Name()
+
+	position, err := c.checkpoints.LoadCheckpoint(ctx, name)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("load checkpoint: %w", err)
+	}
+
+	// Processes belong to the tenant of the events starting them, see
+	// Process.TenantID, so the manager is fed the events of all tenants.
+	events, err := c.eventStore.SubscribeAll(
+		eventstore.WithAllTenants(ctx), position)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("subscribe: %w", err)
+	}
+
+	ticker := time.NewTicker(c.config.timeoutInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				if err := eventstore.SubscriptionErr(events); err != nil {
+					return fmt.Errorf("subscription closed: %w", err)
+				}
+				return errors.New("subscription closed")
+			}
+			if err := c.handleEvent(ctx, manager, event); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("handle event %s at position %d: %w",
+					event.ID, event.GlobalPosition, err)
+			}
+			if err := c.checkpoints.SaveCheckpoint(
+				ctx, name, event.GlobalPosition,
+			); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("save checkpoint: %w", err)
+			}
+		case <-ticker.C:
+			if err := c.handleTimeouts(
+				ctx, manager, c.config.clock.Now(),
+			); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
+
+func (c *Coordinator) handleEvent(
+	ctx context.Context, manager ProcessManager, event *eventstore.Event,
+) error {
+	id := manager.ProcessID(event)
+	if id == "" {
+		return nil
+	}
+
+	process, err := c.processes.LoadProcess(ctx, manager.Name(), id)
+	if err != nil {
+		return fmt.Errorf("load process: %w", err)
+	}
+	if process == nil {
+		correlationID := event.Metadata.CorrelationID()
+		if correlationID == "" {
+			correlationID = event.ID
+		}
+		process = &Process{
+			ID:            id,
+			TenantID:      event.Metadata.TenantID(),
+			CorrelationID: correlationID,
+		}
+	}
+
+	if process.Done || event.GlobalPosition <= process.Position {
+		return nil
+	}
+
+	c.bind(process, event.ID)
+
+	if err := manager.Handle(ctx, process, event); err != nil {
+		return err
+	}
+	process.Position = event.GlobalPosition
+
+	if err := c.processes.SaveProcess(ctx, manager.Name(), process); err != nil {
+		return fmt.Errorf("save process: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Coordinator) handleTimeouts(
+	ctx context.Context, manager ProcessManager, now time.Time,
+) error {
+	processes, err := c.processes.ListDueProcesses(ctx, manager.Name(), now)
+	if err != nil {
+		return fmt.Errorf("list due processes: %w", err)
+	}
+
+	for _, process := range processes {
+		for _, timeout := range process.dueTimeouts(now) {
+			process.Cancel(timeout.Name)
+			c.bind(process, fmt.Sprintf("%s/%s/%d",
+				process.ID, timeout.Name, timeout.At.UnixNano()))
+
+			if err := manager.HandleTimeout(ctx, process, timeout); err != nil {
+				return fmt.Errorf("handle timeout %s of process %s: %w",
+					timeout.Name, process.ID, err)
+			}
+
+			if process.Done {
+				break
+			}
+		}
+
+		if err := c.processes.SaveProcess(
+			ctx, manager.Name(), process,
+		); err != nil {
+			return fmt.Errorf("save process %s: %w", process.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Coordinator) bind(process *Process, causationID string) {
+	process.commandBus = c.commandBus
+	process.clock = c.config.clock
+	process.causationID = causationID
+	process.dispatched = 0
+}
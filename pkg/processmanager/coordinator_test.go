@@ -0,0 +1,306 @@
+package processmanager_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource/eventsourcetest"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoreinmemory"
+	"github.com/rnovatorov/go-eventsource/pkg/processmanager"
+	"github.com/rnovatorov/go-eventsource/pkg/processmanager/processmanagerinmemory"
+	"github.com/rnovatorov/go-eventsource/pkg/projection"
+	"github.com/rnovatorov/go-eventsource/pkg/projection/projectioninmemory"
+)
+
+// tally is the aggregate root processes dispatch commands to.
+type tally struct {
+	total int64
+}
+
+type tallyAdd struct {
+	N int64
+}
+
+func (t *tally) ProcessCommand(
+	command eventsource.Command,
+) (eventsource.StateChanges, error) {
+	switch cmd := command.(type) {
+	case tallyAdd:
+		return eventsource.StateChanges{wrapperspb.Int64(cmd.N)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", eventsource.ErrCommandUnknown, cmd)
+	}
+}
+
+func (t *tally) ApplyStateChange(stateChange eventsource.StateChange) error {
+	switch sc := stateChange.(type) {
+	case *wrapperspb.Int64Value:
+		t.total += sc.Value
+	default:
+		return fmt.Errorf("%w: %T", eventsource.ErrUnknownStateChange, sc)
+	}
+	return nil
+}
+
+// mirror adds whatever is added to the tally "src" to the tally "dst",
+// twice, then adds 100 more to "dst" once a minute has passed without
+// further additions to "src".
+type mirror struct {
+	handled atomic.Int64
+}
+
+func (m *mirror) Name() string {
+	return "mirror"
+}
+
+func (m *mirror) ProcessID(event *eventstore.Event) string {
+	if event.AggregateID != "src" {
+		return ""
+	}
+	return "p"
+}
+
+func (m *mirror) Handle(
+	ctx context.Context, process *processmanager.Process,
+	event *eventstore.Event,
+) error {
+	m.handled.Add(1)
+
+	data, err := eventsource.ProtoCodec{}.Unmarshal(event.Type, event.Data)
+	if err != nil {
+		return fmt.Errorf("unmarshal data: %w", err)
+	}
+	n := data.(*wrapperspb.Int64Value).Value
+
+	for range 2 {
+		if err := process.Dispatch(ctx, "dst", tallyAdd{N: n}); err != nil {
+			return err
+		}
+	}
+	process.ScheduleAfter("settle", time.Minute)
+
+	return nil
+}
+
+func (m *mirror) HandleTimeout(
+	ctx context.Context, process *processmanager.Process,
+	timeout processmanager.Timeout,
+) error {
+	if err := process.Dispatch(ctx, "dst", tallyAdd{N: 100}); err != nil {
+		return err
+	}
+	process.Complete()
+	return nil
+}
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type fixture struct {
+	clock       *eventsourcetest.FakeClock
+	eventStore  *eventstoreinmemory.Store
+	repository  *eventsource.AggregateRepository[tally, *tally]
+	bus         *eventsource.CommandBus
+	processes   processmanager.Store
+	checkpoints projection.CheckpointStore
+	manager     *mirror
+}
+
+func newFixture(t testing.TB) *fixture {
+	t.Helper()
+
+	f := &fixture{
+		clock:       eventsourcetest.NewFakeClock(epoch),
+		eventStore:  eventstoreinmemory.New(),
+		bus:         eventsource.NewCommandBus(),
+		processes:   processmanagerinmemory.NewStore(),
+		checkpoints: projectioninmemory.NewCheckpointStore(),
+		manager:     &mirror{},
+	}
+	f.repository = eventsource.NewAggregateRepository[tally](f.eventStore)
+	f.bus.Register(tallyAdd{}, f.repository.UpdateCommandHandler())
+
+	if _, err := f.repository.Create(
+		context.Background(), "dst", tallyAdd{N: 0},
+	); err != nil {
+		t.Fatalf("create dst: %v", err)
+	}
+
+	return f
+}
+
+// run runs a coordinator until stop is called or the test ends, returning
+// a channel receiving the error it returns.
+func (f *fixture) run(t testing.TB) (done <-chan error, stop func()) {
+	t.Helper()
+
+	c := processmanager.NewCoordinator(
+		f.eventStore, f.bus, f.processes, f.checkpoints,
+		processmanager.WithClock(f.clock),
+		processmanager.WithTimeoutInterval(time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		errs <- c.Run(ctx, f.manager)
+	}()
+
+	stop = func() {
+		cancel()
+		<-stopped
+	}
+	t.Cleanup(stop)
+
+	return errs, stop
+}
+
+func (f *fixture) add(t testing.TB, n int64) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	_, err := f.repository.Update(ctx, "src", tallyAdd{N: n})
+	if errors.Is(err, eventsource.ErrAggregateDoesNotExist) {
+		_, err = f.repository.Create(ctx, "src", tallyAdd{N: n})
+	}
+	if err != nil {
+		t.Fatalf("add to src: %v", err)
+	}
+}
+
+func (f *fixture) dst(t testing.TB) *eventsource.Aggregate[tally, *tally] {
+	t.Helper()
+
+	agg, err := f.repository.Get(context.Background(), "dst")
+	if err != nil {
+		t.Fatalf("get dst: %v", err)
+	}
+	return agg
+}
+
+func (f *fixture) process(t testing.TB) *processmanager.Process {
+	t.Helper()
+
+	process, err := f.processes.LoadProcess(
+		context.Background(), f.manager.Name(), "p")
+	if err != nil {
+		t.Fatalf("load process: %v", err)
+	}
+	return process
+}
+
+func eventually(t testing.TB, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRedeliveredEventsAreSkippedByPosition(t *testing.T) {
+	f := newFixture(t)
+	f.add(t, 1)
+
+	_, stop := f.run(t)
+	eventually(t, "event to be handled", func() bool {
+		return f.dst(t).Root().total == 2
+	})
+	stop()
+
+	// Losing the checkpoint redelivers every event.
+	f.checkpoints = projectioninmemory.NewCheckpointStore()
+	f.add(t, 3)
+	f.run(t)
+	eventually(t, "new event to be handled", func() bool {
+		return f.dst(t).Root().total == 8
+	})
+
+	if got := f.manager.handled.Load(); got != 2 {
+		t.Fatalf("handled %d events, want 2", got)
+	}
+}
+
+// failingSave fails to save processes once, as if the coordinator crashed
+// after dispatching the commands of an event.
+type failingSave struct {
+	processmanager.Store
+	failed atomic.Bool
+}
+
+func (s *failingSave) SaveProcess(
+	ctx context.Context, manager string, process *processmanager.Process,
+) error {
+	if s.failed.CompareAndSwap(false, true) {
+		return errors.New("connection lost")
+	}
+	return s.Store.SaveProcess(ctx, manager, process)
+}
+
+func TestCommandsOfRehandledEventAreDeduplicated(t *testing.T) {
+	f := newFixture(t)
+	f.processes = &failingSave{Store: f.processes}
+	f.add(t, 1)
+
+	done, _ := f.run(t)
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("run: got nil, want the save error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for run to fail")
+	}
+	if got := f.dst(t).Version(); got != 3 {
+		t.Fatalf("dst version after first handling: got %d, want 3", got)
+	}
+
+	// The event is handled again, dispatching both commands again with
+	// the same causation IDs, which dst drops.
+	f.run(t)
+	eventually(t, "process to be saved", func() bool {
+		return f.process(t) != nil
+	})
+
+	if got := f.manager.handled.Load(); got != 2 {
+		t.Fatalf("handled %d events, want 2", got)
+	}
+	if dst := f.dst(t); dst.Version() != 3 || dst.Root().total != 2 {
+		t.Fatalf("dst: got total %d at version %d, want 2 at 3",
+			dst.Root().total, dst.Version())
+	}
+}
+
+func TestTimeoutFires(t *testing.T) {
+	f := newFixture(t)
+	f.add(t, 1)
+
+	f.run(t)
+	eventually(t, "process to be saved", func() bool {
+		return f.process(t) != nil
+	})
+
+	f.clock.Advance(time.Minute)
+	eventually(t, "process to complete", func() bool {
+		return f.process(t).Done
+	})
+
+	if dst := f.dst(t); dst.Root().total != 102 {
+		t.Fatalf("dst total: got %d, want 102", dst.Root().total)
+	}
+	if timeouts := f.process(t).Timeouts; len(timeouts) != 0 {
+		t.Fatalf("timeouts of completed process: %v", timeouts)
+	}
+}
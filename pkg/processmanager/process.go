@@ -0,0 +1,99 @@
+package processmanager
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// Process is an instance of a process run by a ProcessManager. The tenant
+// and correlation IDs are taken from the event starting the process and
+// passed on to the commands it dispatches. State is opaque to the
+// coordinator, managers encode it as they see fit.
+type Process struct {
+	ID            string
+	TenantID      string
+	CorrelationID string
+	State         []byte
+	Done          bool
+	// Position is the global position of the last event handled, events
+	// at or before it are ignored when delivered again.
+	Position int64
+	Timeouts []Timeout
+
+	commandBus  *eventsource.CommandBus
+	clock       eventsource.Clock
+	causationID string
+	dispatched  int
+}
+
+// Timeout is scheduled by a process to be handled once At has passed, e.g.
+// to give up waiting for an event.
+type Timeout struct {
+	Name string
+	At   time.Time
+}
+
+// Dispatch passes cmd to the command bus on behalf of the process. The
+// causation ID of the command is derived from the event or timeout being
+// handled and the number of commands dispatched before it while handling
+// it, so that redelivered events do not apply commands twice.
+func (p *Process) Dispatch(
+	ctx context.Context, aggregateID string, cmd eventsource.Command,
+) error {
+	if p.CorrelationID != "" {
+		ctx = eventstore.WithCorrelationID(ctx, p.CorrelationID)
+	}
+	ctx = eventstore.WithCausationID(ctx,
+		fmt.Sprintf("%s/%d", p.causationID, p.dispatched))
+	if p.TenantID != "" {
+		ctx = eventstore.WithTenantID(ctx, p.TenantID)
+	}
+	p.dispatched++
+
+	return p.commandBus.Dispatch(ctx, aggregateID, cmd)
+}
+
+// Schedule schedules a timeout, replacing the one with the same name, if
+// any.
+func (p *Process) Schedule(name string, at time.Time) {
+	p.Cancel(name)
+	p.Timeouts = append(p.Timeouts, Timeout{Name: name, At: at})
+}
+
+// ScheduleAfter schedules a timeout d from now, as told by the clock of the
+// coordinator, see WithClock.
+func (p *Process) ScheduleAfter(name string, d time.Duration) {
+	p.Schedule(name, p.clock.Now().Add(d))
+}
+
+// Cancel cancels the timeout with the given name, if any.
+func (p *Process) Cancel(name string) {
+	p.Timeouts = slices.DeleteFunc(p.Timeouts, func(t Timeout) bool {
+		return t.Name == name
+	})
+}
+
+// Complete ends the process, cancelling its timeouts. Events of a completed
+// process are ignored.
+func (p *Process) Complete() {
+	p.Done = true
+	p.Timeouts = nil
+}
+
+func (p *Process) dueTimeouts(now time.Time) []Timeout {
+	var due []Timeout
+	for _, t := range p.Timeouts {
+		if !t.At.After(now) {
+			due = append(due, t)
+		}
+	}
+	slices.SortFunc(due, func(a, b Timeout) int {
+		return a.At.Compare(b.At)
+	})
+	return due
+}
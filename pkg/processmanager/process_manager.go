@@ -0,0 +1,28 @@
+package processmanager
+
+import (
+	"context"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// ProcessManager coordinates processes spanning several aggregates by
+// dispatching commands in reaction to their events. Name identifies its
+// checkpoint and processes, so it must not change between runs.
+//
+// Handle and HandleTimeout may be called again with the same process state
+// after a failure, so they must dispatch the same commands in the same
+// order given the same state and event or timeout: the commands are then
+// deduplicated by the aggregates using causation IDs.
+type ProcessManager interface {
+	Name() string
+	// ProcessID returns the ID of the process the event belongs to, or ""
+	// if the event is of no interest to the manager.
+	ProcessID(event *eventstore.Event) string
+	Handle(
+		ctx context.Context, process *Process, event *eventstore.Event,
+	) error
+	HandleTimeout(
+		ctx context.Context, process *Process, timeout Timeout,
+	) error
+}
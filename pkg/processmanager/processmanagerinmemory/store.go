@@ -0,0 +1,86 @@
+package processmanagerinmemory
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/processmanager"
+)
+
+var _ processmanager.Store = (*Store)(nil)
+
+type processKey struct {
+	manager string
+	id      string
+}
+
+type Store struct {
+	mu        sync.Mutex
+	processes map[processKey]processmanager.Process
+}
+
+func NewStore() *Store {
+	return &Store{
+		processes: make(map[processKey]processmanager.Process),
+	}
+}
+
+func (s *Store) LoadProcess(
+	ctx context.Context, manager string, id string,
+) (*processmanager.Process, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	process, ok := s.processes[processKey{manager, id}]
+	if !ok {
+		return nil, nil
+	}
+
+	return clone(process), nil
+}
+
+func (s *Store) SaveProcess(
+	ctx context.Context, manager string, process *processmanager.Process,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.processes[processKey{manager, process.ID}] = *clone(*process)
+
+	return nil
+}
+
+func (s *Store) ListDueProcesses(
+	ctx context.Context, manager string, now time.Time,
+) ([]*processmanager.Process, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*processmanager.Process
+	for key, process := range s.processes {
+		if key.manager != manager {
+			continue
+		}
+		if slices.ContainsFunc(process.Timeouts, func(t processmanager.Timeout) bool {
+			return !t.At.After(now)
+		}) {
+			due = append(due, clone(process))
+		}
+	}
+
+	return due, nil
+}
+
+func clone(process processmanager.Process) *processmanager.Process {
+	return &processmanager.Process{
+		ID:            process.ID,
+		TenantID:      process.TenantID,
+		CorrelationID: process.CorrelationID,
+		State:         slices.Clone(process.State),
+		Done:          process.Done,
+		Position:      process.Position,
+		Timeouts:      slices.Clone(process.Timeouts),
+	}
+}
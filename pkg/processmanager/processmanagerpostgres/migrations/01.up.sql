@@ -0,0 +1,20 @@
+BEGIN;
+
+CREATE TABLE es_processes (
+    manager TEXT NOT NULL,
+    id TEXT NOT NULL,
+    tenant_id TEXT NOT NULL,
+    correlation_id TEXT NOT NULL,
+    state BYTEA NOT NULL,
+    done BOOLEAN NOT NULL,
+    position BIGINT NOT NULL,
+    timeouts JSONB NOT NULL,
+    next_timeout_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (manager, id)
+);
+
+CREATE INDEX ON es_processes (manager, next_timeout_at)
+WHERE
+    next_timeout_at IS NOT NULL;
+
+END;
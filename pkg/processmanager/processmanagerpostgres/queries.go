@@ -0,0 +1,14 @@
+package processmanagerpostgres
+
+import _ "embed"
+
+var (
+	//go:embed queries/load_process.sql
+	loadProcessQuery string
+
+	//go:embed queries/save_process.sql
+	saveProcessQuery string
+
+	//go:embed queries/list_due_processes.sql
+	listDueProcessesQuery string
+)
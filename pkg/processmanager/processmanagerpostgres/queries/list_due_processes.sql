@@ -0,0 +1,15 @@
+SELECT
+    id,
+    tenant_id,
+    correlation_id,
+    state,
+    done,
+    position,
+    timeouts
+FROM
+    es_processes
+WHERE
+    manager = @manager
+    AND next_timeout_at <= @now
+ORDER BY
+    next_timeout_at;
@@ -0,0 +1,13 @@
+SELECT
+    id,
+    tenant_id,
+    correlation_id,
+    state,
+    done,
+    position,
+    timeouts
+FROM
+    es_processes
+WHERE
+    manager = @manager
+    AND id = @id;
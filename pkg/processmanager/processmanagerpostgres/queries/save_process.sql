@@ -0,0 +1,11 @@
+INSERT INTO es_processes (manager, id, tenant_id, correlation_id, state,
+    done, position, timeouts, next_timeout_at)
+    VALUES (@manager, @id, @tenant_id, @correlation_id, @state, @done,
+        @position, @timeouts, @next_timeout_at)
+ON CONFLICT (manager, id)
+    DO UPDATE SET
+        state = excluded.state,
+        done = excluded.done,
+        position = excluded.position,
+        timeouts = excluded.timeouts,
+        next_timeout_at = excluded.next_timeout_at;
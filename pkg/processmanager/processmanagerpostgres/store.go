@@ -0,0 +1,122 @@
+package processmanagerpostgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rnovatorov/go-eventsource/pkg/processmanager"
+)
+
+var _ processmanager.Store = (*Store)(nil)
+
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{
+		pool: pool,
+	}
+}
+
+type timeoutRecord struct {
+	Name string    `json:"name"`
+	At   time.Time `json:"at"`
+}
+
+func (s *Store) LoadProcess(
+	ctx context.Context, manager string, id string,
+) (*processmanager.Process, error) {
+	rows, _ := s.pool.Query(ctx, loadProcessQuery, pgx.NamedArgs{
+		"manager": manager,
+		"id":      id,
+	})
+
+	process, err := pgx.CollectExactlyOneRow(rows, collectProcess)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return process, nil
+}
+
+func (s *Store) SaveProcess(
+	ctx context.Context, manager string, process *processmanager.Process,
+) error {
+	timeouts := make([]timeoutRecord, len(process.Timeouts))
+	var nextTimeoutAt *time.Time
+	for i, t := range process.Timeouts {
+		timeouts[i] = timeoutRecord{Name: t.Name, At: t.At}
+		if nextTimeoutAt == nil || t.At.Before(*nextTimeoutAt) {
+			nextTimeoutAt = &t.At
+		}
+	}
+
+	timeoutsBytes, err := json.Marshal(timeouts)
+	if err != nil {
+		return fmt.Errorf("marshal timeouts: %w", err)
+	}
+
+	state := process.State
+	if state == nil {
+		state = []byte{}
+	}
+
+	_, err = s.pool.Exec(ctx, saveProcessQuery, pgx.NamedArgs{
+		"manager":         manager,
+		"id":              process.ID,
+		"tenant_id":       process.TenantID,
+		"correlation_id":  process.CorrelationID,
+		"state":           state,
+		"done":            process.Done,
+		"position":        process.Position,
+		"timeouts":        timeoutsBytes,
+		"next_timeout_at": nextTimeoutAt,
+	})
+	return err
+}
+
+func (s *Store) ListDueProcesses(
+	ctx context.Context, manager string, now time.Time,
+) ([]*processmanager.Process, error) {
+	rows, _ := s.pool.Query(ctx, listDueProcessesQuery, pgx.NamedArgs{
+		"manager": manager,
+		"now":     now,
+	})
+
+	return pgx.CollectRows(rows, collectProcess)
+}
+
+func collectProcess(row pgx.CollectableRow) (*processmanager.Process, error) {
+	var p processmanager.Process
+	var timeoutsBytes []byte
+
+	if err := row.Scan(
+		&p.ID, &p.TenantID, &p.CorrelationID, &p.State, &p.Done,
+		&p.Position, &timeoutsBytes,
+	); err != nil {
+		return nil, fmt.Errorf("scan row: %w", err)
+	}
+
+	var timeouts []timeoutRecord
+	if err := json.Unmarshal(timeoutsBytes, &timeouts); err != nil {
+		return nil, fmt.Errorf("unmarshal timeouts: %w", err)
+	}
+	for _, t := range timeouts {
+		p.Timeouts = append(p.Timeouts, processmanager.Timeout{
+			Name: t.Name,
+			At:   t.At,
+		})
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,22 @@
+package processmanager
+
+import (
+	"context"
+	"time"
+)
+
+// Store persists the processes of process managers.
+type Store interface {
+	// LoadProcess returns nil if the manager has no process with the ID.
+	LoadProcess(
+		ctx context.Context, manager string, id string,
+	) (*Process, error)
+	SaveProcess(
+		ctx context.Context, manager string, process *Process,
+	) error
+	// ListDueProcesses lists the processes of the manager with a timeout
+	// at or before now.
+	ListDueProcesses(
+		ctx context.Context, manager string, now time.Time,
+	) ([]*Process, error)
+}
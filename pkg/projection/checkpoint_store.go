@@ -0,0 +1,17 @@
+package projection
+
+import (
+	"context"
+)
+
+// CheckpointStore keeps the global position up to which each projection has
+// handled events.
+type CheckpointStore interface {
+	// LoadCheckpoint returns 0 for a projection without a checkpoint.
+	LoadCheckpoint(
+		ctx context.Context, name string,
+	) (int64, error)
+	SaveCheckpoint(
+		ctx context.Context, name string, position int64,
+	) error
+}
@@ -0,0 +1,95 @@
+package projection
+
+import (
+	"time"
+)
+
+type config struct {
+	batchSize        int
+	pollInterval     time.Duration
+	progress         ProgressFunc
+	handleAttempts   int
+	handleBackoff    func(attempt int) time.Duration
+	deadLetters      DeadLetterStore
+	haltOnDeadLetter bool
+	metrics          MetricsCollector
+}
+
+func newConfig(opts ...option) config {
+	cfg := config{
+		batchSize:      100,
+		pollInterval:   time.Second,
+		handleAttempts: 1,
+		metrics:        NopMetricsCollector{},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+type option func(*config)
+
+// WithBatchSize sets how many events are read at a time. The checkpoint is
+// saved after each batch.
+func WithBatchSize(size int) option {
+	return func(cfg *config) {
+		cfg.batchSize = size
+	}
+}
+
+// WithPollInterval sets how long to wait before reading again once all
+// events have been handled.
+func WithPollInterval(interval time.Duration) option {
+	return func(cfg *config) {
+		cfg.pollInterval = interval
+	}
+}
+
+// ProgressFunc is called by Runner.Rebuild after each batch with the global
+// position reached and the last global position at the start of the
+// rebuild. Positions of deleted events are counted too.
+type ProgressFunc = func(position int64, total int64)
+
+func WithProgress(progress ProgressFunc) option {
+	return func(cfg *config) {
+		cfg.progress = progress
+	}
+}
+
+// WithHandleRetry makes the runner attempt to handle each event up to
+// maxAttempts times, waiting backoff(attempt) in between unless backoff is
+// nil. By default each event is attempted once.
+func WithHandleRetry(
+	maxAttempts int, backoff func(attempt int) time.Duration,
+) option {
+	return func(cfg *config) {
+		cfg.handleAttempts = max(maxAttempts, 1)
+		cfg.handleBackoff = backoff
+	}
+}
+
+// WithDeadLetters makes the runner record the events that a projection
+// fails to handle in all attempts to store, and carry on with the following
+// events rather than stop. Events skipped this way are handled again by
+// Runner.Redrive.
+func WithDeadLetters(store DeadLetterStore) option {
+	return func(cfg *config) {
+		cfg.deadLetters = store
+	}
+}
+
+// WithHaltOnDeadLetter makes the runner stop after recording a dead letter,
+// as it does without WithDeadLetters, instead of skipping the event.
+func WithHaltOnDeadLetter() option {
+	return func(cfg *config) {
+		cfg.haltOnDeadLetter = true
+	}
+}
+
+// WithMetricsCollector makes the runner report dead letters to collector.
+func WithMetricsCollector(collector MetricsCollector) option {
+	return func(cfg *config) {
+		cfg.metrics = collector
+	}
+}
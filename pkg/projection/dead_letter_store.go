@@ -0,0 +1,35 @@
+package projection
+
+import (
+	"context"
+	"time"
+)
+
+// DeadLetter records an event that a projection failed to handle, see
+// WithDeadLetters.
+type DeadLetter struct {
+	Projection     string
+	EventID        string
+	GlobalPosition int64
+	Error          string
+	Attempts       int
+	Timestamp      time.Time
+}
+
+// DeadLetterStore keeps dead letters until they are re-driven, see
+// Runner.Redrive.
+type DeadLetterStore interface {
+	// SaveDeadLetter replaces the dead letter of the same projection and
+	// event, if any.
+	SaveDeadLetter(
+		ctx context.Context, deadLetter DeadLetter,
+	) error
+	// ListDeadLetters lists the dead letters of the projection ordered by
+	// global position.
+	ListDeadLetters(
+		ctx context.Context, projection string,
+	) ([]DeadLetter, error)
+	DeleteDeadLetter(
+		ctx context.Context, projection string, eventID string,
+	) error
+}
@@ -0,0 +1,9 @@
+package projection
+
+import "errors"
+
+var (
+	ErrProjectionRunning = errors.New("projection running")
+	ErrReadModelNotFound = errors.New("read model not found")
+	ErrRunPanicked       = errors.New("run panicked")
+)
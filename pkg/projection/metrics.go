@@ -0,0 +1,13 @@
+package projection
+
+// MetricsCollector records the events that projections fail to handle, e.g.
+// by adapting a prometheus.Registerer, so that dead letters can be alerted
+// on.
+type MetricsCollector interface {
+	IncDeadLetters(projection string)
+}
+
+// NopMetricsCollector discards all metrics.
+type NopMetricsCollector struct{}
+
+func (NopMetricsCollector) IncDeadLetters(string) {}
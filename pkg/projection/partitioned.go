@@ -0,0 +1,275 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// RunPartitioned is like Run, but hands the events to the given number of
+// partitions, each handling the events of the aggregates hashed to it in a
+// goroutine of its own. Events of an aggregate are thus handled in order,
+// while events of different aggregates are handled concurrently, so the
+// projection has to be safe for concurrent use.
+//
+// Each partition keeps a checkpoint of its own, under the name of the
+// projection suffixed with the partition and the number of partitions. The
+// checkpoint of the projection itself is kept at the lowest of them, the
+// position up to which all events have been handled, so that Run and
+// RunPartitioned can take over from each other. When the number of
+// partitions changes, the new partitions have no checkpoints yet and start
+// from that of the projection, handling again the events that some of the
+// old partitions had already handled, as Run does after a failure.
+//
+// A partition failing to handle an event, or panicking, cancels the others
+// and the dispatching of events, and RunPartitioned returns its error once
+// they have all stopped.
+func (r *Runner) RunPartitioned(
+	ctx context.Context, projection Projection, partitions int,
+) error {
+	if partitions < 2 {
+		return r.Run(ctx, projection)
+	}
+
+	name := projection.Name()
+
+	if err := r.acquire(name); err != nil {
+		return err
+	}
+	defer r.release(name)
+
+	global, err := r.checkpoints.LoadCheckpoint(ctx, name)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("load checkpoint: %w", err)
+	}
+
+	starts := make([]int64, partitions)
+	for i := range starts {
+		position, err := r.checkpoints.LoadCheckpoint(
+			ctx, partitionCheckpointName(name, i, partitions))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("load checkpoint of partition %d: %w", i, err)
+		}
+		// A checkpoint behind that of the projection is left over from
+		// an earlier run with as many partitions.
+		starts[i] = max(position, global)
+	}
+
+	p := &partitionedRun{
+		runner:     r,
+		projection: projection,
+		name:       name,
+		queues:     make([]chan partitionItem, partitions),
+		positions:  slices.Clone(starts),
+		saved:      global,
+	}
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for i := range p.queues {
+		p.queues[i] = make(chan partitionItem, r.config.batchSize)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.runPartition(runCtx, i, starts[i]); err != nil {
+				cancel(err)
+			}
+		}()
+	}
+
+	if err := p.dispatch(runCtx, slices.Min(starts)); err != nil {
+		cancel(err)
+	}
+	cancel(nil)
+	wg.Wait()
+
+	if err := context.Cause(runCtx); ctx.Err() == nil &&
+		!errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return nil
+}
+
+func partitionCheckpointName(name string, partition int, partitions int) string {
+	return fmt.Sprintf("%s/%d-of-%d", name, partition, partitions)
+}
+
+func partitionOf(aggregateID string, partitions int) int {
+	h := fnv.New32a()
+	h.Write([]byte(aggregateID))
+	return int(h.Sum32() % uint32(partitions))
+}
+
+// partitionItem is either an event to handle or a mark telling the
+// partition that all events up to the position have been dispatched.
+type partitionItem struct {
+	event *eventstore.Event
+	mark  int64
+}
+
+type partitionedRun struct {
+	runner     *Runner
+	projection Projection
+	name       string
+	queues     []chan partitionItem
+	mu         sync.Mutex
+	positions  []int64
+	saved      int64
+}
+
+// dispatch reads the events after position and queues them to their
+// partitions until ctx is done, marking the end of every batch.
+func (p *partitionedRun) dispatch(ctx context.Context, position int64) error {
+	logCtx := eventstore.WithAllTenants(ctx)
+
+	for {
+		events, err := p.runner.eventStore.ListAllEvents(
+			logCtx, position, p.runner.config.batchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("list events: %w", err)
+		}
+
+		if len(events) == 0 {
+			// Partitions may have caught up since the last batch.
+			if err := p.saveCheckpoint(ctx); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("save checkpoint: %w", err)
+			}
+			timer := time.NewTimer(p.runner.config.pollInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+				continue
+			}
+		}
+
+		for _, event := range events {
+			queue := p.queues[partitionOf(event.AggregateID, len(p.queues))]
+			if !p.enqueue(ctx, queue, partitionItem{event: event}) {
+				return nil
+			}
+			position = event.GlobalPosition
+		}
+
+		for _, queue := range p.queues {
+			if !p.enqueue(ctx, queue, partitionItem{mark: position}) {
+				return nil
+			}
+		}
+
+		if err := p.saveCheckpoint(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("save checkpoint: %w", err)
+		}
+	}
+}
+
+func (p *partitionedRun) enqueue(
+	ctx context.Context, queue chan<- partitionItem, item partitionItem,
+) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case queue <- item:
+		return true
+	}
+}
+
+// runPartition handles the events queued to the partition, skipping those
+// up to its checkpoint, and advances the checkpoint at every mark. Panics
+// are recovered as errors wrapping ErrRunPanicked, besides those of Handle,
+// see Run.
+func (p *partitionedRun) runPartition(
+	ctx context.Context, partition int, start int64,
+) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("partition %d: %w: %v", partition, ErrRunPanicked, r)
+		}
+	}()
+
+	checkpoint := partitionCheckpointName(p.name, partition, len(p.queues))
+
+	for {
+		var item partitionItem
+		select {
+		case <-ctx.Done():
+			return nil
+		case item = <-p.queues[partition]:
+		}
+
+		if event := item.event; event != nil {
+			if event.GlobalPosition <= start {
+				continue
+			}
+			if err := p.runner.handle(ctx, p.projection, event); err != nil {
+				return fmt.Errorf("handle event %s at position %d: %w",
+					event.ID, event.GlobalPosition, err)
+			}
+			continue
+		}
+
+		if item.mark <= start {
+			continue
+		}
+
+		if err := p.runner.checkpoints.SaveCheckpoint(
+			ctx, checkpoint, item.mark,
+		); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("save checkpoint of partition %d: %w",
+				partition, err)
+		}
+
+		p.mu.Lock()
+		p.positions[partition] = item.mark
+		p.mu.Unlock()
+	}
+}
+
+// saveCheckpoint saves the checkpoint of the projection at the lowest
+// checkpoint of the partitions, if it has advanced.
+func (p *partitionedRun) saveCheckpoint(ctx context.Context) error {
+	p.mu.Lock()
+	position := slices.Min(p.positions)
+	p.mu.Unlock()
+
+	if position <= p.saved {
+		return nil
+	}
+
+	if err := p.runner.checkpoints.SaveCheckpoint(
+		ctx, p.name, position,
+	); err != nil {
+		return err
+	}
+	p.saved = position
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package projection
+
+import (
+	"context"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// Projection builds a read model from the events of all aggregates. Name
+// identifies its checkpoint, so it must not change between runs.
+type Projection interface {
+	Name() string
+	Handle(ctx context.Context, event *eventstore.Event) error
+}
+
+// Resettable is implemented by projections that can clear their read model,
+// which Runner.Rebuild does before replaying the events.
+type Resettable interface {
+	Reset(ctx context.Context) error
+}
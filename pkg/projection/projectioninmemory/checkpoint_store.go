@@ -0,0 +1,43 @@
+package projectioninmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rnovatorov/go-eventsource/pkg/projection"
+)
+
+var _ projection.CheckpointStore = (*CheckpointStore)(nil)
+
+// CheckpointStore suits projections whose read model is kept in memory as
+// well, so that both start from scratch together.
+type CheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]int64
+}
+
+func NewCheckpointStore() *CheckpointStore {
+	return &CheckpointStore{
+		checkpoints: make(map[string]int64),
+	}
+}
+
+func (s *CheckpointStore) LoadCheckpoint(
+	ctx context.Context, name string,
+) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.checkpoints[name], nil
+}
+
+func (s *CheckpointStore) SaveCheckpoint(
+	ctx context.Context, name string, position int64,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoints[name] = position
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package projectioninmemory
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/rnovatorov/go-eventsource/pkg/projection"
+)
+
+var _ projection.DeadLetterStore = (*DeadLetterStore)(nil)
+
+type DeadLetterStore struct {
+	mu          sync.Mutex
+	deadLetters map[string]map[string]projection.DeadLetter
+}
+
+func NewDeadLetterStore() *DeadLetterStore {
+	return &DeadLetterStore{
+		deadLetters: make(map[string]map[string]projection.DeadLetter),
+	}
+}
+
+func (s *DeadLetterStore) SaveDeadLetter(
+	ctx context.Context, deadLetter projection.DeadLetter,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deadLetters, ok := s.deadLetters[deadLetter.Projection]
+	if !ok {
+		deadLetters = make(map[string]projection.DeadLetter)
+		s.deadLetters[deadLetter.Projection] = deadLetters
+	}
+	deadLetters[deadLetter.EventID] = deadLetter
+
+	return nil
+}
+
+func (s *DeadLetterStore) ListDeadLetters(
+	ctx context.Context, name string,
+) ([]projection.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.SortedFunc(maps.Values(s.deadLetters[name]),
+		func(a, b projection.DeadLetter) int {
+			return cmp.Compare(a.GlobalPosition, b.GlobalPosition)
+		}), nil
+}
+
+func (s *DeadLetterStore) DeleteDeadLetter(
+	ctx context.Context, name string, eventID string,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.deadLetters[name], eventID)
+
+	return nil
+}
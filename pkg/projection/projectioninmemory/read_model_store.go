@@ -0,0 +1,83 @@
+package projectioninmemory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/rnovatorov/go-eventsource/pkg/projection"
+)
+
+var _ projection.ReadModelStore[any] = (*ReadModelStore[any])(nil)
+
+// ReadModelStore keeps documents in a map. List sorts the IDs on every call,
+// which suits read models of moderate size.
+type ReadModelStore[V any] struct {
+	mu   sync.RWMutex
+	docs map[string]V
+}
+
+func NewReadModelStore[V any]() *ReadModelStore[V] {
+	return &ReadModelStore[V]{
+		docs: make(map[string]V),
+	}
+}
+
+func (s *ReadModelStore[V]) Get(ctx context.Context, id string) (V, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return doc, projection.ErrReadModelNotFound
+	}
+
+	return doc, nil
+}
+
+func (s *ReadModelStore[V]) List(
+	ctx context.Context, afterID string, limit int,
+) ([]V, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var docs []V
+	for _, id := range slices.Sorted(maps.Keys(s.docs)) {
+		if limit > 0 && len(docs) == limit {
+			break
+		}
+		if id > afterID {
+			docs = append(docs, s.docs[id])
+		}
+	}
+
+	return docs, nil
+}
+
+func (s *ReadModelStore[V]) Put(ctx context.Context, id string, doc V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.docs[id] = doc
+
+	return nil
+}
+
+func (s *ReadModelStore[V]) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.docs, id)
+
+	return nil
+}
+
+func (s *ReadModelStore[V]) Clear(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.docs)
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package projectionpostgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rnovatorov/go-eventsource/pkg/projection"
+)
+
+var _ projection.CheckpointStore = (*CheckpointStore)(nil)
+
+type CheckpointStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewCheckpointStore(pool *pgxpool.Pool) *CheckpointStore {
+	return &CheckpointStore{
+		pool: pool,
+	}
+}
+
+func (s *CheckpointStore) LoadCheckpoint(
+	ctx context.Context, name string,
+) (int64, error) {
+	var position int64
+	if err := s.pool.QueryRow(ctx, loadCheckpointQuery, pgx.NamedArgs{
+		"name": name,
+	}).Scan(&position); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return position, nil
+}
+
+func (s *CheckpointStore) SaveCheckpoint(
+	ctx context.Context, name string, position int64,
+) error {
+	_, err := s.pool.Exec(ctx, saveCheckpointQuery, pgx.NamedArgs{
+		"name":     name,
+		"position": position,
+	})
+	return err
+}
@@ -0,0 +1,62 @@
+package projectionpostgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rnovatorov/go-eventsource/pkg/projection"
+)
+
+var _ projection.DeadLetterStore = (*DeadLetterStore)(nil)
+
+type DeadLetterStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewDeadLetterStore(pool *pgxpool.Pool) *DeadLetterStore {
+	return &DeadLetterStore{
+		pool: pool,
+	}
+}
+
+func (s *DeadLetterStore) SaveDeadLetter(
+	ctx context.Context, deadLetter projection.DeadLetter,
+) error {
+	_, err := s.pool.Exec(ctx, saveDeadLetterQuery, pgx.NamedArgs{
+		"projection":      deadLetter.Projection,
+		"event_id":        deadLetter.EventID,
+		"global_position": deadLetter.GlobalPosition,
+		"error":           deadLetter.Error,
+		"attempts":        deadLetter.Attempts,
+		"timestamp":       deadLetter.Timestamp,
+	})
+	return err
+}
+
+func (s *DeadLetterStore) ListDeadLetters(
+	ctx context.Context, name string,
+) ([]projection.DeadLetter, error) {
+	rows, _ := s.pool.Query(ctx, listDeadLettersQuery, pgx.NamedArgs{
+		"projection": name,
+	})
+
+	return pgx.CollectRows(rows,
+		func(row pgx.CollectableRow) (projection.DeadLetter, error) {
+			var dl projection.DeadLetter
+			err := row.Scan(&dl.Projection, &dl.EventID, &dl.GlobalPosition,
+				&dl.Error, &dl.Attempts, &dl.Timestamp)
+			return dl, err
+		})
+}
+
+func (s *DeadLetterStore) DeleteDeadLetter(
+	ctx context.Context, name string, eventID string,
+) error {
+	_, err := s.pool.Exec(ctx, deleteDeadLetterQuery, pgx.NamedArgs{
+		"projection": name,
+		"event_id":   eventID,
+	})
+	return err
+}
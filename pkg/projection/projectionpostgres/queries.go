@@ -0,0 +1,20 @@
+package projectionpostgres
+
+import _ "embed"
+
+var (
+	//go:embed queries/load_checkpoint.sql
+	loadCheckpointQuery string
+
+	//go:embed queries/save_checkpoint.sql
+	saveCheckpointQuery string
+
+	//go:embed queries/save_dead_letter.sql
+	saveDeadLetterQuery string
+
+	//go:embed queries/list_dead_letters.sql
+	listDeadLettersQuery string
+
+	//go:embed queries/delete_dead_letter.sql
+	deleteDeadLetterQuery string
+)
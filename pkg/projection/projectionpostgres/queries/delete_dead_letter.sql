@@ -0,0 +1,3 @@
+DELETE FROM es_projection_dead_letters
+WHERE projection = @projection
+    AND event_id = @event_id;
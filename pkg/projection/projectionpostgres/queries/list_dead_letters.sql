@@ -0,0 +1,13 @@
+SELECT
+    projection,
+    event_id,
+    global_position,
+    error,
+    attempts,
+    timestamp
+FROM
+    es_projection_dead_letters
+WHERE
+    projection = @projection
+ORDER BY
+    global_position;
@@ -0,0 +1,6 @@
+SELECT
+    position
+FROM
+    es_projection_checkpoints
+WHERE
+    name = @name;
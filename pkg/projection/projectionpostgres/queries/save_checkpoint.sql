@@ -0,0 +1,5 @@
+INSERT INTO es_projection_checkpoints (name, position)
+    VALUES (@name, @position)
+ON CONFLICT (name)
+    DO UPDATE SET
+        position = excluded.position;
@@ -0,0 +1,5 @@
+INSERT INTO es_projection_dead_letters (projection, event_id, global_position, error, attempts, timestamp)
+    VALUES (@projection, @event_id, @global_position, @error, @attempts, @timestamp)
+ON CONFLICT (projection, event_id)
+    DO UPDATE SET
+        error = excluded.error, attempts = excluded.attempts, timestamp = excluded.timestamp;
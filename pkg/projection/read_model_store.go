@@ -0,0 +1,32 @@
+package projection
+
+import (
+	"context"
+)
+
+// ReadModelStore keeps the documents of a read model by ID. Projections
+// write to it as they handle events and queries read from it, so that
+// reading does not require replaying aggregates.
+type ReadModelStore[V any] interface {
+	// Get fails with ErrReadModelNotFound if there is no document with the
+	// ID.
+	Get(
+		ctx context.Context, id string,
+	) (V, error)
+	// List lists up to limit documents with an ID greater than afterID,
+	// ordered by ID. A limit of 0 means no limit.
+	List(
+		ctx context.Context, afterID string, limit int,
+	) ([]V, error)
+	Put(
+		ctx context.Context, id string, doc V,
+	) error
+	Delete(
+		ctx context.Context, id string,
+	) error
+	// Clear deletes all documents, for projections to implement
+	// Resettable.
+	Clear(
+		ctx context.Context,
+	) error
+}
@@ -0,0 +1,346 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+type Runner struct {
+	eventStore  eventstore.Interface
+	checkpoints CheckpointStore
+	config      config
+	mu          sync.Mutex
+	running     map[string]struct{}
+}
+
+func NewRunner(
+	eventStore eventstore.Interface, checkpoints CheckpointStore,
+	opts ...option,
+) *Runner {
+	return &Runner{
+		eventStore:  eventStore,
+		checkpoints: checkpoints,
+		config:      newConfig(opts...),
+		running:     make(map[string]struct{}),
+	}
+}
+
+// Run feeds the projection the events following its checkpoint, polling for
+// new ones, until ctx is done, in which case it returns nil once the event
+// being handled, if any, has been handled. The checkpoint is advanced after
+// each batch. If the projection fails to handle an event, Run returns the
+// error without advancing the checkpoint past the batch, so the next run
+// handles the failed event again, along with the events of the batch before
+// it, unless the event is dead-lettered, see WithDeadLetters. Handle
+// panicking counts as failing with an error wrapping
+// eventstore.ErrEventHandlerPanicked. It fails with ErrProjectionRunning if
+// the runner is already running or rebuilding a projection with the same
+// name.
+func (r *Runner) Run(ctx context.Context, projection Projection) error {
+	name := projection.Name()
+
+	if err := r.acquire(name); err != nil {
+		return err
+	}
+	defer r.release(name)
+
+	position, err := r.checkpoints.LoadCheckpoint(ctx, name)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("load checkpoint: %w", err)
+	}
+
+	return r.run(ctx, projection, position, 0)
+}
+
+// Rebuild resets the projection, if it is Resettable, and its checkpoint,
+// then replays the events up to the last one saved when the rebuild
+// started, reporting progress to the WithProgress callback. It returns
+// ctx.Err() if ctx is done before the rebuild completes. Like Run, it fails
+// with ErrProjectionRunning if the projection is already running.
+func (r *Runner) Rebuild(ctx context.Context, projection Projection) error {
+	name := projection.Name()
+
+	if err := r.acquire(name); err != nil {
+		return err
+	}
+	defer r.release(name)
+
+	total, err := r.eventStore.LastGlobalPosition(ctx)
+	if err != nil {
+		return fmt.Errorf("get last global position: %w", err)
+	}
+
+	if resettable, ok := projection.(Resettable); ok {
+		if err := resettable.Reset(ctx); err != nil {
+			return fmt.Errorf("reset: %w", err)
+		}
+	}
+
+	if err := r.checkpoints.SaveCheckpoint(ctx, name, 0); err != nil {
+		return fmt.Errorf("reset checkpoint: %w", err)
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	if err := r.run(ctx, projection, 0, total); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// run handles events after position until ctx is done or, if until is not
+// 0, the position until is reached or passed, the latter when the events up
+// to until have been deleted since.
+func (r *Runner) run(
+	ctx context.Context, projection Projection, position int64, until int64,
+) error {
+	name := projection.Name()
+
+	// Projections span all tenants, each event telling its own.
+	logCtx := eventstore.WithAllTenants(ctx)
+
+	for {
+		events, err := r.eventStore.ListAllEvents(
+			logCtx, position, r.config.batchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("list events: %w", err)
+		}
+
+		if len(events) == 0 {
+			// The events up to until may have been deleted since.
+			if until != 0 {
+				return nil
+			}
+			timer := time.NewTimer(r.config.pollInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+				continue
+			}
+		}
+
+		passedUntil := false
+		for _, event := range events {
+			if until != 0 && event.GlobalPosition > until {
+				passedUntil = true
+				break
+			}
+			if err := r.handle(ctx, projection, event); err != nil {
+				return fmt.Errorf("handle event %s at position %d: %w",
+					event.ID, event.GlobalPosition, err)
+			}
+			position = event.GlobalPosition
+		}
+
+		if err := r.checkpoints.SaveCheckpoint(ctx, name, position); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("save checkpoint: %w", err)
+		}
+
+		if until != 0 {
+			if r.config.progress != nil {
+				r.config.progress(position, until)
+			}
+			if passedUntil || position >= until {
+				return nil
+			}
+		}
+	}
+}
+
+// handle makes the configured number of attempts to handle the event, then
+// dead-letters it if a DeadLetterStore is configured, returning nil unless
+// the runner is to halt.
+func (r *Runner) handle(
+	ctx context.Context, projection Projection, event *eventstore.Event,
+) error {
+	var (
+		attempt int
+		err     error
+	)
+	for attempt = 1; ; attempt++ {
+		if err = callHandle(ctx, projection, event); err == nil {
+			return nil
+		}
+		if attempt >= r.config.handleAttempts || ctx.Err() != nil {
+			break
+		}
+		if backoff := r.config.handleBackoff; backoff != nil {
+			timer := time.NewTimer(backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+	}
+
+	// Failures caused by stopping are not the fault of the event.
+	if r.config.deadLetters == nil || ctx.Err() != nil {
+		return err
+	}
+
+	name := projection.Name()
+
+	if saveErr := r.config.deadLetters.SaveDeadLetter(ctx, DeadLetter{
+		Projection:     name,
+		EventID:        event.ID,
+		GlobalPosition: event.GlobalPosition,
+		Error:          err.Error(),
+		Attempts:       attempt,
+		Timestamp:      time.Now(),
+	}); saveErr != nil {
+		return fmt.Errorf("save dead letter: %w (handle: %w)", saveErr, err)
+	}
+
+	r.config.metrics.IncDeadLetters(name)
+
+	if r.config.haltOnDeadLetter {
+		return err
+	}
+
+	return nil
+}
+
+// callHandle hands the event to the projection, turning a panic into an
+// error wrapping eventstore.ErrEventHandlerPanicked, which is retried and
+// dead-lettered like any other.
+func callHandle(
+	ctx context.Context, projection Projection, event *eventstore.Event,
+) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", eventstore.ErrEventHandlerPanicked, r)
+		}
+	}()
+
+	return projection.Handle(ctx, event)
+}
+
+// Redrive hands the projection its dead-lettered events again, in order of
+// global position, deleting the dead letter of each event it handles. The
+// events are handled after those following them, so projections have to
+// cope with that. Redrive stops at the first event the projection fails to
+// handle, which stays dead-lettered. Like Run, it fails with
+// ErrProjectionRunning if the projection is running, so it is meant to be
+// called before Run once the cause of the failures has been fixed. Without
+// WithDeadLetters, there is nothing to re-drive.
+func (r *Runner) Redrive(ctx context.Context, projection Projection) error {
+	if r.config.deadLetters == nil {
+		return nil
+	}
+
+	name := projection.Name()
+
+	if err := r.acquire(name); err != nil {
+		return err
+	}
+	defer r.release(name)
+
+	deadLetters, err := r.config.deadLetters.ListDeadLetters(ctx, name)
+	if err != nil {
+		return fmt.Errorf("list dead letters: %w", err)
+	}
+
+	for _, deadLetter := range deadLetters {
+		events, err := r.eventStore.ListAllEvents(
+			eventstore.WithAllTenants(ctx), deadLetter.GlobalPosition-1, 1)
+		if err != nil {
+			return fmt.Errorf("list events: %w", err)
+		}
+
+		// The event may have been deleted since.
+		if len(events) == 1 && events[0].ID == deadLetter.EventID {
+			if err := callHandle(ctx, projection, events[0]); err != nil {
+				return fmt.Errorf("handle event %s at position %d: %w",
+					deadLetter.EventID, deadLetter.GlobalPosition, err)
+			}
+		}
+
+		if err := r.config.deadLetters.DeleteDeadLetter(
+			ctx, name, deadLetter.EventID,
+		); err != nil {
+			return fmt.Errorf("delete dead letter: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RunAll runs the projections concurrently, each as by Run, until ctx is
+// done or one of them fails, which cancels the others. It returns once all
+// of them have returned, with the errors of those that failed joined, each
+// prefixed with the name of its projection, or nil if they were all stopped
+// by ctx. Panics of a projection are recovered as errors wrapping
+// ErrRunPanicked, besides those of Handle, see Run.
+func (r *Runner) RunAll(ctx context.Context, projections ...Projection) error {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	errs := make([]error, len(projections))
+
+	var wg sync.WaitGroup
+	for i, projection := range projections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.runRecovered(runCtx, projection); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", projection.Name(), err)
+				cancel(errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (r *Runner) runRecovered(
+	ctx context.Context, projection Projection,
+) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%w: %v", ErrRunPanicked, rec)
+		}
+	}()
+
+	return r.Run(ctx, projection)
+}
+
+func (r *Runner) acquire(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.running[name]; ok {
+		return fmt.Errorf("%w: %s", ErrProjectionRunning, name)
+	}
+	r.running[name] = struct{}{}
+
+	return nil
+}
+
+func (r *Runner) release(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.running, name)
+}
@@ -0,0 +1,298 @@
+package projection_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoreinmemory"
+	"github.com/rnovatorov/go-eventsource/pkg/projection"
+	"github.com/rnovatorov/go-eventsource/pkg/projection/projectioninmemory"
+)
+
+// recorder is a projection recording the IDs of the events it handles,
+// calling onHandle first if set.
+type recorder struct {
+	mu       sync.Mutex
+	handled  []string
+	onHandle func(ctx context.Context, event *eventstore.Event) error
+}
+
+func (p *recorder) Name() string {
+	return "recorder"
+}
+
+func (p *recorder) Handle(ctx context.Context, event *eventstore.Event) error {
+	if p.onHandle != nil {
+		if err := p.onHandle(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handled = append(p.handled, event.ID)
+
+	return nil
+}
+
+func (p *recorder) Handled() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string(nil), p.handled...)
+}
+
+func saveEvent(
+	t testing.TB, store eventstore.Interface, aggregateID string, version int,
+) {
+	t.Helper()
+
+	if err := store.SaveEvents(
+		context.Background(), aggregateID, version-1, eventstore.Events{{
+			ID:               fmt.Sprintf("%s-%d", aggregateID, version),
+			AggregateID:      aggregateID,
+			AggregateVersion: version,
+			Timestamp:        time.Now(),
+			Metadata:         eventstore.Metadata{},
+			Type:             "test.Event",
+		}},
+	); err != nil {
+		t.Fatalf("save event %d of %s: %v", version, aggregateID, err)
+	}
+}
+
+func TestRebuildStopsPastDeletedTail(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := eventstoreinmemory.New()
+	checkpoints := projectioninmemory.NewCheckpointStore()
+	saveEvent(t, store, "a", 1)
+	saveEvent(t, store, "b", 1)
+
+	// The last event as of the start of the rebuild is deleted and another
+	// one appended while the rebuild is under way, so that the following
+	// batch only has an event past the end of the rebuild.
+	p := &recorder{
+		onHandle: func(ctx context.Context, event *eventstore.Event) error {
+			if event.ID != "a-1" {
+				return nil
+			}
+			if err := store.DeleteStream(ctx, "b"); err != nil {
+				return err
+			}
+			saveEvent(t, store, "c", 1)
+			return nil
+		},
+	}
+
+	runner := projection.NewRunner(store, checkpoints,
+		projection.WithBatchSize(1))
+	if err := runner.Rebuild(ctx, p); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+
+	if got := p.Handled(); len(got) != 1 || got[0] != "a-1" {
+		t.Fatalf("handled: got %q, want [a-1]", got)
+	}
+
+	checkpoint, err := checkpoints.LoadCheckpoint(ctx, p.Name())
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	if checkpoint != 1 {
+		t.Fatalf("checkpoint: got %d, want 1", checkpoint)
+	}
+}
+
+// named renames the projection it wraps.
+type named struct {
+	projection.Projection
+	name string
+}
+
+func (p named) Name() string {
+	return p.name
+}
+
+func panicOn(id string) func(context.Context, *eventstore.Event) error {
+	return func(ctx context.Context, event *eventstore.Event) error {
+		if event.ID == id {
+			panic("boom")
+		}
+		return nil
+	}
+}
+
+func TestHandlerPanicFailsRun(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := eventstoreinmemory.New()
+	checkpoints := projectioninmemory.NewCheckpointStore()
+	saveEvent(t, store, "a", 1)
+	saveEvent(t, store, "a", 2)
+
+	p := &recorder{onHandle: panicOn("a-2")}
+	runner := projection.NewRunner(store, checkpoints,
+		projection.WithBatchSize(1))
+
+	if err := runner.Run(ctx, p); !errors.Is(
+		err, eventstore.ErrEventHandlerPanicked,
+	) {
+		t.Fatalf("run: got %v, want %v", err, eventstore.ErrEventHandlerPanicked)
+	}
+
+	checkpoint, err := checkpoints.LoadCheckpoint(ctx, p.Name())
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	if checkpoint != 1 {
+		t.Fatalf("checkpoint: got %d, want 1", checkpoint)
+	}
+}
+
+func TestHandlerPanicCancelsPartitions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := eventstoreinmemory.New()
+	for i := range 8 {
+		saveEvent(t, store, fmt.Sprintf("agg-%d", i), 1)
+	}
+
+	p := &recorder{onHandle: panicOn("agg-3-1")}
+	runner := projection.NewRunner(
+		store, projectioninmemory.NewCheckpointStore(),
+		projection.WithPollInterval(time.Millisecond))
+
+	if err := runner.RunPartitioned(ctx, p, 4); !errors.Is(
+		err, eventstore.ErrEventHandlerPanicked,
+	) {
+		t.Fatalf("run partitioned: got %v, want %v",
+			err, eventstore.ErrEventHandlerPanicked)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("run partitioned was not stopped by the panic")
+	}
+}
+
+func TestRunAllCancelsOthersOnFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := eventstoreinmemory.New()
+	saveEvent(t, store, "a", 1)
+
+	runner := projection.NewRunner(
+		store, projectioninmemory.NewCheckpointStore(),
+		projection.WithPollInterval(time.Millisecond))
+
+	err := runner.RunAll(ctx,
+		named{&recorder{}, "healthy"},
+		named{&recorder{onHandle: panicOn("a-1")}, "panicking"})
+	if !errors.Is(err, eventstore.ErrEventHandlerPanicked) {
+		t.Fatalf("run all: got %v, want %v",
+			err, eventstore.ErrEventHandlerPanicked)
+	}
+	if want := "panicking: "; !strings.HasPrefix(err.Error(), want) {
+		t.Fatalf("run all: got %q, want it prefixed with %q", err, want)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("run all was not stopped by the failure")
+	}
+}
+
+func TestRunAllStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	store := eventstoreinmemory.New()
+	saveEvent(t, store, "a", 1)
+
+	var handled sync.WaitGroup
+	handled.Add(2)
+	onHandle := func(context.Context, *eventstore.Event) error {
+		handled.Done()
+		return nil
+	}
+
+	runner := projection.NewRunner(
+		store, projectioninmemory.NewCheckpointStore(),
+		projection.WithPollInterval(time.Millisecond))
+
+	go func() {
+		handled.Wait()
+		cancel()
+	}()
+
+	if err := runner.RunAll(ctx,
+		named{&recorder{onHandle: onHandle}, "first"},
+		named{&recorder{onHandle: onHandle}, "second"},
+	); err != nil {
+		t.Fatalf("run all: %v", err)
+	}
+}
+
+// countdown is a projection taking delay to handle each event, e.g. writing
+// it to a database, that calls done once it has handled n events.
+type countdown struct {
+	delay time.Duration
+	n     atomic.Int64
+	done  func()
+}
+
+func (p *countdown) Name() string {
+	return "countdown"
+}
+
+func (p *countdown) Handle(ctx context.Context, event *eventstore.Event) error {
+	time.Sleep(p.delay)
+	if p.n.Add(-1) == 0 {
+		p.done()
+	}
+	return nil
+}
+
+// BenchmarkRunPartitioned measures how the throughput of a projection
+// bound by the latency of handling each event scales with the partitions.
+func BenchmarkRunPartitioned(b *testing.B) {
+	const (
+		aggregates = 64
+		versions   = 16
+	)
+
+	store := eventstoreinmemory.New()
+	for version := 1; version <= versions; version++ {
+		for i := range aggregates {
+			saveEvent(b, store, fmt.Sprintf("agg-%d", i), version)
+		}
+	}
+
+	for _, partitions := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("partitions=%d", partitions), func(b *testing.B) {
+			for range b.N {
+				ctx, cancel := context.WithCancel(context.Background())
+				p := &countdown{delay: 100 * time.Microsecond, done: cancel}
+				p.n.Store(aggregates * versions)
+
+				runner := projection.NewRunner(
+					store, projectioninmemory.NewCheckpointStore())
+				if err := runner.RunPartitioned(ctx, p, partitions); err != nil {
+					b.Fatalf("run partitioned: %v", err)
+				}
+				cancel()
+			}
+			b.ReportMetric(
+				float64(aggregates*versions*b.N)/b.Elapsed().Seconds(),
+				"events/s")
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+)
+
+type config struct {
+	clock        eventsource.Clock
+	logger       *slog.Logger
+	pollInterval time.Duration
+	batchSize    int
+	maxAttempts  int
+	retryDelay   time.Duration
+	lease        time.Duration
+}
+
+func newConfig(opts ...option) config {
+	cfg := config{
+		clock:        eventsource.SystemClock{},
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		pollInterval: time.Second,
+		batchSize:    100,
+		maxAttempts:  3,
+		retryDelay:   time.Second,
+		lease:        time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+type option func(*config)
+
+func WithLogger(logger *slog.Logger) option {
+	return func(cfg *config) {
+		cfg.logger = logger
+	}
+}
+
+// WithClock makes the scheduler tell whether timers are due by the time told
+// by clock.
+func WithClock(clock eventsource.Clock) option {
+	return func(cfg *config) {
+		cfg.clock = clock
+	}
+}
+
+// WithPollInterval sets how often to look for due timers.
+func WithPollInterval(interval time.Duration) option {
+	return func(cfg *config) {
+		cfg.pollInterval = interval
+	}
+}
+
+// WithBatchSize sets how many due timers are read at a time.
+func WithBatchSize(size int) option {
+	return func(cfg *config) {
+		cfg.batchSize = size
+	}
+}
+
+// WithRetry sets how many times the command of a timer is dispatched before
+// the timer is given up on, and how long to wait between attempts.
+func WithRetry(maxAttempts int, delay time.Duration) option {
+	return func(cfg *config) {
+		cfg.maxAttempts = maxAttempts
+		cfg.retryDelay = delay
+	}
+}
+
+// WithLease sets for how long the timers a scheduler is firing are hidden
+// from other schedulers sharing the TimerStore. A timer that is neither
+// fired nor rescheduled by then, e.g. because the scheduler crashed, is
+// fired again, so the lease has to exceed the time it takes to fire a
+// batch of timers.
+func WithLease(lease time.Duration) option {
+	return func(cfg *config) {
+		cfg.lease = lease
+	}
+}
@@ -0,0 +1,8 @@
+package scheduler
+
+import "errors"
+
+var (
+	ErrTimerNotFound        = errors.New("timer not found")
+	ErrCommandNotRegistered = errors.New("command not registered")
+)
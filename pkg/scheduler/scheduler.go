@@ -0,0 +1,215 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// Scheduler dispatches commands to the command bus once they are due. Timers
+// survive restarts, being kept in a TimerStore, and are fired at least once:
+// a timer is deleted only after its command has been dispatched, with the
+// timer ID as the causation ID, so that aggregates can drop the command if
+// it is dispatched again. Schedulers in several processes can share a
+// TimerStore, each timer being leased to one of them at a time, see
+// WithLease.
+type Scheduler struct {
+	timers     TimerStore
+	commandBus *eventsource.CommandBus
+	config     config
+	mu         sync.RWMutex
+	types      map[string]reflect.Type
+}
+
+func NewScheduler(
+	timers TimerStore, commandBus *eventsource.CommandBus, opts ...option,
+) *Scheduler {
+	return &Scheduler{
+		timers:     timers,
+		commandBus: commandBus,
+		config:     newConfig(opts...),
+		types:      make(map[string]reflect.Type),
+	}
+}
+
+// Register allows commands of the type of cmd to be scheduled. The type is
+// recorded in timers by its name, so renaming it orphans the timers already
+// scheduled.
+func (s *Scheduler) Register(cmd eventsource.Command) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := reflect.TypeOf(cmd)
+	s.types[t.String()] = t
+}
+
+// Schedule schedules cmd to be dispatched to the aggregate at dueAt, with
+// the metadata in ctx, and returns the ID of the timer. It fails with
+// ErrCommandNotRegistered if the type of cmd is not registered.
+func (s *Scheduler) Schedule(
+	ctx context.Context, dueAt time.Time, aggregateID string,
+	cmd eventsource.Command,
+) (string, error) {
+	commandType := reflect.TypeOf(cmd).String()
+
+	s.mu.RLock()
+	_, ok := s.types[commandType]
+	s.mu.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrCommandNotRegistered, commandType)
+	}
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return "", fmt.Errorf("marshal command: %w", err)
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("new timer ID: %w", err)
+	}
+
+	metadata := make(eventstore.Metadata)
+	maps.Copy(metadata, eventstore.MetadataFromContext(ctx))
+
+	if err := s.timers.SaveTimer(ctx, &Timer{
+		ID:          id.String(),
+		DueAt:       dueAt,
+		AggregateID: aggregateID,
+		CommandType: commandType,
+		Command:     data,
+		Metadata:    metadata,
+	}); err != nil {
+		return "", fmt.Errorf("save timer: %w", err)
+	}
+
+	return id.String(), nil
+}
+
+// Cancel cancels a pending timer. It fails with ErrTimerNotFound if the
+// timer has already fired or does not exist.
+func (s *Scheduler) Cancel(ctx context.Context, timerID string) error {
+	return s.timers.DeleteTimer(ctx, timerID)
+}
+
+// Run fires due timers until ctx is done, in which case it returns nil.
+// Commands failing to be dispatched are retried, see WithRetry, and logged
+// once their timer is given up on.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.config.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.fireDue(ctx, s.config.clock.Now()); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
+
+func (s *Scheduler) fireDue(ctx context.Context, now time.Time) error {
+	for {
+		timers, err := s.timers.ListDueTimers(
+			ctx, now, now.Add(s.config.lease), s.config.batchSize)
+		if err != nil {
+			return fmt.Errorf("list due timers: %w", err)
+		}
+
+		for _, timer := range timers {
+			if err := s.fire(ctx, timer); err != nil {
+				return fmt.Errorf("fire timer %s: %w", timer.ID, err)
+			}
+		}
+
+		if len(timers) < s.config.batchSize {
+			return nil
+		}
+	}
+}
+
+func (s *Scheduler) fire(ctx context.Context, timer *Timer) error {
+	err := s.dispatch(ctx, timer)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if err != nil {
+		timer.Attempts++
+		if timer.Attempts < s.config.maxAttempts {
+			s.config.logger.Warn("failed to dispatch scheduled command",
+				slog.String("timer_id", timer.ID),
+				slog.Int("attempts", timer.Attempts),
+				slog.String("error", err.Error()))
+			if err := s.timers.RescheduleTimer(ctx, timer.ID,
+				s.config.clock.Now().Add(s.config.retryDelay), timer.Attempts,
+			); err != nil {
+				// Cancelled while being fired.
+				if errors.Is(err, ErrTimerNotFound) {
+					return nil
+				}
+				return fmt.Errorf("reschedule: %w", err)
+			}
+			return nil
+		}
+		s.config.logger.Error("gave up dispatching scheduled command",
+			slog.String("timer_id", timer.ID),
+			slog.String("aggregate_id", timer.AggregateID),
+			slog.String("command_type", timer.CommandType),
+			slog.String("error", err.Error()))
+	}
+
+	if err := s.timers.DeleteTimer(ctx, timer.ID); err != nil {
+		// Cancelled while being fired.
+		if errors.Is(err, ErrTimerNotFound) {
+			return nil
+		}
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Scheduler) dispatch(ctx context.Context, timer *Timer) error {
+	s.mu.RLock()
+	t, ok := s.types[timer.CommandType]
+	s.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrCommandNotRegistered, timer.CommandType)
+	}
+
+	cmd := reflect.New(t)
+	if err := json.Unmarshal(timer.Command, cmd.Interface()); err != nil {
+		return fmt.Errorf("unmarshal command: %w", err)
+	}
+
+	// The metadata was taken from the context the timer was scheduled with,
+	// so its correlation ID is as legitimate as it was there.
+	ctx = eventstore.WithMetadata(ctx, timer.Metadata)
+	if correlationID := timer.Metadata.CorrelationID(); correlationID != "" {
+		ctx = eventstore.WithCorrelationID(ctx, correlationID)
+	}
+	ctx = eventstore.WithCausationID(ctx, timer.ID)
+
+	return s.commandBus.Dispatch(ctx, timer.AggregateID,
+		cmd.Elem().Interface())
+}
@@ -0,0 +1,369 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource"
+	"github.com/rnovatorov/go-eventsource/pkg/eventsource/eventsourcetest"
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore/eventstoreinmemory"
+	"github.com/rnovatorov/go-eventsource/pkg/scheduler"
+	"github.com/rnovatorov/go-eventsource/pkg/scheduler/schedulerinmemory"
+)
+
+// tally is the aggregate root scheduled commands are dispatched to.
+type tally struct {
+	total int64
+}
+
+type tallyAdd struct {
+	N int64
+}
+
+func (t *tally) ProcessCommand(
+	command eventsource.Command,
+) (eventsource.StateChanges, error) {
+	switch cmd := command.(type) {
+	case tallyAdd:
+		return eventsource.StateChanges{wrapperspb.Int64(cmd.N)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", eventsource.ErrCommandUnknown, cmd)
+	}
+}
+
+func (t *tally) ApplyStateChange(stateChange eventsource.StateChange) error {
+	switch sc := stateChange.(type) {
+	case *wrapperspb.Int64Value:
+		t.total += sc.Value
+	default:
+		return fmt.Errorf("%w: %T", eventsource.ErrUnknownStateChange, sc)
+	}
+	return nil
+}
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fixture makes schedulers with the default retries and lease, dispatching
+// to a repository of tallies unless tests register other handlers.
+type fixture struct {
+	clock      *eventsourcetest.FakeClock
+	tracked    *trackingStore
+	timers     scheduler.TimerStore
+	repository *eventsource.AggregateRepository[tally, *tally]
+	bus        *eventsource.CommandBus
+}
+
+func newFixture(t testing.TB) *fixture {
+	t.Helper()
+
+	tracked := &trackingStore{
+		TimerStore: schedulerinmemory.NewStore(),
+		timers:     make(map[string]struct{}),
+	}
+	f := &fixture{
+		clock:   eventsourcetest.NewFakeClock(epoch),
+		tracked: tracked,
+		timers:  tracked,
+		repository: eventsource.NewAggregateRepository[tally](
+			eventstoreinmemory.New()),
+		bus: eventsource.NewCommandBus(),
+	}
+	f.bus.Register(tallyAdd{}, f.repository.UpdateCommandHandler())
+
+	if _, err := f.repository.Create(
+		context.Background(), "t", tallyAdd{N: 0},
+	); err != nil {
+		t.Fatalf("create tally: %v", err)
+	}
+
+	return f
+}
+
+func (f *fixture) newScheduler() *scheduler.Scheduler {
+	s := scheduler.NewScheduler(f.timers, f.bus,
+		scheduler.WithClock(f.clock),
+		scheduler.WithPollInterval(time.Millisecond))
+	s.Register(tallyAdd{})
+	return s
+}
+
+// run runs a scheduler until the test ends, returning a channel receiving
+// the error it returns.
+func (f *fixture) run(t testing.TB) <-chan error {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		done <- f.newScheduler().Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	return done
+}
+
+func (f *fixture) total(t testing.TB) int64 {
+	t.Helper()
+
+	agg, err := f.repository.Get(context.Background(), "t")
+	if err != nil {
+		t.Fatalf("get tally: %v", err)
+	}
+	return agg.Root().total
+}
+
+// trackingStore keeps track of the timers in the store it wraps, which
+// cannot be listed without leasing them.
+type trackingStore struct {
+	scheduler.TimerStore
+	mu     sync.Mutex
+	timers map[string]struct{}
+}
+
+func (s *trackingStore) SaveTimer(
+	ctx context.Context, timer *scheduler.Timer,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.TimerStore.SaveTimer(ctx, timer); err != nil {
+		return err
+	}
+	s.timers[timer.ID] = struct{}{}
+
+	return nil
+}
+
+func (s *trackingStore) DeleteTimer(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.TimerStore.DeleteTimer(ctx, id); err != nil {
+		return err
+	}
+	delete(s.timers, id)
+
+	return nil
+}
+
+// pending returns the number of timers left, due or not.
+func (f *fixture) pending() int {
+	f.tracked.mu.Lock()
+	defer f.tracked.mu.Unlock()
+
+	return len(f.tracked.timers)
+}
+
+func eventually(t testing.TB, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// settle gives a running scheduler time to poll, for checking that
+// nothing happens.
+func settle() {
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestTimerSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	if _, err := f.newScheduler().Schedule(
+		ctx, epoch.Add(time.Hour), "t", tallyAdd{N: 1},
+	); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+
+	// Another scheduler over the same store takes over.
+	f.run(t)
+	settle()
+	if got := f.total(t); got != 0 {
+		t.Fatalf("total before due: got %d, want 0", got)
+	}
+
+	f.clock.Advance(time.Hour)
+	eventually(t, "timer to fire", func() bool { return f.total(t) == 1 })
+	eventually(t, "timer to be deleted", func() bool { return f.pending() == 0 })
+}
+
+func TestCancelledTimerDoesNotFire(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	s := f.newScheduler()
+
+	id, err := s.Schedule(ctx, epoch.Add(time.Hour), "t", tallyAdd{N: 1})
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	if err := s.Cancel(ctx, id); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if err := s.Cancel(ctx, id); !errors.Is(err, scheduler.ErrTimerNotFound) {
+		t.Fatalf("cancel again: got %v, want %v", err, scheduler.ErrTimerNotFound)
+	}
+
+	f.run(t)
+	f.clock.Advance(time.Hour)
+	settle()
+
+	if got := f.total(t); got != 0 {
+		t.Fatalf("total: got %d, want 0", got)
+	}
+}
+
+func TestFailingCommandIsRetriedUpToMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	var attempts atomic.Int64
+	f.bus.Register(tallyAdd{}, func(
+		context.Context, string, eventsource.Command,
+	) error {
+		attempts.Add(1)
+		return errors.New("unavailable")
+	})
+
+	if _, err := f.newScheduler().Schedule(
+		ctx, epoch, "t", tallyAdd{N: 1},
+	); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	f.run(t)
+
+	for attempt := int64(1); attempt <= 3; attempt++ {
+		eventually(t, fmt.Sprintf("attempt %d", attempt), func() bool {
+			return attempts.Load() == attempt
+		})
+		settle()
+		if got := attempts.Load(); got != attempt {
+			t.Fatalf("attempts before retry delay: got %d, want %d",
+				got, attempt)
+		}
+		// The default retry delay.
+		f.clock.Advance(time.Second)
+	}
+
+	eventually(t, "timer to be given up on", func() bool {
+		return f.pending() == 0
+	})
+	settle()
+	if got := attempts.Load(); got != 3 {
+		t.Fatalf("attempts: got %d, want 3", got)
+	}
+}
+
+func TestTimerCancelledWhileFailingIsNotRescheduled(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	s := f.newScheduler()
+
+	var id string
+	f.bus.Register(tallyAdd{}, func(
+		ctx context.Context, _ string, _ eventsource.Command,
+	) error {
+		if err := s.Cancel(ctx, id); err != nil {
+			t.Errorf("cancel: %v", err)
+		}
+		return errors.New("unavailable")
+	})
+
+	var err error
+	id, err = s.Schedule(ctx, epoch, "t", tallyAdd{N: 1})
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+
+	done := f.run(t)
+	eventually(t, "timer to be cancelled", func() bool {
+		return f.pending() == 0
+	})
+	settle()
+
+	select {
+	case err := <-done:
+		t.Fatalf("run: %v", err)
+	default:
+	}
+	if n := f.pending(); n != 0 {
+		t.Fatalf("cancelled timer rescheduled: %d timers left", n)
+	}
+}
+
+// failingDelete fails to delete timers once, as if the scheduler crashed
+// after dispatching the command of a timer.
+type failingDelete struct {
+	scheduler.TimerStore
+	failed atomic.Bool
+}
+
+func (s *failingDelete) DeleteTimer(ctx context.Context, id string) error {
+	if s.failed.CompareAndSwap(false, true) {
+		return errors.New("connection lost")
+	}
+	return s.TimerStore.DeleteTimer(ctx, id)
+}
+
+func TestRefiredTimerIsDeduplicatedByCausationID(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	f.timers = &failingDelete{TimerStore: f.timers}
+
+	if _, err := f.newScheduler().Schedule(
+		ctx, epoch, "t", tallyAdd{N: 1},
+	); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+
+	done := f.run(t)
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("run: got nil, want the delete error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for run to fail")
+	}
+	if got := f.total(t); got != 1 {
+		t.Fatalf("total after first firing: got %d, want 1", got)
+	}
+
+	// The timer is fired again by the next scheduler once its lease, a
+	// minute by default, expires, and the command is dropped as already
+	// processed.
+	f.run(t)
+	settle()
+	if n := f.pending(); n != 1 {
+		t.Fatalf("leased timer: got %d timers, want 1", n)
+	}
+	f.clock.Advance(time.Minute)
+	eventually(t, "timer to be deleted", func() bool { return f.pending() == 0 })
+
+	agg, err := f.repository.Get(ctx, "t")
+	if err != nil {
+		t.Fatalf("get tally: %v", err)
+	}
+	if agg.Root().total != 1 || agg.Version() != 2 {
+		t.Fatalf("tally: got total %d at version %d, want 1 at 2",
+			agg.Root().total, agg.Version())
+	}
+}
@@ -0,0 +1,100 @@
+package schedulerinmemory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/scheduler"
+)
+
+var _ scheduler.TimerStore = (*Store)(nil)
+
+type Store struct {
+	mu     sync.Mutex
+	timers map[string]scheduler.Timer
+}
+
+func NewStore() *Store {
+	return &Store{
+		timers: make(map[string]scheduler.Timer),
+	}
+}
+
+func (s *Store) SaveTimer(
+	ctx context.Context, timer *scheduler.Timer,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.timers[timer.ID] = clone(*timer)
+
+	return nil
+}
+
+func (s *Store) RescheduleTimer(
+	ctx context.Context, id string, dueAt time.Time, attempts int,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timer, ok := s.timers[id]
+	if !ok {
+		return scheduler.ErrTimerNotFound
+	}
+	timer.DueAt = dueAt
+	timer.Attempts = attempts
+	s.timers[id] = timer
+
+	return nil
+}
+
+func (s *Store) DeleteTimer(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.timers[id]; !ok {
+		return scheduler.ErrTimerNotFound
+	}
+	delete(s.timers, id)
+
+	return nil
+}
+
+func (s *Store) ListDueTimers(
+	ctx context.Context, now time.Time, leaseUntil time.Time, limit int,
+) ([]*scheduler.Timer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*scheduler.Timer
+	for _, timer := range s.timers {
+		if !timer.DueAt.After(now) {
+			t := clone(timer)
+			due = append(due, &t)
+		}
+	}
+
+	slices.SortFunc(due, func(a, b *scheduler.Timer) int {
+		return a.DueAt.Compare(b.DueAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	for _, t := range due {
+		timer := s.timers[t.ID]
+		timer.DueAt = leaseUntil
+		s.timers[t.ID] = timer
+	}
+
+	return due, nil
+}
+
+func clone(timer scheduler.Timer) scheduler.Timer {
+	timer.Command = slices.Clone(timer.Command)
+	timer.Metadata = maps.Clone(timer.Metadata)
+	return timer
+}
@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE TABLE es_timers (
+    id TEXT PRIMARY KEY,
+    due_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    aggregate_id TEXT NOT NULL,
+    command_type TEXT NOT NULL,
+    command JSONB NOT NULL,
+    metadata JSONB NOT NULL,
+    attempts INT NOT NULL
+);
+
+CREATE INDEX ON es_timers (due_at);
+
+END;
@@ -0,0 +1,17 @@
+package schedulerpostgres
+
+import _ "embed"
+
+var (
+	//go:embed queries/save_timer.sql
+	saveTimerQuery string
+
+	//go:embed queries/reschedule_timer.sql
+	rescheduleTimerQuery string
+
+	//go:embed queries/delete_timer.sql
+	deleteTimerQuery string
+
+	//go:embed queries/list_due_timers.sql
+	listDueTimersQuery string
+)
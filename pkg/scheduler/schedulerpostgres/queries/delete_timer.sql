@@ -0,0 +1,2 @@
+DELETE FROM es_timers
+WHERE id = @id;
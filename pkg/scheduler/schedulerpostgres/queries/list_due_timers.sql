@@ -0,0 +1,29 @@
+WITH due AS (
+    SELECT
+        id,
+        due_at
+    FROM
+        es_timers
+    WHERE
+        due_at <= @now
+    ORDER BY
+        due_at
+    LIMIT @limit
+    FOR UPDATE
+        SKIP LOCKED)
+UPDATE
+    es_timers
+SET
+    due_at = @lease_until
+FROM
+    due
+WHERE
+    es_timers.id = due.id
+RETURNING
+    es_timers.id,
+    due.due_at,
+    es_timers.aggregate_id,
+    es_timers.command_type,
+    es_timers.command,
+    es_timers.metadata,
+    es_timers.attempts;
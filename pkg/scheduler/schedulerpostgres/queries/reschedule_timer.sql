@@ -0,0 +1,7 @@
+UPDATE
+    es_timers
+SET
+    due_at = @due_at,
+    attempts = @attempts
+WHERE
+    id = @id;
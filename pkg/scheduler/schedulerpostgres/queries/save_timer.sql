@@ -0,0 +1,12 @@
+INSERT INTO es_timers (id, due_at, aggregate_id, command_type, command,
+    metadata, attempts)
+    VALUES (@id, @due_at, @aggregate_id, @command_type, @command, @metadata,
+        @attempts)
+ON CONFLICT (id)
+    DO UPDATE SET
+        due_at = excluded.due_at,
+        aggregate_id = excluded.aggregate_id,
+        command_type = excluded.command_type,
+        command = excluded.command,
+        metadata = excluded.metadata,
+        attempts = excluded.attempts;
@@ -0,0 +1,120 @@
+package schedulerpostgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rnovatorov/go-eventsource/pkg/scheduler"
+)
+
+var _ scheduler.TimerStore = (*Store)(nil)
+
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{
+		pool: pool,
+	}
+}
+
+func (s *Store) SaveTimer(
+	ctx context.Context, timer *scheduler.Timer,
+) error {
+	metadataBytes, err := json.Marshal(timer.Metadata)
+	if err != nil {
+		return fmt.Errorf("marshal metadata: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, saveTimerQuery, pgx.NamedArgs{
+		"id":           timer.ID,
+		"due_at":       timer.DueAt,
+		"aggregate_id": timer.AggregateID,
+		"command_type": timer.CommandType,
+		"command":      timer.Command,
+		"metadata":     metadataBytes,
+		"attempts":     timer.Attempts,
+	})
+	return err
+}
+
+func (s *Store) RescheduleTimer(
+	ctx context.Context, id string, dueAt time.Time, attempts int,
+) error {
+	tag, err := s.pool.Exec(ctx, rescheduleTimerQuery, pgx.NamedArgs{
+		"id":       id,
+		"due_at":   dueAt,
+		"attempts": attempts,
+	})
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return scheduler.ErrTimerNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteTimer(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, deleteTimerQuery, pgx.NamedArgs{
+		"id": id,
+	})
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return scheduler.ErrTimerNotFound
+	}
+
+	return nil
+}
+
+// ListDueTimers locks the due timers it leases with SKIP LOCKED, so that
+// concurrent calls skip them rather than wait to lease them again.
+func (s *Store) ListDueTimers(
+	ctx context.Context, now time.Time, leaseUntil time.Time, limit int,
+) ([]*scheduler.Timer, error) {
+	rows, _ := s.pool.Query(ctx, listDueTimersQuery, pgx.NamedArgs{
+		"now":         now,
+		"lease_until": leaseUntil,
+		"limit":       limit,
+	})
+
+	timers, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*scheduler.Timer, error) {
+		var t scheduler.Timer
+		var metadataBytes []byte
+
+		if err := row.Scan(
+			&t.ID, &t.DueAt, &t.AggregateID, &t.CommandType, &t.Command,
+			&metadataBytes, &t.Attempts,
+		); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+
+		if err := json.Unmarshal(metadataBytes, &t.Metadata); err != nil {
+			return nil, fmt.Errorf("unmarshal metadata: %w", err)
+		}
+
+		return &t, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// UPDATE returns the rows in no particular order.
+	slices.SortFunc(timers, func(a, b *scheduler.Timer) int {
+		return a.DueAt.Compare(b.DueAt)
+	})
+
+	return timers, nil
+}
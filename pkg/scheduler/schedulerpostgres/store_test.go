@@ -0,0 +1,205 @@
+package schedulerpostgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+	"github.com/rnovatorov/go-eventsource/pkg/scheduler"
+)
+
+// testDatabaseURLEnv names the variable holding the URL of the database the
+// tests run against, the same as for the event store. The tests are skipped
+// if it is not set. Every test migrates a schema of its own and drops it
+// when done.
+const testDatabaseURLEnv = "EVENTSTOREPOSTGRES_TEST_DATABASE_URL"
+
+// newTestStore returns a store whose connections have the search path set
+// to a freshly migrated schema.
+func newTestStore(t testing.TB) *Store {
+	t.Helper()
+
+	url := os.Getenv(testDatabaseURLEnv)
+	if url == "" {
+		t.Skipf("%s not set", testDatabaseURLEnv)
+	}
+
+	ctx := context.Background()
+
+	admin, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(admin.Close)
+
+	schema := fmt.Sprintf("es_test_timers_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(
+			context.Background(), "DROP SCHEMA "+schema+" CASCADE",
+		); err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+	})
+
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("parse URL: %v", err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	files, err := filepath.Glob("migrations/*.up.sql")
+	if err != nil {
+		t.Fatalf("list migrations: %v", err)
+	}
+	slices.Sort(files)
+
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read migration: %v", err)
+		}
+		if _, err := pool.Exec(ctx, string(migration)); err != nil {
+			t.Fatalf("migration %s: %v", file, err)
+		}
+	}
+
+	return NewStore(pool)
+}
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func saveTestTimers(t testing.TB, s *Store, n int) {
+	t.Helper()
+
+	for i := range n {
+		if err := s.SaveTimer(context.Background(), &scheduler.Timer{
+			ID:          fmt.Sprintf("timer-%d", i),
+			DueAt:       epoch.Add(time.Duration(i) * time.Second),
+			AggregateID: "a",
+			CommandType: "test.Command",
+			Command:     []byte("{}"),
+			Metadata:    eventstore.Metadata{},
+		}); err != nil {
+			t.Fatalf("save timer %d: %v", i, err)
+		}
+	}
+}
+
+func timerIDs(timers []*scheduler.Timer) []string {
+	ids := make([]string, 0, len(timers))
+	for _, timer := range timers {
+		ids = append(ids, timer.ID)
+	}
+	return ids
+}
+
+func TestListDueTimersLeasesThem(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	saveTestTimers(t, s, 3)
+
+	now := epoch.Add(time.Hour)
+	leaseUntil := now.Add(time.Minute)
+
+	for _, want := range []string{"[timer-0 timer-1]", "[timer-2]", "[]"} {
+		timers, err := s.ListDueTimers(ctx, now, leaseUntil, 2)
+		if err != nil {
+			t.Fatalf("list due timers: %v", err)
+		}
+		if got := fmt.Sprint(timerIDs(timers)); got != want {
+			t.Fatalf("due timers: got %s, want %s", got, want)
+		}
+	}
+
+	timers, err := s.ListDueTimers(ctx, leaseUntil, leaseUntil, 10)
+	if err != nil {
+		t.Fatalf("list timers with expired leases: %v", err)
+	}
+	if got := len(timers); got != 3 {
+		t.Fatalf("timers with expired leases: got %d, want 3", got)
+	}
+}
+
+func TestConcurrentListDueTimersSkipLockedTimers(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	const timers = 50
+	saveTestTimers(t, s, timers)
+
+	now := epoch.Add(time.Hour)
+
+	var (
+		mu     sync.Mutex
+		listed []string
+		wg     sync.WaitGroup
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				due, err := s.ListDueTimers(ctx, now, now.Add(time.Hour), 1)
+				if err != nil {
+					t.Errorf("list due timers: %v", err)
+					return
+				}
+				if len(due) == 0 {
+					return
+				}
+				mu.Lock()
+				listed = append(listed, due[0].ID)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	slices.Sort(listed)
+	if got := len(slices.Compact(listed)); got != timers || len(listed) != timers {
+		t.Fatalf("listed %d timers, %d distinct, want each of %d once",
+			len(listed), got, timers)
+	}
+}
+
+func TestRescheduleTimerDoesNotResurrectIt(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	saveTestTimers(t, s, 1)
+
+	if err := s.DeleteTimer(ctx, "timer-0"); err != nil {
+		t.Fatalf("delete timer: %v", err)
+	}
+	if err := s.RescheduleTimer(
+		ctx, "timer-0", epoch, 1,
+	); !errors.Is(err, scheduler.ErrTimerNotFound) {
+		t.Fatalf("reschedule deleted timer: got %v, want %v",
+			err, scheduler.ErrTimerNotFound)
+	}
+
+	timers, err := s.ListDueTimers(ctx, epoch, epoch, 10)
+	if err != nil {
+		t.Fatalf("list due timers: %v", err)
+	}
+	if len(timers) != 0 {
+		t.Fatalf("deleted timer listed: %v", timerIDs(timers))
+	}
+}
@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/rnovatorov/go-eventsource/pkg/eventstore"
+)
+
+// Timer is a command scheduled to be dispatched to an aggregate once DueAt
+// has passed. The command is encoded as JSON, CommandType naming its type,
+// see Scheduler.Register. Metadata is the context metadata at the time the
+// command was scheduled.
+type Timer struct {
+	ID          string
+	DueAt       time.Time
+	AggregateID string
+	CommandType string
+	Command     []byte
+	Metadata    eventstore.Metadata
+	Attempts    int
+}
+
+// TimerStore persists the timers of a Scheduler.
+type TimerStore interface {
+	// SaveTimer inserts the timer or replaces the one with the same ID.
+	SaveTimer(
+		ctx context.Context, timer *Timer,
+	) error
+	// RescheduleTimer sets when the timer is due and how many attempts
+	// have been made to fire it, failing with ErrTimerNotFound if there is
+	// no timer with the ID, e.g. because it was cancelled.
+	RescheduleTimer(
+		ctx context.Context, id string, dueAt time.Time, attempts int,
+	) error
+	// DeleteTimer fails with ErrTimerNotFound if there is no timer with the
+	// ID.
+	DeleteTimer(
+		ctx context.Context, id string,
+	) error
+	// ListDueTimers lists up to limit timers due at or before now, the
+	// earliest first, and makes them due at leaseUntil, so that concurrent
+	// calls, e.g. by schedulers in other processes, list each timer once
+	// until then. The listed timers keep the time they were due at.
+	ListDueTimers(
+		ctx context.Context, now time.Time, leaseUntil time.Time, limit int,
+	) ([]*Timer, error)
+}